@@ -12,6 +12,7 @@ import (
 	"io/ioutil"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/gdamore/tcell"
 	"go.uber.org/zap"
@@ -19,6 +20,8 @@ import (
 
 const (
 	rcFile = ".xlrc"
+	// keySequenceTimeout is the time to type the next key of a bound key sequence.
+	keySequenceTimeout = time.Second
 )
 
 type App struct {
@@ -29,10 +32,31 @@ type App struct {
 	output  ui.OutputInterface
 	doc     *document.Document
 	file    fs.FileInterface
-	hotKeys map[Key]string
+	hotKeys map[string]string
+	motion  motionState
+	mouse   mouseState
+	search  searchState
+	options options
+
+	// keys of a bound sequence typed so far and the number of the latest one to detect timeouts
+	pendingKeys []Key
+	pendingSeq  int
 
 	// Keeps the cell for copy/cut/paste operations.
 	cellBuffer *sheet.Cell
+
+	// windows showing sheets and their placement on the screen
+	windows      []*window
+	activeWindow int
+	layout       *ui.WindowLayout
+}
+
+// options are changed by set command.
+type options struct {
+	// search patterns are case insensitive regular expressions
+	ignoreCase bool
+	regex      bool
+	search     document.SearchOptions
 }
 
 type Config struct {
@@ -48,16 +72,18 @@ func New(config *Config) *App {
 		logger:  config.Logger,
 		input:   config.Input,
 		output:  config.Output,
-		hotKeys: make(map[Key]string),
+		hotKeys: make(map[string]string),
 	}
 	a.output.SetDataDelegate(a)
 	a.loadRC()
+	a.loadHistory()
 	return a
 }
 
 // ResetDocument creates a new empty document.
 func (a *App) ResetDocument() {
 	a.doc = document.NewWithEmptySheet()
+	a.resetWindows()
 	a.output.SetDataDelegate(a)
 	a.output.RefreshView()
 }
@@ -77,6 +103,8 @@ func (a *App) OpenDocument(filename string) error {
 		a.doc.CurrentSheet = a.doc.Sheets[0]
 		a.doc.CurrentSheetN = 0
 	}
+	a.resetWindows()
+	a.doc.UpdateSpills()
 	a.output.RefreshView()
 	return nil
 }
@@ -92,7 +120,7 @@ func (a *App) Write() error {
 // WriteAs writes document to file with given name.
 func (a *App) WriteAs(filename string) error {
 	if filename != "" {
-		a.file = bufcsv.NewWithFilename(filename)
+		a.file = guessFileFormat(filename)
 	}
 	return a.file.Write(a.doc)
 }
@@ -114,8 +142,18 @@ func (a *App) Loop() {
 			}
 		case *tcell.EventResize:
 			a.output.RefreshView()
+		case *tcell.EventInterrupt:
+			if seq, ok := ev.Data().(hotKeyTimeout); ok && a.onHotKeyTimeout(seq) {
+				return
+			}
 		case *tcell.EventMouse:
-			//handling event mouse
+			x, y := ev.Position()
+			a.processMouseEvent(ui.MouseEvent{
+				X:       x,
+				Y:       y,
+				Buttons: ev.Buttons(),
+				Mod:     ev.Modifiers(),
+			})
 		case *tcell.EventError:
 			a.logger.Error("unknown input event")
 			return
@@ -1,36 +1,87 @@
 package app
 
 import (
+	"xl/document"
+	"xl/document/eval"
 	"xl/document/sheet"
 	"xl/ui"
 
 	"fmt"
 	"os"
 	"runtime/pprof"
+	"sort"
+	"strconv"
 	"strings"
 )
 
-const colSizeIncrementStep = 6
+const (
+	colSizeIncrementStep = 6
+	rowSizeIncrementStep = 20
+)
 
 // processCommand do the job associated with the command.
 // If no such command found, shows the error in status line.
 func (a *App) processCommand(c string) bool {
+	if strings.HasPrefix(c, "s/") || strings.HasPrefix(c, "%s/") {
+		// the pattern may contain spaces and quotes, so the command is not split into arguments
+		a.cmdSubstitute(c)
+		return false
+	}
 	c, args := parseArgs(c)
 	switch c {
+	case "":
+		// nothing entered
 	case "q", "quit":
-		return true
+		// like in vim, the command closes the window and quits only if it is the last one
+		return !a.cmdClose()
 	case "w", "write":
 		a.cmdWrite(arg1(args))
 	case "wider":
 		a.cmdResizeColumn(1)
 	case "narrower":
 		a.cmdResizeColumn(-1)
+	case "taller":
+		a.cmdResizeRow(1)
+	case "shorter":
+		a.cmdResizeRow(-1)
+	case "colWidth":
+		a.cmdSetSize(false, arg1(args))
+	case "rowHeight":
+		a.cmdSetSize(true, arg1(args))
+	case "autofit":
+		a.cmdAutofit(arg1(args))
+	case "format":
+		a.cmdFormat(arg1(args))
+	case "colFormat":
+		a.cmdColFormat(args)
+	case "style":
+		a.cmdStyle(args)
+	case "merge":
+		a.cmdMerge(arg1(args))
+	case "unmerge":
+		a.cmdUnmerge(arg1(args))
+	case "cf":
+		a.cmdCF(args)
+	case "validate":
+		a.cmdValidate(args)
+	case "pick":
+		a.pickValue()
+	case "note":
+		a.cmdNote(strings.Join(args, " "))
+	case "deleteNote":
+		a.cmdDeleteNote(arg1(args))
 	case "newSheet":
 		a.cmdNewSheet(arg1(args))
 	case "nextSheet":
 		a.cmdNextSheet()
+	case "sheet":
+		a.cmdSheet(arg1(args))
 	case "bind":
 		a.cmdBind(args)
+	case "unbind":
+		a.cmdUnbind(arg1(args))
+	case "map":
+		a.cmdMap(arg1(args))
 	case "cutCell":
 		a.cmdCutCell()
 	case "pasteCell":
@@ -53,6 +104,54 @@ func (a *App) processCommand(c string) bool {
 		a.cmdMemProf()
 	case "go":
 		a.cmdGo(arg1(args))
+	case "name":
+		a.cmdName(args)
+	case "deleteName":
+		a.cmdDeleteName(arg1(args))
+	case "set":
+		a.cmdSet(args)
+	case "sort":
+		a.cmdSort(args)
+	case "filter":
+		a.cmdFilter(args)
+	case "noh", "nohlsearch":
+		a.cmdNoHighlight()
+	case "split", "sp":
+		a.cmdSplit(false, arg1(args))
+	case "vsplit", "vs":
+		a.cmdSplit(true, arg1(args))
+	case "close":
+		if !a.cmdClose() {
+			a.output.SetStatus("cannot close the last window", ui.StatusFlagError)
+		}
+	case "only":
+		a.cmdOnly()
+	case "freeze":
+		a.cmdFreeze(arg1(args))
+	case "hideRow":
+		a.cmdHide(true, true, args)
+	case "unhideRow":
+		a.cmdHide(true, false, args)
+	case "hideCol":
+		a.cmdHide(false, true, args)
+	case "unhideCol":
+		a.cmdHide(false, false, args)
+	case "groupRow":
+		a.cmdGroup(true, 1, args)
+	case "ungroupRow":
+		a.cmdGroup(true, -1, args)
+	case "groupCol":
+		a.cmdGroup(false, 1, args)
+	case "ungroupCol":
+		a.cmdGroup(false, -1, args)
+	case "collapseRow":
+		a.cmdCollapse(true, true)
+	case "expandRow":
+		a.cmdCollapse(true, false)
+	case "collapseCol":
+		a.cmdCollapse(false, true)
+	case "expandCol":
+		a.cmdCollapse(false, false)
 	default:
 		a.output.SetStatus(fmt.Sprintf("unknown command %s", c), ui.StatusFlagError)
 	}
@@ -73,11 +172,16 @@ func argN(args []string, n int) string {
 }
 
 // parseArgs splits raw command line into command itself and list of command arguments.
-// TODO: arguments can possibly be wrapped in quotes
 func parseArgs(cmd string) (string, []string) {
-	// FIXME: naive implementation
-	c := strings.Split(cmd, " ")
-	return c[0], c[1:]
+	tokens := splitCommandLine(cmd)
+	if len(tokens) == 0 {
+		return "", nil
+	}
+	args := make([]string, len(tokens)-1)
+	for i := range args {
+		args[i] = tokens[i+1].value
+	}
+	return tokens[0].value, args
 }
 
 // cmdResizeColumn resizes column under cursor so its width becomes given N pixels.
@@ -88,6 +192,71 @@ func (a *App) cmdResizeColumn(n int) {
 	a.output.SetDirty(ui.DirtyHRuler | ui.DirtyGrid)
 }
 
+// cmdResizeRow changes height of the row under cursor on N lines.
+func (a *App) cmdResizeRow(n int) {
+	row := a.doc.CurrentSheet.Cursor.Y
+	size := a.doc.CurrentSheet.RowSize(row)
+	a.doc.CurrentSheet.SetRowSize(row, size+n*rowSizeIncrementStep)
+	a.output.SetDirty(ui.DirtyVRuler | ui.DirtyGrid)
+}
+
+// cmdSetSize sets height of selected rows in lines or width of selected columns in chars,
+// the row or the column under cursor is resized if nothing is selected.
+func (a *App) cmdSetSize(rows bool, size string) {
+	s := a.doc.CurrentSheet
+	n, err := strconv.Atoi(size)
+	step, maxSize := colSizeIncrementStep, sheet.CellMaxWidth
+	if rows {
+		step, maxSize = rowSizeIncrementStep, sheet.CellMaxHeight
+	}
+	if err != nil || n < 1 || n*step > maxSize {
+		a.output.SetStatus(fmt.Sprintf("size must be a number from 1 to %d", maxSize/step), ui.StatusFlagError)
+		return
+	}
+	from, to, _ := a.outlineRange(rows, "")
+	for i := from; i <= to; i++ {
+		if rows {
+			s.SetRowSize(i, n*step)
+		} else {
+			s.SetColSize(i, n*step)
+		}
+	}
+	a.output.SetDirty(ui.DirtyHRuler | ui.DirtyVRuler | ui.DirtyGrid)
+}
+
+// cmdAutofit makes columns given like "B:D", selected ones or the one under cursor as wide as
+// their widest displayed value.
+func (a *App) cmdAutofit(cols string) {
+	s := a.doc.CurrentSheet
+	from, to, err := a.outlineRange(false, cols)
+	if err != nil {
+		a.showError(err)
+		return
+	}
+	used := s.UsedRect()
+	for x := from; x <= to; x++ {
+		width := 0
+		for y := used.Y; y <= used.MaxY() && used.Width > 0; y++ {
+			c := a.CellView(a.doc.CurrentSheetN, x, y)
+			text := c.DisplayText
+			if c.Error != nil {
+				text = *c.Error
+			}
+			if text == "" {
+				continue
+			}
+			if w := a.output.TextWidth(text); w > width {
+				width = w
+			}
+		}
+		if width == 0 {
+			width = sheet.CellDefaultWidth
+		}
+		s.SetColSize(x, minInt(width, sheet.CellMaxWidth))
+	}
+	a.output.SetDirty(ui.DirtyHRuler | ui.DirtyGrid)
+}
+
 // cmdWrite saves document to file.
 func (a *App) cmdWrite(filename string) {
 	var err error
@@ -114,27 +283,96 @@ func (a *App) cmdNewSheet(title string) {
 // cmdNextSheet switches the current sheet to next one.
 // If current sheet is the last one, it switches to first.
 func (a *App) cmdNextSheet() {
-	a.doc.CurrentSheetN++
-	if a.doc.CurrentSheetN >= len(a.doc.Sheets) {
-		a.doc.CurrentSheetN = 0
+	if a.doc.CurrentSheetN+1 >= len(a.doc.Sheets) {
+		a.switchSheet(0)
+		return
 	}
-	a.doc.CurrentSheet = a.doc.Sheets[a.doc.CurrentSheetN]
-	a.output.SetDirty(ui.DirtyStatusLine | ui.DirtyGrid | ui.DirtyFormulaLine)
+	a.switchSheet(a.doc.CurrentSheetN + 1)
 }
 
-// cmdBind binds a command to a hot key.
+// cmdSheet switches the current sheet to the one with given title.
+func (a *App) cmdSheet(title string) {
+	for i, s := range a.doc.Sheets {
+		if s.Title == title {
+			a.switchSheet(i)
+			return
+		}
+	}
+	a.output.SetStatus(fmt.Sprintf("sheet %s does not exist", title), ui.StatusFlagError)
+}
+
+// switchSheet makes the sheet with given index the current one.
+func (a *App) switchSheet(n int) {
+	a.doc.CurrentSheetN = n
+	a.doc.CurrentSheet = a.doc.Sheets[n]
+	a.output.SetDirty(ui.DirtyStatusLine | ui.DirtyGrid | ui.DirtyFormulaLine | ui.DirtyHRuler | ui.DirtyVRuler)
+}
+
+// cmdBind binds a command to a hot key or a sequence of keys.
 func (a *App) cmdBind(args []string) {
 	if len(args) < 2 {
 		a.output.SetStatus("hot key and command must be specified", ui.StatusFlagError)
 		return
 	}
-	k, ok := HotKeys[args[0]]
-	if !ok {
-		a.output.SetStatus(fmt.Sprintf("key %s is not a valid key", args[0]), ui.StatusFlagError)
+	keys, err := ParseKeys(args[0])
+	if err != nil {
+		a.showError(err)
+		return
+	}
+	if len(args) == 2 {
+		// the command is quoted as a whole: bind <F2> "go A1"
+		a.hotKeys[FormatKeys(keys)] = args[1]
+		return
+	}
+	a.hotKeys[FormatKeys(keys)] = joinArgs(args[1:])
+}
+
+// cmdUnbind removes the binding of a hot key or a sequence of keys.
+func (a *App) cmdUnbind(notation string) {
+	keys, err := ParseKeys(notation)
+	if err != nil {
+		a.showError(err)
+		return
+	}
+	if _, ok := a.hotKeys[FormatKeys(keys)]; !ok {
+		a.output.SetStatus(fmt.Sprintf("key %s is not bound", notation), ui.StatusFlagError)
+		return
+	}
+	delete(a.hotKeys, FormatKeys(keys))
+}
+
+// cmdMap shows commands bound to keys starting with given ones, or all bindings.
+func (a *App) cmdMap(notation string) {
+	prefix := ""
+	if notation != "" {
+		keys, err := ParseKeys(notation)
+		if err != nil {
+			a.showError(err)
+			return
+		}
+		prefix = FormatKeys(keys)
+	}
+	var bindings []string
+	for _, k := range a.boundKeys() {
+		if strings.HasPrefix(k, prefix) {
+			bindings = append(bindings, k+" "+a.hotKeys[k])
+		}
+	}
+	if len(bindings) == 0 {
+		a.output.SetStatus("no bindings found", 0)
 		return
 	}
-	// TODO: escape with quotes
-	a.hotKeys[k] = strings.Join(args[1:], " ")
+	a.output.SetStatus(strings.Join(bindings, ", "), 0)
+}
+
+// boundKeys returns sorted notations of bound keys.
+func (a *App) boundKeys() []string {
+	keys := make([]string, 0, len(a.hotKeys))
+	for k := range a.hotKeys {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
 }
 
 // cmdCutCell erases the cell (but puts its value to buffer first).
@@ -163,26 +401,35 @@ func (a *App) cmdPasteCell() {
 	cellCopy := *a.cellBuffer
 	s := a.doc.CurrentSheet
 	s.SetCell(s.Cursor.X, s.Cursor.Y, &cellCopy)
+	a.doc.UpdateSpill(eval.Cell{SheetIdx: s.Idx, X: s.Cursor.X, Y: s.Cursor.Y})
 	a.output.SetDirty(ui.DirtyGrid | ui.DirtyFormulaLine)
 }
 
 func (a *App) cmdInsertRow(n int) {
 	a.doc.InsertEmptyRow(n)
+	a.shiftWindows(true, a.doc.CurrentSheet.Cursor.Y, 1)
+	a.doc.ApplyFilter(a.doc.CurrentSheet)
 	a.output.SetDirty(ui.DirtyGrid | ui.DirtyFormulaLine)
 }
 
 func (a *App) cmdInsertCol(n int) {
 	a.doc.InsertEmptyCol(n)
+	a.shiftWindows(false, a.doc.CurrentSheet.Cursor.X, 1)
+	a.doc.ApplyFilter(a.doc.CurrentSheet)
 	a.output.SetDirty(ui.DirtyGrid | ui.DirtyFormulaLine)
 }
 
 func (a *App) cmdDeleteRow() {
 	a.doc.DeleteRow()
+	a.shiftWindows(true, a.doc.CurrentSheet.Cursor.Y, -1)
+	a.doc.ApplyFilter(a.doc.CurrentSheet)
 	a.output.SetDirty(ui.DirtyGrid | ui.DirtyFormulaLine)
 }
 
 func (a *App) cmdDeleteCol() {
 	a.doc.DeleteCol()
+	a.shiftWindows(false, a.doc.CurrentSheet.Cursor.X, -1)
+	a.doc.ApplyFilter(a.doc.CurrentSheet)
 	a.output.SetDirty(ui.DirtyGrid | ui.DirtyFormulaLine)
 }
 
@@ -203,3 +450,145 @@ func (a *App) cmdGo(cellName string) {
 	}
 	a.moveCursorTo(x, y)
 }
+
+// cmdName defines a named range or constant. With no definition given, shows existing names.
+func (a *App) cmdName(args []string) {
+	if len(args) == 0 {
+		var defs []string
+		for _, name := range a.doc.Names() {
+			def, _ := a.doc.NameDefinition(name)
+			defs = append(defs, name+"="+def)
+		}
+		a.output.SetStatus(strings.Join(defs, ", "), 0)
+		return
+	}
+	if len(args) == 1 {
+		def, err := a.doc.NameDefinition(args[0])
+		if err != nil {
+			a.showError(err)
+			return
+		}
+		a.output.SetStatus(args[0]+"="+def, 0)
+		return
+	}
+	if err := a.doc.SetName(args[0], strings.Join(args[1:], " ")); err != nil {
+		a.showError(err)
+		return
+	}
+	a.output.SetDirty(ui.DirtyGrid | ui.DirtyFormulaLine)
+}
+
+// cmdDeleteName removes a named range or constant.
+func (a *App) cmdDeleteName(name string) {
+	if err := a.doc.DeleteName(name); err != nil {
+		a.showError(err)
+		return
+	}
+	a.output.SetDirty(ui.DirtyGrid | ui.DirtyFormulaLine)
+}
+
+// cmdSort sorts rows of the selection or the whole sheet: "sort [header] [column]...", where column name
+// is prefixed with "-" for descending order. Rows are sorted by the column under cursor if no columns given.
+func (a *App) cmdSort(args []string) {
+	s := a.doc.CurrentSheet
+	header := false
+	var keys []document.SortKey
+	for _, arg := range args {
+		if arg == "header" {
+			header = true
+			continue
+		}
+		k := document.SortKey{}
+		if strings.HasPrefix(arg, "-") {
+			k.Descending = true
+			arg = arg[1:]
+		}
+		x, err := columnByName(arg)
+		if err != nil {
+			a.showError(err)
+			return
+		}
+		k.Col = x
+		keys = append(keys, k)
+	}
+	if len(keys) == 0 {
+		keys = []document.SortKey{{Col: s.Cursor.X}}
+	}
+	area := s.Selection
+	if area.Width == 0 {
+		area = s.UsedRect()
+	}
+	newY, err := a.doc.SortRows(s, area, keys, header)
+	if err != nil {
+		a.showError(err)
+		return
+	}
+	a.followSortedRows(s, area, newY)
+	a.doc.ApplyFilter(s)
+	a.output.SetDirty(ui.DirtyGrid | ui.DirtyFormulaLine)
+}
+
+// cmdFreeze pins rows above and columns left of the cell (under cursor by default), so they stay on the screen
+// while scrolling. "freeze off" unpins them.
+func (a *App) cmdFreeze(cellName string) {
+	s := a.doc.CurrentSheet
+	defer a.output.SetDirty(ui.DirtyHRuler | ui.DirtyVRuler | ui.DirtyGrid)
+	if cellName == "off" {
+		s.Viewport.FrozenRows, s.Viewport.FrozenCols = 0, 0
+		return
+	}
+	x, y := s.Cursor.X, s.Cursor.Y
+	if cellName != "" {
+		var err error
+		if x, y, err = a.doc.FindCell(cellName); err != nil {
+			a.output.SetStatus("incorrect cell", ui.StatusFlagError)
+			return
+		}
+	}
+	// frozen rows and columns must leave room for the scrolled area
+	if visibleBefore(s.RowHidden, y) >= a.output.ViewportHeight()+visibleBefore(s.RowHidden, s.Viewport.FrozenRows) ||
+		visibleBefore(s.ColHidden, x) >= a.output.ViewportWidth()+visibleBefore(s.ColHidden, s.Viewport.FrozenCols) {
+		a.output.SetStatus("frozen rows and columns do not fit the screen", ui.StatusFlagError)
+		return
+	}
+	s.Viewport.FrozenRows, s.Viewport.FrozenCols = y, x
+	s.Viewport.Top = maxInt(s.Viewport.Top, y)
+	s.Viewport.Left = maxInt(s.Viewport.Left, x)
+	a.moveCursorTo(s.Cursor.X, s.Cursor.Y)
+}
+
+// visibleBefore counts rows or columns before n which are not hidden.
+func visibleBefore(hidden func(int) bool, n int) int {
+	count := 0
+	for i := 0; i < n; i++ {
+		if !hidden(i) {
+			count++
+		}
+	}
+	return count
+}
+
+// cmdSet changes options: "set option" turns the option on, "set nooption" turns it off.
+func (a *App) cmdSet(args []string) {
+	for _, arg := range args {
+		name, enabled := arg, true
+		if strings.HasPrefix(name, "no") {
+			name, enabled = name[2:], false
+		}
+		switch name {
+		case "vim":
+			a.output.SetVimMode(enabled)
+		case "ignorecase":
+			a.options.ignoreCase = enabled
+		case "regex":
+			a.options.regex = enabled
+		case "searchformulas":
+			a.options.search.Formulas = enabled
+		case "searchsheets":
+			a.options.search.AllSheets = enabled
+		default:
+			a.output.SetStatus(fmt.Sprintf("unknown option %s", arg), ui.StatusFlagError)
+			return
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package app
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// commandNames lists commands for completion, keep in sync with processCommand.
+var commandNames = []string{
+	"autofit", "bind", "cf", "close", "colFormat", "colWidth", "collapseCol", "collapseRow", "copyCell",
+	"cutCell", "deleteCol", "deleteName", "deleteNote", "deleteRow", "expandCol", "expandRow", "filter",
+	"format", "freeze", "go", "groupCol", "groupRow", "hideCol", "hideRow", "insertCol", "insertColAfter",
+	"insertRow", "insertRowAfter", "map", "merge", "mprof", "name", "narrower", "newSheet", "nextSheet",
+	"nohlsearch", "note", "only", "pasteCell", "pick", "q", "quit", "rowHeight", "set", "sheet", "shorter",
+	"sort", "split", "style", "taller", "unbind", "ungroupCol", "ungroupRow", "unhideCol", "unhideRow",
+	"unmerge", "validate", "vsplit", "w", "wider", "write",
+}
+
+// optionNames lists options of the set command.
+var optionNames = []string{
+	"ignorecase", "noignorecase", "regex", "noregex", "searchformulas", "nosearchformulas",
+	"searchsheets", "nosearchsheets", "vim", "novim",
+}
+
+// commandToken is a word of the command line.
+type commandToken struct {
+	// start is a byte offset of the token in the command line, including opening quote
+	start int
+	value string
+}
+
+// splitCommandLine splits the command line into words. Words containing spaces can be wrapped
+// in double or single quotes. Backslash escapes the next char everywhere but in single quotes.
+// Unterminated quotes are closed at the end of the line.
+func splitCommandLine(line string) []commandToken {
+	var tokens []commandToken
+	var buf strings.Builder
+	inToken := false
+	var quote rune
+	escaped := false
+	start := 0
+	for i, r := range line {
+		switch {
+		case escaped:
+			buf.WriteRune(r)
+			escaped = false
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else if r == '\\' && quote == '"' {
+				escaped = true
+			} else {
+				buf.WriteRune(r)
+			}
+		case r == ' ' || r == '\t':
+			if inToken {
+				tokens = append(tokens, commandToken{start, buf.String()})
+				buf.Reset()
+				inToken = false
+			}
+			continue
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '\\':
+			escaped = true
+		default:
+			buf.WriteRune(r)
+		}
+		if !inToken {
+			inToken = true
+			start = i
+		}
+	}
+	if inToken {
+		tokens = append(tokens, commandToken{start, buf.String()})
+	}
+	return tokens
+}
+
+// quoteArg wraps the argument in quotes if necessary, so it is read back as is by splitCommandLine.
+func quoteArg(arg string) string {
+	if arg != "" && !strings.ContainsAny(arg, " \t\"'\\") {
+		return arg
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(arg) + `"`
+}
+
+// joinArgs makes the command line from arguments.
+func joinArgs(args []string) string {
+	quoted := make([]string, len(args))
+	for i := range args {
+		quoted[i] = quoteArg(args[i])
+	}
+	return strings.Join(quoted, " ")
+}
+
+// CompleteCommand returns completion candidates for the last word of the command line
+// and byte offset of the word to be replaced by the candidate.
+func (a *App) CompleteCommand(line string) (int, []string) {
+	tokens := splitCommandLine(line)
+	// the word being completed is a new one if any char typed would start a new word
+	if next := splitCommandLine(line + "x"); next[len(next)-1].start == len(line) {
+		tokens = append(tokens, commandToken{len(line), ""})
+	}
+	last := tokens[len(tokens)-1]
+	var options []string
+	switch {
+	case len(tokens) == 1:
+		options = commandNames
+	case len(tokens) == 3 && tokens[0].value == "bind":
+		options = commandNames
+	default:
+		switch tokens[0].value {
+		case "sheet", "split", "vsplit":
+			for _, s := range a.doc.Sheets {
+				options = append(options, s.Title)
+			}
+		case "name", "deleteName":
+			options = a.doc.Names()
+		case "set":
+			options = optionNames
+		case "unbind", "map":
+			options = a.boundKeys()
+		case "filter":
+			if x, err := columnByName(tokens[1].value); err == nil && len(tokens) > 2 {
+				options = a.doc.FilterValues(a.doc.CurrentSheet, x)
+			}
+		case "w", "write":
+			return last.start, completeFilename(last.value)
+		}
+	}
+	var candidates []string
+	for _, o := range options {
+		if strings.HasPrefix(o, last.value) {
+			candidates = append(candidates, quoteArg(o))
+		}
+	}
+	return last.start, candidates
+}
+
+// completeFilename returns paths starting with the prefix, directories end with a slash.
+func completeFilename(prefix string) []string {
+	dir, base := filepath.Split(prefix)
+	readDir := dir
+	if readDir == "" {
+		readDir = "."
+	} else if strings.HasPrefix(readDir, "~/") {
+		readDir = os.Getenv("HOME") + readDir[1:]
+	}
+	files, err := ioutil.ReadDir(readDir)
+	if err != nil {
+		return nil
+	}
+	var candidates []string
+	for _, f := range files {
+		name := f.Name()
+		if !strings.HasPrefix(name, base) || (strings.HasPrefix(name, ".") && !strings.HasPrefix(base, ".")) {
+			continue
+		}
+		if f.IsDir() {
+			name += "/"
+		}
+		candidates = append(candidates, quoteArg(dir+name))
+	}
+	sort.Strings(candidates)
+	return candidates
+}
@@ -0,0 +1,100 @@
+package app
+
+import (
+	"xl/document"
+
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitCommandLine(t *testing.T) {
+	testCases := []struct {
+		line   string
+		tokens []commandToken
+	}{
+		{``, nil},
+		{`   `, nil},
+		{`q`, []commandToken{{0, "q"}}},
+		{`go  A1 `, []commandToken{{0, "go"}, {4, "A1"}}},
+		{"set\tvim", []commandToken{{0, "set"}, {4, "vim"}}},
+		{`sheet "My Sheet"`, []commandToken{{0, "sheet"}, {6, "My Sheet"}}},
+		{`sheet 'My Sheet'`, []commandToken{{0, "sheet"}, {6, "My Sheet"}}},
+		{`note "say \"hi\""`, []commandToken{{0, "note"}, {5, `say "hi"`}}},
+		{`note 'a\b'`, []commandToken{{0, "note"}, {5, `a\b`}}},
+		{`note "it's"`, []commandToken{{0, "note"}, {5, "it's"}}},
+		{`note a\ b`, []commandToken{{0, "note"}, {5, "a b"}}},
+		{`note pre"fix"`, []commandToken{{0, "note"}, {5, "prefix"}}},
+		{`note ""`, []commandToken{{0, "note"}, {5, ""}}},
+		{`sheet "My Sh`, []commandToken{{0, "sheet"}, {6, "My Sh"}}},
+		{`sheet "My `, []commandToken{{0, "sheet"}, {6, "My "}}},
+		{`note a\`, []commandToken{{0, "note"}, {5, "a"}}},
+	}
+	for _, c := range testCases {
+		assert.Equalf(t, c.tokens, splitCommandLine(c.line), "case %s", c.line)
+	}
+}
+
+func TestQuoteArg(t *testing.T) {
+	testCases := []struct {
+		arg    string
+		quoted string
+	}{
+		{`A1`, `A1`},
+		{``, `""`},
+		{`My Sheet`, `"My Sheet"`},
+		{"a\tb", "\"a\tb\""},
+		{`say "hi"`, `"say \"hi\""`},
+		{`it's`, `"it's"`},
+		{`a\b`, `"a\\b"`},
+	}
+	for _, c := range testCases {
+		assert.Equalf(t, c.quoted, quoteArg(c.arg), "case %s", c.arg)
+	}
+}
+
+func TestJoinArgs(t *testing.T) {
+	testCases := [][]string{
+		{"go", "A1"},
+		{"sheet", "My Sheet"},
+		{"note", `say "hi"`, `it's`, `a\b`, ""},
+		{"bind", "<C-x>", "style bold"},
+	}
+	for _, args := range testCases {
+		line := joinArgs(args)
+		var values []string
+		for _, token := range splitCommandLine(line) {
+			values = append(values, token.value)
+		}
+		assert.Equalf(t, args, values, "case %s", line)
+	}
+}
+
+func TestCompleteCommand(t *testing.T) {
+	a := &App{doc: document.NewWithEmptySheet(), hotKeys: map[string]string{"<C-x>": "q"}}
+	_, err := a.doc.NewSheet("Summary")
+	assert.NoError(t, err)
+	testCases := []struct {
+		line       string
+		offset     int
+		candidates []string
+	}{
+		{``, 0, commandNames},
+		{`qu`, 0, []string{"quit"}},
+		{`unh`, 0, []string{"unhideCol", "unhideRow"}},
+		{`xyz`, 0, nil},
+		{`set `, 4, optionNames},
+		{`set  novi`, 5, []string{"novim"}},
+		{`sheet S`, 6, []string{`"Sheet 1"`, "Summary"}},
+		{`sheet "Sheet`, 6, []string{`"Sheet 1"`}},
+		{`sheet "Sheet 1" `, 16, []string{`"Sheet 1"`, "Summary"}},
+		{`sheet "Sheet `, 6, []string{`"Sheet 1"`}},
+		{`bind <C-y> sty`, 11, []string{"style"}},
+		{`unbind `, 7, []string{"<C-x>"}},
+	}
+	for _, c := range testCases {
+		offset, candidates := a.CompleteCommand(c.line)
+		assert.Equalf(t, c.offset, offset, "case %s", c.line)
+		assert.Equalf(t, c.candidates, candidates, "case %s", c.line)
+	}
+}
@@ -0,0 +1,156 @@
+package app
+
+import (
+	"xl/document"
+	"xl/document/sheet"
+	"xl/ui"
+
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// cmdCF manages conditional formatting rules of the current sheet: "cf add [RANGE] CONDITION STYLE..." adds the rule
+// for the range or the selection, "cf delete N" deletes the rule number N, "cf clear" deletes all rules, "cf list"
+// lists them, the first one has the highest priority. CONDITION is a comparison like ">10", "top N", "bottom N",
+// "duplicates", "scale COLOR COLOR" or "formula =B2>C2" written for the top left cell of the range.
+// STYLE is like in cmdStyle.
+func (a *App) cmdCF(args []string) {
+	s := a.doc.CurrentSheet
+	if len(args) == 0 || args[0] == "list" {
+		a.listRules(s)
+		return
+	}
+	switch args[0] {
+	case "add":
+		r, err := a.parseRule(args[1:])
+		if err != nil {
+			a.showError(err)
+			return
+		}
+		s.Rules = append(s.Rules, r)
+	case "delete":
+		n, err := strconv.Atoi(arg1(args[1:]))
+		if err != nil || n < 1 || n > len(s.Rules) {
+			a.output.SetStatus("invalid rule number", ui.StatusFlagError)
+			return
+		}
+		s.Rules = append(s.Rules[:n-1], s.Rules[n:]...)
+	case "clear":
+		s.Rules = nil
+	default:
+		a.output.SetStatus(fmt.Sprintf("unknown cf command %s", args[0]), ui.StatusFlagError)
+		return
+	}
+	a.output.SetDirty(ui.DirtyGrid)
+}
+
+// listRules shows conditional formatting rules of the sheet in the status line.
+func (a *App) listRules(s *sheet.Sheet) {
+	if len(s.Rules) == 0 {
+		a.output.SetStatus("no conditional formatting rules", 0)
+		return
+	}
+	var lines []string
+	for i, r := range s.Rules {
+		lines = append(lines, fmt.Sprintf("%d: %s %s", i+1, rangeName(r.Range), ruleDescription(r)))
+	}
+	a.output.SetStatus(strings.Join(lines, "; "), 0)
+}
+
+// parseRule parses arguments of "cf add".
+func (a *App) parseRule(args []string) (*sheet.ConditionalRule, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("condition expected")
+	}
+	r := &sheet.ConditionalRule{Range: a.selectedRect()}
+	if rng, err := a.cellRange(args[0]); err == nil && !sheet.IsFilterCondition(args[0]) {
+		r.Range = rng
+		args = args[1:]
+	}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("condition expected")
+	}
+	switch cond := args[0]; {
+	case sheet.IsFilterCondition(cond):
+		r.Kind = sheet.RuleCompare
+		r.Condition = sheet.ParseFilterCondition(cond)
+		args = args[1:]
+	case cond == "top" || cond == "bottom":
+		r.Kind = sheet.RuleTop
+		if cond == "bottom" {
+			r.Kind = sheet.RuleBottom
+		}
+		if len(args) < 2 {
+			return nil, fmt.Errorf("number of values expected")
+		}
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid number of values %s", args[1])
+		}
+		r.N = n
+		args = args[2:]
+	case cond == "duplicates":
+		r.Kind = sheet.RuleDuplicate
+		args = args[1:]
+	case cond == "scale":
+		r.Kind = sheet.RuleColorScale
+		if len(args) != 3 {
+			return nil, fmt.Errorf("two colors expected")
+		}
+		var err error
+		if r.MinColor, err = parseColor(args[1]); err != nil {
+			return nil, err
+		}
+		if r.MaxColor, err = parseColor(args[2]); err != nil {
+			return nil, err
+		}
+		return r, nil
+	case cond == "formula":
+		r.Kind = sheet.RuleFormula
+		if len(args) < 2 || !strings.HasPrefix(args[1], "=") {
+			return nil, fmt.Errorf("formula expected")
+		}
+		r.Formula = args[1]
+		args = args[2:]
+	default:
+		return nil, fmt.Errorf("unknown condition %s", cond)
+	}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("style expected")
+	}
+	for _, arg := range args {
+		change, err := parseStyleChange(arg)
+		if err != nil {
+			return nil, err
+		}
+		change(&r.Style)
+	}
+	return r, nil
+}
+
+// ruleDescription describes the condition and the style of the rule.
+func ruleDescription(r *sheet.ConditionalRule) string {
+	switch r.Kind {
+	case sheet.RuleColorScale:
+		return fmt.Sprintf("scale %s %s", r.MinColor, r.MaxColor)
+	case sheet.RuleTop:
+		return fmt.Sprintf("top %d: %s", r.N, styleDescription(r.Style))
+	case sheet.RuleBottom:
+		return fmt.Sprintf("bottom %d: %s", r.N, styleDescription(r.Style))
+	case sheet.RuleDuplicate:
+		return "duplicates: " + styleDescription(r.Style)
+	case sheet.RuleFormula:
+		return fmt.Sprintf("formula %s: %s", r.Formula, styleDescription(r.Style))
+	}
+	return fmt.Sprintf("%s: %s", r.Condition, styleDescription(r.Style))
+}
+
+// rangeName returns the name of the range like "A1:C3" or "B2" for a single cell.
+func rangeName(r sheet.Rect) string {
+	name := document.CellName(r.X, r.Y)
+	if r.Width > 1 || r.Height > 1 {
+		name += ":" + document.CellName(r.MaxX(), r.MaxY())
+	}
+	return name
+}
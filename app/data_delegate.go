@@ -3,53 +3,87 @@ package app
 import (
 	"xl/document"
 	"xl/document/eval"
+	"xl/document/sheet"
+	"xl/formula"
 	"xl/ui"
 )
 
 // Callbacks collection providing data to be displayed.
 
-func (a *App) CellView(x, y int) *ui.CellView {
-	c := a.doc.CurrentSheet.Cell(x, y)
-	if c == nil {
-		return &ui.CellView{
-			Name: document.CellName(x, y),
-		}
-	}
-	v, err := c.StringValue(eval.NewContext(a.doc, a.doc.CurrentSheet.Idx))
+func (a *App) CellView(sheetN, x, y int) *ui.CellView {
+	s := a.doc.Sheets[sheetN]
+	cell := eval.Cell{SheetIdx: s.Idx, X: x, Y: y}
+	ec := eval.NewContext(a.doc, cell.SheetIdx)
+	v, t, err := a.doc.FormattedValue(ec, cell)
 	if err != nil {
-		t := err.Error()
-		return &ui.CellView{
+		e := err.Error()
+		cv := &ui.CellView{
 			Name:  document.CellName(x, y),
-			Error: &t,
+			Error: &e,
 		}
+		cv.Merge, _ = s.MergedRect(x, y)
+		cv.Note = s.Note(x, y) != ""
+		return cv
 	}
-	return &ui.CellView{
+	cv := &ui.CellView{
 		Name:        document.CellName(x, y),
 		DisplayText: v,
-		Expression:  c.Expression(eval.NewContext(a.doc, a.doc.CurrentSheet.Idx)),
+		Style:       s.CellStyle(x, y).Overlay(a.doc.ConditionalStyle(s, x, y)),
+	}
+	cv.Merge, _ = s.MergedRect(x, y)
+	cv.Note = s.Note(x, y) != ""
+	if cv.Style.Align == sheet.AlignGeneral {
+		// like in Excel, numbers go to the right and booleans to the center
+		switch t {
+		case eval.TypeDecimal:
+			cv.Style.Align = sheet.AlignRight
+		case eval.TypeBool:
+			cv.Style.Align = sheet.AlignCenter
+		default:
+			cv.Style.Align = sheet.AlignLeft
+		}
+	}
+	c := s.Cell(x, y)
+	cv.Match = a.cellMatches(c, v)
+	if c != nil && c.RawValue() != "" {
+		cv.Expression = c.Expression(ec)
+	} else {
+		cv.ReadOnly = a.doc.Spilled(cell)
 	}
+	return cv
 }
 
-func (a *App) RowView(n int) *ui.RowView {
+func (a *App) RowView(sheet, n int) *ui.RowView {
+	s := a.doc.Sheets[sheet]
 	return &ui.RowView{
 		Name:   document.RowName(n),
-		Height: a.doc.CurrentSheet.RowSize(n),
+		Height: s.RowSize(n),
+		Hidden: s.RowHidden(n),
 	}
 }
 
-func (a *App) ColView(n int) *ui.ColView {
-	return &ui.ColView{
-		Name:  document.ColName(n),
-		Width: a.doc.CurrentSheet.ColSize(n),
+func (a *App) ColView(sheet, n int) *ui.ColView {
+	s := a.doc.Sheets[sheet]
+	cv := &ui.ColView{
+		Name:   document.ColName(n),
+		Width:  s.ColSize(n),
+		Hidden: s.ColHidden(n),
+	}
+	if f := s.Filter; f != nil && n >= f.Range.X && n <= f.Range.MaxX() {
+		cv.Filter = true
+		cv.Filtered = f.Criteria[n] != nil
 	}
+	return cv
 }
 
 func (a *App) SheetView() *ui.SheetView {
 	c := a.doc.CurrentSheet.CellUnderCursor()
 	sv := &ui.SheetView{
-		Name:     a.doc.CurrentSheet.Title,
-		Cursor:   a.doc.CurrentSheet.Cursor,
-		Viewport: a.doc.CurrentSheet.Viewport,
+		Sheet:     a.doc.CurrentSheetN,
+		Name:      a.doc.CurrentSheet.Title,
+		Cursor:    a.doc.CurrentSheet.Cursor,
+		Viewport:  a.doc.CurrentSheet.Viewport,
+		Selection: a.doc.CurrentSheet.Selection,
 	}
 	if c != nil {
 		sv.FormulaLineView = ui.FormulaLineView{
@@ -60,7 +94,35 @@ func (a *App) SheetView() *ui.SheetView {
 	return sv
 }
 
+func (a *App) WindowsView() *ui.WindowsView {
+	wv := &ui.WindowsView{
+		Windows: make([]*ui.SheetView, len(a.windows)),
+		Active:  a.activeWindow,
+		Layout:  a.layout,
+	}
+	for i, w := range a.windows {
+		if i == a.activeWindow {
+			wv.Windows[i] = a.SheetView()
+			continue
+		}
+		wv.Windows[i] = &ui.SheetView{
+			Name:      w.sheet.Title,
+			Cursor:    w.cursor,
+			Viewport:  w.viewport,
+			Selection: w.selection,
+		}
+		for n, s := range a.doc.Sheets {
+			if s == w.sheet {
+				wv.Windows[i].Sheet = n
+			}
+		}
+	}
+	return wv
+}
+
 func (a *App) DocView() *ui.DocView {
+	// the view is requested once per refresh, cells may have been changed since the last one
+	a.doc.ResetRuleStats()
 	sheetNames := make([]string, len(a.doc.Sheets))
 	currentSheetIdx := 0
 	for i, s := range a.doc.Sheets {
@@ -74,3 +136,36 @@ func (a *App) DocView() *ui.DocView {
 		CurrentSheetIdx: currentSheetIdx,
 	}
 }
+
+func (a *App) FormulaRefs(source string) []ui.FormulaRef {
+	refs := formula.References(source)
+	res := make([]ui.FormulaRef, len(refs))
+	for i, r := range refs {
+		res[i] = ui.FormulaRef{
+			Offset: r.Offset,
+			Length: r.Length,
+		}
+		if r.Sheet != "" && r.Sheet != a.doc.CurrentSheet.Title {
+			continue
+		}
+		x, y, err := document.CellAxis(r.CellFrom)
+		if err != nil {
+			continue
+		}
+		x2, y2 := x, y
+		if r.CellTo != "" {
+			if x2, y2, err = document.CellAxis(r.CellTo); err != nil {
+				continue
+			}
+		}
+		if x2 < x {
+			x, x2 = x2, x
+		}
+		if y2 < y {
+			y, y2 = y2, y
+		}
+		res[i].CurrentSheet = true
+		res[i].X, res[i].Y, res[i].X2, res[i].Y2 = x, y, x2, y2
+	}
+	return res
+}
@@ -0,0 +1,106 @@
+package app
+
+import (
+	"xl/document"
+	"xl/document/sheet"
+	"xl/ui"
+
+	"fmt"
+	"strings"
+)
+
+// cmdFilter manages the autofilter of the current sheet. With no arguments it turns the filter on for the selection
+// or used cells (the first row is a header), or applies the existing filter again. "filter off" removes the filter,
+// "filter COL v1 v2" shows rows having one of the values in the column, "filter COL >10 <=100" shows rows meeting
+// all the conditions ("=a*" may contain wildcards), "filter COL clear" removes criteria of the column.
+func (a *App) cmdFilter(args []string) {
+	s := a.doc.CurrentSheet
+	defer a.output.SetDirty(ui.DirtyHRuler | ui.DirtyVRuler | ui.DirtyGrid)
+	if len(args) == 0 {
+		if s.Filter != nil {
+			a.doc.ApplyFilter(s)
+			a.moveCursorTo(s.Cursor.X, s.Cursor.Y)
+			a.output.SetStatus(filterDescription(s.Filter), 0)
+			return
+		}
+		r := s.Selection
+		if r.Width == 0 {
+			r = s.UsedRect()
+		}
+		if r.Height < 2 {
+			a.output.SetStatus("filter needs a header row and data below it", ui.StatusFlagError)
+			return
+		}
+		s.Filter = sheet.NewAutoFilter(r)
+		a.doc.ApplyFilter(s)
+		return
+	}
+	if args[0] == "off" {
+		s.Filter = nil
+		a.doc.ApplyFilter(s)
+		return
+	}
+	if s.Filter == nil {
+		a.output.SetStatus("sheet has no filter", ui.StatusFlagError)
+		return
+	}
+	x, err := columnByName(args[0])
+	if err != nil {
+		a.showError(err)
+		return
+	}
+	if x < s.Filter.Range.X || x > s.Filter.Range.MaxX() {
+		a.output.SetStatus(fmt.Sprintf("column %s is out of the filter range", args[0]), ui.StatusFlagError)
+		return
+	}
+	switch {
+	case len(args) == 1:
+		a.output.SetStatus(strings.Join(a.doc.FilterValues(s, x), ", "), 0)
+		return
+	case len(args) == 2 && args[1] == "clear":
+		delete(s.Filter.Criteria, x)
+	case sheet.IsFilterCondition(args[1]):
+		c := &sheet.FilterCriteria{}
+		for _, arg := range args[1:] {
+			c.Conditions = append(c.Conditions, sheet.ParseFilterCondition(arg))
+		}
+		s.Filter.Criteria[x] = c
+	default:
+		s.Filter.Criteria[x] = &sheet.FilterCriteria{Values: args[1:]}
+	}
+	a.doc.ApplyFilter(s)
+	// the cursor must not stay on a hidden row
+	a.moveCursorTo(s.Cursor.X, s.Cursor.Y)
+}
+
+// filterDescription lists the filter criteria.
+func filterDescription(f *sheet.AutoFilter) string {
+	var desc []string
+	for x := f.Range.X; x <= f.Range.MaxX(); x++ {
+		c := f.Criteria[x]
+		if c == nil {
+			continue
+		}
+		var parts []string
+		if len(c.Values) > 0 {
+			parts = append(parts, strings.Join(c.Values, "|"))
+		}
+		for _, cond := range c.Conditions {
+			parts = append(parts, cond.String())
+		}
+		desc = append(desc, document.ColName(x)+": "+strings.Join(parts, " "))
+	}
+	if len(desc) == 0 {
+		return "filter has no criteria"
+	}
+	return strings.Join(desc, ", ")
+}
+
+// columnByName returns X of the column with given name like "B" or "aa".
+func columnByName(name string) (int, error) {
+	x, y, err := document.CellAxis(strings.ToUpper(name) + "1")
+	if err != nil || y != 0 {
+		return 0, fmt.Errorf("invalid column %s", name)
+	}
+	return x, nil
+}
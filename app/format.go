@@ -0,0 +1,180 @@
+package app
+
+import (
+	"xl/document/sheet"
+	"xl/ui"
+
+	"fmt"
+	"strings"
+
+	"github.com/gdamore/tcell"
+)
+
+// cmdFormat sets the number format like "#,##0.00", "0%" or "yyyy-mm-dd" for selected cells or the cell under cursor,
+// "general" removes the format. With no arguments shows the format of the cell under cursor.
+func (a *App) cmdFormat(code string) {
+	s := a.doc.CurrentSheet
+	if code == "" {
+		a.output.SetStatus(formatDescription(s.CellFormat(s.Cursor.X, s.Cursor.Y)), 0)
+		return
+	}
+	if strings.EqualFold(code, "general") {
+		code = ""
+	}
+	r := a.selectedRect()
+	for x := r.X; x <= r.MaxX(); x++ {
+		for y := r.Y; y <= r.MaxY(); y++ {
+			c := s.Cell(x, y)
+			if c == nil {
+				if code == "" {
+					continue
+				}
+				c = sheet.NewCellEmpty()
+				s.SetCell(x, y, c)
+				c = s.Cell(x, y)
+			}
+			c.SetFormat(code)
+		}
+	}
+	a.output.SetDirty(ui.DirtyGrid)
+}
+
+// cmdColFormat sets the number format for whole columns given like "B:D", selected ones or the one under cursor.
+// Cells having own formats keep them.
+func (a *App) cmdColFormat(args []string) {
+	s := a.doc.CurrentSheet
+	code := arg1(args)
+	if code == "" {
+		a.output.SetStatus(formatDescription(s.ColFormat(s.Cursor.X)), 0)
+		return
+	}
+	if strings.EqualFold(code, "general") {
+		code = ""
+	}
+	from, to, err := a.outlineRange(false, argN(args, 2))
+	if err != nil {
+		a.showError(err)
+		return
+	}
+	for x := from; x <= to; x++ {
+		s.SetColFormat(x, code)
+	}
+	a.output.SetDirty(ui.DirtyGrid)
+}
+
+// cmdStyle changes the style of selected cells or the cell under cursor. Arguments are "bold", "italic"
+// and "underline" or the same with "no" prefix, "left", "center", "right" or "general" alignment, "fg=COLOR"
+// and "bg=COLOR" where the color is a name like "red" or like "#ff8000", empty one resets the color.
+// "clear" resets the whole style. With no arguments shows the style of the cell under cursor.
+func (a *App) cmdStyle(args []string) {
+	s := a.doc.CurrentSheet
+	if len(args) == 0 {
+		a.output.SetStatus(styleDescription(s.CellStyle(s.Cursor.X, s.Cursor.Y)), 0)
+		return
+	}
+	var changes []func(*sheet.Style)
+	for _, arg := range args {
+		change, err := parseStyleChange(arg)
+		if err != nil {
+			a.showError(err)
+			return
+		}
+		changes = append(changes, change)
+	}
+	s.SetRangeStyle(a.selectedRect(), func(st sheet.Style) sheet.Style {
+		for _, change := range changes {
+			change(&st)
+		}
+		return st
+	})
+	a.output.SetDirty(ui.DirtyGrid)
+}
+
+// parseStyleChange returns the function changing the style as the argument of cmdStyle says.
+func parseStyleChange(arg string) (func(*sheet.Style), error) {
+	switch arg {
+	case "bold", "nobold":
+		return func(st *sheet.Style) { st.Bold = arg == "bold" }, nil
+	case "italic", "noitalic":
+		return func(st *sheet.Style) { st.Italic = arg == "italic" }, nil
+	case "underline", "nounderline":
+		return func(st *sheet.Style) { st.Underline = arg == "underline" }, nil
+	case "clear":
+		return func(st *sheet.Style) { *st = sheet.Style{} }, nil
+	}
+	for i, name := range alignNames {
+		if arg == name {
+			return func(st *sheet.Style) { st.Align = i }, nil
+		}
+	}
+	parts := strings.SplitN(arg, "=", 2)
+	if len(parts) != 2 || (parts[0] != "fg" && parts[0] != "bg") {
+		return nil, fmt.Errorf("unknown style %s", arg)
+	}
+	color := ""
+	if parts[1] != "" {
+		var err error
+		if color, err = parseColor(parts[1]); err != nil {
+			return nil, err
+		}
+	}
+	if parts[0] == "fg" {
+		return func(st *sheet.Style) { st.Fg = color }, nil
+	}
+	return func(st *sheet.Style) { st.Bg = color }, nil
+}
+
+// parseColor converts the color name like "red" or "#ff0000" to the form used in sheet.Style.
+func parseColor(name string) (string, error) {
+	c := tcell.GetColor(strings.ToLower(name))
+	if c == tcell.ColorDefault {
+		return "", fmt.Errorf("unknown color %s", name)
+	}
+	return fmt.Sprintf("#%06x", c.Hex()), nil
+}
+
+// alignNames are names of sheet.Align* constants.
+var alignNames = []string{"general", "left", "center", "right"}
+
+// styleDescription lists style settings to show in the status line.
+func styleDescription(st sheet.Style) string {
+	if st.IsDefault() {
+		return "default style"
+	}
+	var desc []string
+	if st.Align != sheet.AlignGeneral {
+		desc = append(desc, alignNames[st.Align])
+	}
+	for _, flag := range []struct {
+		on   bool
+		name string
+	}{{st.Bold, "bold"}, {st.Italic, "italic"}, {st.Underline, "underline"}} {
+		if flag.on {
+			desc = append(desc, flag.name)
+		}
+	}
+	if st.Fg != "" {
+		desc = append(desc, "fg="+st.Fg)
+	}
+	if st.Bg != "" {
+		desc = append(desc, "bg="+st.Bg)
+	}
+	return strings.Join(desc, " ")
+}
+
+// selectedRect returns the selection or the cell under cursor if nothing is selected.
+func (a *App) selectedRect() sheet.Rect {
+	s := a.doc.CurrentSheet
+	if s.Selection.Width == 0 {
+		return sheet.Rect{X: s.Cursor.X, Y: s.Cursor.Y, Width: 1, Height: 1}
+	}
+	return s.Selection
+}
+
+// formatDescription returns the format code to show in the status line.
+func formatDescription(code string) string {
+	if code == "" {
+		return "General"
+	}
+	return code
+}
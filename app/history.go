@@ -0,0 +1,58 @@
+package app
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+const (
+	historyFile = ".xl_history"
+	// maxHistory is a number of commands kept in the history file.
+	maxHistory = 1000
+)
+
+// historyLocation returns path of the command history file.
+func historyLocation() string {
+	return os.Getenv("HOME") + "/" + historyFile
+}
+
+// loadHistory reads previously entered commands and passes them to the command line.
+func (a *App) loadHistory() {
+	data, err := ioutil.ReadFile(historyLocation())
+	if err != nil {
+		// no history yet
+		return
+	}
+	var history []string
+	for _, l := range strings.Split(string(data), "\n") {
+		if l != "" {
+			history = append(history, l)
+		}
+	}
+	if len(history) > maxHistory {
+		history = history[len(history)-maxHistory:]
+		// keep the file small
+		data := []byte(strings.Join(history, "\n") + "\n")
+		if err := ioutil.WriteFile(historyLocation(), data, 0600); err != nil {
+			a.logger.Error(err.Error())
+		}
+	}
+	a.output.SetCommandHistory(history)
+}
+
+// appendHistory saves the command to the history file.
+func (a *App) appendHistory(command string) {
+	if strings.TrimSpace(command) == "" {
+		return
+	}
+	f, err := os.OpenFile(historyLocation(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		a.logger.Error(err.Error())
+		return
+	}
+	defer f.Close()
+	if _, err := f.WriteString(command + "\n"); err != nil {
+		a.logger.Error(err.Error())
+	}
+}
@@ -1,6 +1,13 @@
 package app
 
 import (
+	"xl/ui"
+
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
 	"github.com/gdamore/tcell"
 )
 
@@ -10,34 +17,194 @@ type Key struct {
 	Ch  rune
 }
 
-var HotKeys = map[string]Key{
-	"a": {tcell.ModNone, tcell.KeyRune, 'a'},
-	"b": {tcell.ModNone, tcell.KeyRune, 'b'},
-	"c": {tcell.ModNone, tcell.KeyRune, 'c'},
-	"d": {tcell.ModNone, tcell.KeyRune, 'd'},
-	"e": {tcell.ModNone, tcell.KeyRune, 'e'},
-	"f": {tcell.ModNone, tcell.KeyRune, 'f'},
-	"g": {tcell.ModNone, tcell.KeyRune, 'g'},
-	"h": {tcell.ModNone, tcell.KeyRune, 'h'},
-	"i": {tcell.ModNone, tcell.KeyRune, 'i'},
-	"j": {tcell.ModNone, tcell.KeyRune, 'j'},
-	"k": {tcell.ModNone, tcell.KeyRune, 'k'},
-	"l": {tcell.ModNone, tcell.KeyRune, 'l'},
-	"m": {tcell.ModNone, tcell.KeyRune, 'm'},
-	"n": {tcell.ModNone, tcell.KeyRune, 'n'},
-	"o": {tcell.ModNone, tcell.KeyRune, 'o'},
-	"p": {tcell.ModNone, tcell.KeyRune, 'p'},
-	"q": {tcell.ModNone, tcell.KeyRune, 'q'},
-	"r": {tcell.ModNone, tcell.KeyRune, 'r'},
-	"s": {tcell.ModNone, tcell.KeyRune, 's'},
-	"t": {tcell.ModNone, tcell.KeyRune, 't'},
-	"u": {tcell.ModNone, tcell.KeyRune, 'u'},
-	"v": {tcell.ModNone, tcell.KeyRune, 'v'},
-	"w": {tcell.ModNone, tcell.KeyRune, 'w'},
-	"x": {tcell.ModNone, tcell.KeyRune, 'x'},
-	"y": {tcell.ModNone, tcell.KeyRune, 'y'},
-	"z": {tcell.ModNone, tcell.KeyRune, 'z'},
-	">": {tcell.ModNone, tcell.KeyRune, '>'},
-	"}": {tcell.ModNone, tcell.KeyRune, '}'},
-	"{": {tcell.ModNone, tcell.KeyRune, '{'},
+// specialKeys maps names used in <...> notation to keys, the first name of a key is used for formatting.
+var specialKeys = []struct {
+	name string
+	key  tcell.Key
+}{
+	{"Tab", tcell.KeyTab},
+	{"S-Tab", tcell.KeyBacktab},
+	{"CR", tcell.KeyEnter},
+	{"Enter", tcell.KeyEnter},
+	{"Return", tcell.KeyEnter},
+	{"Esc", tcell.KeyEsc},
+	{"BS", tcell.KeyBackspace2},
+	{"Backspace", tcell.KeyBackspace2},
+	{"Del", tcell.KeyDelete},
+	{"Delete", tcell.KeyDelete},
+	{"Insert", tcell.KeyInsert},
+	{"Up", tcell.KeyUp},
+	{"Down", tcell.KeyDown},
+	{"Left", tcell.KeyLeft},
+	{"Right", tcell.KeyRight},
+	{"Home", tcell.KeyHome},
+	{"End", tcell.KeyEnd},
+	{"PageUp", tcell.KeyPgUp},
+	{"PageDown", tcell.KeyPgDn},
+}
+
+// specialRunes maps names used in <...> notation to characters which can't be typed as is.
+var specialRunes = []struct {
+	name string
+	ch   rune
+}{
+	{"Space", ' '},
+	{"lt", '<'},
+}
+
+// NewKey makes a key from the key event.
+func NewKey(event ui.KeyEvent) Key {
+	return Key{event.Mod, event.Key, event.Ch}.normalize()
+}
+
+// event makes the key event which could be read for the key.
+func (k Key) event() ui.KeyEvent {
+	return ui.KeyEvent{Mod: k.Mod, Key: k.Key, Ch: k.Ch}
+}
+
+// normalize makes the same key pressed in different terminals or written in different notations
+// give the same value.
+func (k Key) normalize() Key {
+	if k.Key == tcell.KeyRune {
+		// shift is already applied to the char
+		k.Mod &^= tcell.ModShift
+		return k
+	}
+	k.Ch = 0
+	if k.Key <= tcell.KeyUS || k.Key == tcell.KeyBackspace2 {
+		// control chars are typed with Ctrl
+		k.Mod &^= tcell.ModCtrl
+	}
+	if k.Key == tcell.KeyBackspace {
+		k.Key = tcell.KeyBackspace2
+	}
+	return k
+}
+
+// ParseKeys parses key sequence notation like "gg", "<C-s>", "<F2>", "<S-Tab>" or "<A-x>".
+func ParseKeys(notation string) ([]Key, error) {
+	var keys []Key
+	for rest := notation; rest != ""; {
+		if rest[0] == '<' {
+			if end := strings.IndexByte(rest, '>'); end > 1 {
+				k, err := parseSpecialKey(rest[1:end])
+				if err != nil {
+					return nil, err
+				}
+				keys = append(keys, k.normalize())
+				rest = rest[end+1:]
+				continue
+			}
+		}
+		r, n := utf8.DecodeRuneInString(rest)
+		keys = append(keys, Key{tcell.ModNone, tcell.KeyRune, r})
+		rest = rest[n:]
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("key must be specified")
+	}
+	return keys, nil
+}
+
+// parseSpecialKey parses the key notation inside angle brackets.
+func parseSpecialKey(s string) (Key, error) {
+	var mod tcell.ModMask
+	name := s
+	for len(name) > 2 && name[1] == '-' {
+		switch unicode.ToUpper(rune(name[0])) {
+		case 'C':
+			mod |= tcell.ModCtrl
+		case 'A', 'M':
+			mod |= tcell.ModAlt
+		case 'S':
+			mod |= tcell.ModShift
+		default:
+			return Key{}, fmt.Errorf("key %s is not a valid key", s)
+		}
+		name = name[2:]
+	}
+
+	if r, n := utf8.DecodeRuneInString(name); n == len(name) {
+		switch {
+		case mod&tcell.ModCtrl != 0 && unicode.IsLetter(r) && r < unicode.MaxASCII:
+			return Key{mod &^ (tcell.ModCtrl | tcell.ModShift), tcell.KeyCtrlA + tcell.Key(unicode.ToLower(r)-'a'), 0}, nil
+		case mod&tcell.ModShift != 0:
+			r = unicode.ToUpper(r)
+		}
+		return Key{mod &^ tcell.ModShift, tcell.KeyRune, r}, nil
+	}
+	for _, sr := range specialRunes {
+		if strings.EqualFold(sr.name, name) {
+			return Key{mod &^ tcell.ModShift, tcell.KeyRune, sr.ch}, nil
+		}
+	}
+	if strings.EqualFold(name, "Tab") && mod&tcell.ModShift != 0 {
+		return Key{mod &^ tcell.ModShift, tcell.KeyBacktab, 0}, nil
+	}
+	for _, sk := range specialKeys {
+		if strings.EqualFold(sk.name, name) {
+			return Key{mod, sk.key, 0}, nil
+		}
+	}
+	var n int
+	if _, err := fmt.Sscanf(strings.ToUpper(name), "F%d", &n); err == nil && n >= 1 && n <= 64 {
+		return Key{mod, tcell.KeyF1 + tcell.Key(n-1), 0}, nil
+	}
+	return Key{}, fmt.Errorf("key %s is not a valid key", s)
+}
+
+// FormatKeys returns the notation of the key sequence, the same for all notations of the same keys.
+func FormatKeys(keys []Key) string {
+	var b strings.Builder
+	for _, k := range keys {
+		b.WriteString(formatKey(k))
+	}
+	return b.String()
+}
+
+// formatKey returns the notation of a single key.
+func formatKey(k Key) string {
+	var mods string
+	if k.Mod&tcell.ModCtrl != 0 {
+		mods += "C-"
+	}
+	if k.Mod&tcell.ModAlt != 0 {
+		mods += "A-"
+	}
+	if k.Mod&tcell.ModShift != 0 {
+		mods += "S-"
+	}
+
+	name := ""
+	switch {
+	case k.Key == tcell.KeyRune:
+		for _, sr := range specialRunes {
+			if sr.ch == k.Ch {
+				name = sr.name
+				break
+			}
+		}
+		if name == "" {
+			if mods == "" {
+				return string(k.Ch)
+			}
+			name = string(k.Ch)
+		}
+	case k.Key >= tcell.KeyCtrlA && k.Key <= tcell.KeyCtrlZ && k.Key != tcell.KeyTab && k.Key != tcell.KeyEnter:
+		mods = "C-" + mods
+		name = string(rune('a' + k.Key - tcell.KeyCtrlA))
+	case k.Key >= tcell.KeyF1 && k.Key <= tcell.KeyF64:
+		name = fmt.Sprintf("F%d", k.Key-tcell.KeyF1+1)
+	default:
+		for _, sk := range specialKeys {
+			if sk.key == k.Key {
+				name = sk.name
+				break
+			}
+		}
+		if name == "" {
+			name = fmt.Sprintf("%d", k.Key)
+		}
+	}
+	return "<" + mods + name + ">"
 }
@@ -0,0 +1,106 @@
+package app
+
+import (
+	"xl/document"
+	"xl/document/sheet"
+
+	"testing"
+
+	"github.com/gdamore/tcell"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestParseKeys(t *testing.T) {
+	testCases := []struct {
+		notation string
+		keys     []Key
+		format   string
+	}{
+		{`g`, []Key{{tcell.ModNone, tcell.KeyRune, 'g'}}, `g`},
+		{`gg`, []Key{{tcell.ModNone, tcell.KeyRune, 'g'}, {tcell.ModNone, tcell.KeyRune, 'g'}}, `gg`},
+		{`<C-x>`, []Key{{tcell.ModNone, tcell.KeyCtrlX, 0}}, `<C-x>`},
+		{`<c-X>`, []Key{{tcell.ModNone, tcell.KeyCtrlX, 0}}, `<C-x>`},
+		{`<A-x>`, []Key{{tcell.ModAlt, tcell.KeyRune, 'x'}}, `<A-x>`},
+		{`<M-x>`, []Key{{tcell.ModAlt, tcell.KeyRune, 'x'}}, `<A-x>`},
+		{`<S-a>`, []Key{{tcell.ModNone, tcell.KeyRune, 'A'}}, `A`},
+		{`<lt>`, []Key{{tcell.ModNone, tcell.KeyRune, '<'}}, `<lt>`},
+		{`<`, []Key{{tcell.ModNone, tcell.KeyRune, '<'}}, `<lt>`},
+		{`<>`, []Key{{tcell.ModNone, tcell.KeyRune, '<'}, {tcell.ModNone, tcell.KeyRune, '>'}}, `<lt>>`},
+		{`<Space>`, []Key{{tcell.ModNone, tcell.KeyRune, ' '}}, `<Space>`},
+		{` `, []Key{{tcell.ModNone, tcell.KeyRune, ' '}}, `<Space>`},
+		{`<Tab>`, []Key{{tcell.ModNone, tcell.KeyTab, 0}}, `<Tab>`},
+		{`<C-i>`, []Key{{tcell.ModNone, tcell.KeyTab, 0}}, `<Tab>`},
+		{`<S-Tab>`, []Key{{tcell.ModNone, tcell.KeyBacktab, 0}}, `<S-Tab>`},
+		{`<Enter>`, []Key{{tcell.ModNone, tcell.KeyEnter, 0}}, `<CR>`},
+		{`<BS>`, []Key{{tcell.ModNone, tcell.KeyBackspace2, 0}}, `<BS>`},
+		{`<C-Up>`, []Key{{tcell.ModCtrl, tcell.KeyUp, 0}}, `<C-Up>`},
+		{`<F12>`, []Key{{tcell.ModNone, tcell.KeyF12, 0}}, `<F12>`},
+		{`dd<C-s>`, []Key{
+			{tcell.ModNone, tcell.KeyRune, 'd'}, {tcell.ModNone, tcell.KeyRune, 'd'}, {tcell.ModNone, tcell.KeyCtrlS, 0},
+		}, `dd<C-s>`},
+		{`<Esc><Esc>`, []Key{{tcell.ModNone, tcell.KeyEsc, 0}, {tcell.ModNone, tcell.KeyEsc, 0}}, `<Esc><Esc>`},
+	}
+	for _, c := range testCases {
+		keys, err := ParseKeys(c.notation)
+		if assert.NoErrorf(t, err, "case %s", c.notation) {
+			assert.Equalf(t, c.keys, keys, "case %s", c.notation)
+			assert.Equalf(t, c.format, FormatKeys(keys), "case %s", c.notation)
+			// the formatted notation is parsed back to the same keys
+			again, err := ParseKeys(c.format)
+			assert.NoErrorf(t, err, "case %s", c.notation)
+			assert.Equalf(t, keys, again, "case %s", c.notation)
+		}
+	}
+}
+
+func TestParseKeysInvalid(t *testing.T) {
+	for _, notation := range []string{``, `<Foo>`, `<X-a>`, `<C-Foo>`, `<F0>`, `<F65>`, `g<Nope>`} {
+		_, err := ParseKeys(notation)
+		assert.Errorf(t, err, "case %s", notation)
+	}
+}
+
+func TestProcessHotKey(t *testing.T) {
+	testCases := []struct {
+		name    string
+		hotKeys map[string]string
+		// steps are key notations or "timeout" and "interrupt" events
+		steps []string
+		y     int
+	}{
+		{"bound", map[string]string{"gt": "go A10"}, []string{"g", "t"}, 9},
+		{"unbound prefix", map[string]string{"gt": "go A10"}, []string{"g", "g", "timeout"}, 0},
+		{"timeout", map[string]string{"jk": "go A10"}, []string{"j", "timeout"}, 6},
+		{"mismatch", map[string]string{"jk": "go A10"}, []string{"j", "<Up>"}, 5},
+		{"repeated prefix", map[string]string{"jk": "go A10"}, []string{"j", "j", "timeout"}, 7},
+		{"count", map[string]string{"jk": "go A10"}, []string{"3", "j", "timeout"}, 8},
+		{"bound beginning", map[string]string{"g": "go A10", "gt": "go A1"}, []string{"g", "timeout"}, 9},
+		{"bound beginning mismatch", map[string]string{"g": "go A10", "gt": "go A1"}, []string{"g", "k"}, 8},
+		{"interrupt", map[string]string{"jk": "go A10"}, []string{"j", "interrupt", "k"}, 4},
+	}
+	for _, c := range testCases {
+		a := &App{
+			screen:  tcell.NewSimulationScreen(""),
+			output:  &fakeOutput{},
+			logger:  zap.NewNop(),
+			hotKeys: c.hotKeys,
+		}
+		a.doc = document.NewWithEmptySheet()
+		a.resetWindows()
+		a.doc.CurrentSheet.Cursor = sheet.Cursor{Y: 5}
+		for _, step := range c.steps {
+			switch step {
+			case "timeout":
+				a.onHotKeyTimeout(hotKeyTimeout(a.pendingSeq))
+			case "interrupt":
+				a.Interrupted(hotKeyTimeout(a.pendingSeq))
+			default:
+				keys, err := ParseKeys(step)
+				assert.NoError(t, err)
+				a.processKeyEvent(keys[0].event())
+			}
+		}
+		assert.Equalf(t, c.y, a.doc.CurrentSheet.Cursor.Y, "case %s", c.name)
+	}
+}
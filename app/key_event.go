@@ -1,21 +1,51 @@
 package app
 
 import (
+	"xl/document/eval"
 	"xl/document/sheet"
+	"xl/formula"
 	"xl/ui"
 
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/gdamore/tcell"
 )
 
 // processKeyEvent does the job associated with the key press.
 func (a *App) processKeyEvent(event ui.KeyEvent) bool {
+	defer a.dropSelectionOnMove(a.doc.CurrentSheet, a.doc.CurrentSheet.Cursor)
+	defer a.showNoteOnMove(a.doc.CurrentSheet, a.doc.CurrentSheet.Cursor)
+	// keys bound by user take precedence over built-in ones
+	if stop, ok := a.processHotKey(NewKey(event)); ok {
+		a.output.RefreshView()
+		return stop
+	}
+	return a.processBuiltInKey(event)
+}
+
+// processBuiltInKey does the job associated with the key press unless the key is bound by user.
+func (a *App) processBuiltInKey(event ui.KeyEvent) bool {
+	if a.processMotion(event) {
+		a.output.RefreshView()
+		return false
+	}
+	a.motion = motionState{}
+
 	switch event.Ch {
 	case ':':
 		stop := a.inputCommand()
 		a.output.RefreshView()
 		return stop
+	case '/', '?':
+		a.inputSearch(event.Ch == '?')
+		a.output.RefreshView()
+		return false
+	case 'n', 'N':
+		a.searchNext(event.Ch == 'N')
+		a.output.RefreshView()
+		return false
 	case ' ':
 		a.pageDown()
 		a.output.RefreshView()
@@ -42,95 +72,155 @@ func (a *App) processKeyEvent(event ui.KeyEvent) bool {
 		a.output.RefreshView()
 	default:
 		a.output.SetStatus(fmt.Sprintf("ch: %v, key: %v", event.Ch, event.Key), 0)
-		a.runHotKey(Key{event.Mod, event.Key, event.Ch})
 		a.output.RefreshView()
 	}
 
 	return false
 }
 
+// dropSelectionOnMove clears selected range once the cursor has been moved from given position by keys.
+func (a *App) dropSelectionOnMove(s *sheet.Sheet, cursor sheet.Cursor) {
+	if s != a.doc.CurrentSheet || s.Cursor == cursor || s.Selection.Width == 0 {
+		return
+	}
+	a.dropSelection()
+	a.output.RefreshView()
+}
+
 // moveCursorLeft moves cursor up on one cell.
 func (a *App) moveCursorUp() bool {
-	if a.doc.CurrentSheet.Cursor.Y <= 0 {
+	s := a.doc.CurrentSheet
+	y := s.NextVisibleRow(s.Cursor.Y, -1)
+	if y == s.Cursor.Y {
 		return false
 	}
-	a.doc.CurrentSheet.Cursor.Y--
-	if a.doc.CurrentSheet.Cursor.Y < a.doc.CurrentSheet.Viewport.Top {
-		a.doc.CurrentSheet.Viewport.Top--
+	s.Cursor.Y = y
+	if s.Cursor.Y >= s.Viewport.FrozenRows && s.Cursor.Y < s.Viewport.Top {
+		s.Viewport.Top = s.Cursor.Y
 	}
+	a.snapToMerge()
 	a.output.SetDirty(ui.DirtyVRuler | ui.DirtyGrid | ui.DirtyFormulaLine)
 	return true
 }
 
 // moveCursorLeft moves cursor down on one cell.
 func (a *App) moveCursorDown() bool {
-	a.doc.CurrentSheet.Cursor.Y++
-	if a.doc.CurrentSheet.Cursor.Y >= a.doc.CurrentSheet.Viewport.Top+a.output.ViewportHeight() {
-		a.doc.CurrentSheet.Viewport.Top++
+	s := a.doc.CurrentSheet
+	_, y := a.cursorEdge()
+	s.Cursor.Y = s.NextVisibleRow(y, 1)
+	if s.Cursor.Y >= s.Viewport.FrozenRows && s.Cursor.Y < s.Viewport.Top {
+		// the cursor leaves frozen rows
+		s.Viewport.Top = s.Cursor.Y
+	}
+	for s.Cursor.Y > a.lastRowInView() {
+		s.Viewport.Top = s.NextVisibleRow(s.Viewport.Top, 1)
 	}
+	a.snapToMerge()
 	a.output.SetDirty(ui.DirtyVRuler | ui.DirtyGrid | ui.DirtyFormulaLine)
 	return true
 }
 
 // moveCursorLeft moves cursor left on one cell.
 func (a *App) moveCursorLeft() bool {
-	if a.doc.CurrentSheet.Cursor.X <= 0 {
+	s := a.doc.CurrentSheet
+	x := s.NextVisibleCol(s.Cursor.X, -1)
+	if x == s.Cursor.X {
 		return false
 	}
-	a.doc.CurrentSheet.Cursor.X--
-	if a.doc.CurrentSheet.Cursor.X < a.doc.CurrentSheet.Viewport.Left {
-		a.doc.CurrentSheet.Viewport.Left--
+	s.Cursor.X = x
+	if s.Cursor.X >= s.Viewport.FrozenCols && s.Cursor.X < s.Viewport.Left {
+		s.Viewport.Left = s.Cursor.X
 	}
+	a.snapToMerge()
 	a.output.SetDirty(ui.DirtyHRuler | ui.DirtyGrid | ui.DirtyFormulaLine)
 	return true
 }
 
 // moveCursorRight moves cursor right on one cell.
 func (a *App) moveCursorRight() bool {
-	a.doc.CurrentSheet.Cursor.X++
-	if a.doc.CurrentSheet.Cursor.X >= a.doc.CurrentSheet.Viewport.Left+a.output.ViewportWidth() {
-		a.doc.CurrentSheet.Viewport.Left++
+	s := a.doc.CurrentSheet
+	x, _ := a.cursorEdge()
+	s.Cursor.X = s.NextVisibleCol(x, 1)
+	if s.Cursor.X >= s.Viewport.FrozenCols && s.Cursor.X < s.Viewport.Left {
+		// the cursor leaves frozen columns
+		s.Viewport.Left = s.Cursor.X
 	}
+	for s.Cursor.X > a.lastColInView() {
+		s.Viewport.Left = s.NextVisibleCol(s.Viewport.Left, 1)
+	}
+	a.snapToMerge()
 	a.output.SetDirty(ui.DirtyHRuler | ui.DirtyGrid | ui.DirtyFormulaLine)
 	return true
 }
 
-// pageDown moves cursor down on number of lines equal to window height.
+// lastRowInView returns the last visible row of the viewport.
+func (a *App) lastRowInView() int {
+	s := a.doc.CurrentSheet
+	return s.NextVisibleRow(s.NextVisibleRow(s.Viewport.Top, 0), a.output.ViewportHeight()-1)
+}
+
+// lastColInView returns the last visible column of the viewport.
+func (a *App) lastColInView() int {
+	s := a.doc.CurrentSheet
+	return s.NextVisibleCol(s.NextVisibleCol(s.Viewport.Left, 0), a.output.ViewportWidth()-1)
+}
+
+// pageDown moves cursor down on number of lines equal to height of the scrolled area.
 func (a *App) pageDown() bool {
-	a.doc.CurrentSheet.Cursor.Y += a.output.ViewportHeight()
-	a.doc.CurrentSheet.Viewport.Top += a.output.ViewportHeight()
-	a.output.SetDirty(ui.DirtyHRuler | ui.DirtyVRuler | ui.DirtyGrid | ui.DirtyFormulaLine)
+	a.scroll(a.output.ViewportHeight())
 	return true
 }
 
-// moveCursorRight moves cursor right on one cell.
+// moveCursorTo moves cursor to the cell scrolling the viewport if needed. Frozen rows and columns are always visible.
 func (a *App) moveCursorTo(x, y int) {
-	a.doc.CurrentSheet.Cursor.X = x
-	if a.doc.CurrentSheet.Cursor.X >= a.doc.CurrentSheet.Viewport.Left+a.output.ViewportWidth() {
-		a.doc.CurrentSheet.Viewport.Left = a.doc.CurrentSheet.Cursor.X - a.output.ViewportWidth() + 1
+	s := a.doc.CurrentSheet
+	if r, ok := s.MergedRect(x, y); ok {
+		x, y = r.X, r.Y
+	}
+	s.Cursor.X = s.NextVisibleCol(x, 0)
+	if s.Cursor.X > a.lastColInView() {
+		s.Viewport.Left = maxInt(s.NextVisibleCol(s.Cursor.X, 1-a.output.ViewportWidth()), s.Viewport.FrozenCols)
 	}
-	if a.doc.CurrentSheet.Cursor.X < a.doc.CurrentSheet.Viewport.Left {
-		a.doc.CurrentSheet.Viewport.Left = a.doc.CurrentSheet.Cursor.X
+	if s.Cursor.X >= s.Viewport.FrozenCols && s.Cursor.X < s.Viewport.Left {
+		s.Viewport.Left = s.Cursor.X
 	}
-	a.doc.CurrentSheet.Cursor.Y = y
-	if a.doc.CurrentSheet.Cursor.Y >= a.doc.CurrentSheet.Viewport.Top+a.output.ViewportHeight() {
-		a.doc.CurrentSheet.Viewport.Top = a.doc.CurrentSheet.Cursor.Y - a.output.ViewportHeight() + 1
+	s.Cursor.Y = s.NextVisibleRow(y, 0)
+	if s.Cursor.Y > a.lastRowInView() {
+		s.Viewport.Top = maxInt(s.NextVisibleRow(s.Cursor.Y, 1-a.output.ViewportHeight()), s.Viewport.FrozenRows)
 	}
-	if a.doc.CurrentSheet.Cursor.Y < a.doc.CurrentSheet.Viewport.Top {
-		a.doc.CurrentSheet.Viewport.Top = a.doc.CurrentSheet.Cursor.Y
+	if s.Cursor.Y >= s.Viewport.FrozenRows && s.Cursor.Y < s.Viewport.Top {
+		s.Viewport.Top = s.Cursor.Y
 	}
 	a.output.SetDirty(ui.DirtyHRuler | ui.DirtyVRuler | ui.DirtyGrid | ui.DirtyFormulaLine)
 }
 
+// cursorEdge returns the last column and row of the merged cells under cursor, or the cursor position.
+func (a *App) cursorEdge() (int, int) {
+	s := a.doc.CurrentSheet
+	if r, ok := s.MergedRect(s.Cursor.X, s.Cursor.Y); ok {
+		return r.MaxX(), r.MaxY()
+	}
+	return s.Cursor.X, s.Cursor.Y
+}
+
+// snapToMerge moves the cursor to the top left cell of merged cells it has got into.
+func (a *App) snapToMerge() {
+	s := a.doc.CurrentSheet
+	if r, ok := s.MergedRect(s.Cursor.X, s.Cursor.Y); ok && (r.X != s.Cursor.X || r.Y != s.Cursor.Y) {
+		a.moveCursorTo(r.X, r.Y)
+	}
+}
+
 // inputCommand opens inline editor in status line, with ':' prompt.
 // Once user finishes command input, processes the command.
 func (a *App) inputCommand() bool {
-	command, err := a.output.InputCommand()
+	command, err := a.output.InputCommand(":")
 	if err != nil {
 		a.showError(err)
 		return false
 	}
 	a.output.SetStatus("", 0)
+	a.appendHistory(command)
 	stop := a.processCommand(command)
 	a.output.SetDirty(ui.DirtyStatusLine)
 	return stop
@@ -144,21 +234,159 @@ func (a *App) editCell() {
 	if cell == nil {
 		cell = sheet.NewCellEmpty()
 	}
-	newValue, err := a.output.EditCellValue(cell.RawValue())
-	if err != nil {
-		a.logger.Error(err.Error())
+	ref := eval.Cell{SheetIdx: a.doc.CurrentSheet.Idx, X: cur.X, Y: cur.Y}
+	if cell.RawValue() == "" && a.doc.Spilled(ref) {
+		a.output.SetStatus("cell is a part of array, change the formula it comes from", ui.StatusFlagError)
+		return
+	}
+	if v := a.doc.CurrentSheet.Validation(cur.X, cur.Y); v != nil && v.Kind == sheet.ValidateList && !v.Warning {
+		// only listed values are allowed, so there is nothing to type
+		a.pickValue()
+		return
+	}
+	previous := cell.RawValue()
+	value, cursorOffset := previous, -1
+	for {
+		newValue, cancelled, err := a.output.EditCellValue(value, cursorOffset)
+		if err != nil {
+			a.logger.Error(err.Error())
+			return
+		}
+		if cancelled {
+			// the cell keeps its value, drop the error of the abandoned text
+			if value != previous {
+				a.output.SetStatus("", 0)
+			}
+			return
+		}
+		if newValue == value {
+			break
+		}
+		value = newValue
+		// on syntax error let user fix the formula right away
+		pe := formulaSyntaxError(value)
+		if pe == nil {
+			break
+		}
+		a.output.SetStatus(pe.Description(), ui.StatusFlagError)
+		a.output.RefreshView()
+		cursorOffset = pe.Offset
+	}
+	if value == previous {
 		return
 	}
-	cell.SetValueUntyped(newValue)
+	if formulaSyntaxError(value) == nil {
+		a.output.SetStatus("", 0)
+	}
+	cell.SetValueUntyped(value)
 	a.doc.CurrentSheet.SetCell(cur.X, cur.Y, cell)
+	a.doc.UpdateSpill(ref)
+	a.checkValidation(previous)
 	a.output.SetDirty(ui.DirtyGrid | ui.DirtyFormulaLine)
 }
 
-func (a *App) runHotKey(k Key) bool {
-	c, ok := a.hotKeys[k]
+// formulaSyntaxError returns syntax error of the formula or nil if the value is not a formula or has no errors.
+func formulaSyntaxError(value string) *formula.ParseError {
+	if len(value) < 2 || value[0] != '=' {
+		return nil
+	}
+	if _, err := formula.Parse(value); err != nil {
+		if pe, ok := err.(*formula.ParseError); ok {
+			return pe
+		}
+	}
+	return nil
+}
+
+// processHotKey runs the command bound to the sequence of keys typed so far ending with the key.
+// If the sequence is a beginning of a longer bound one, waits for next keys during keySequenceTimeout.
+// Returns true in the second value if the key has been handled.
+func (a *App) processHotKey(k Key) (bool, bool) {
+	keys := append(a.pendingKeys, k)
+	notation := FormatKeys(keys)
+	a.pendingKeys = nil
+	for bound := range a.hotKeys {
+		if len(bound) > len(notation) && strings.HasPrefix(bound, notation) {
+			a.pendingKeys = keys
+			a.pendingSeq++
+			seq := a.pendingSeq
+			time.AfterFunc(keySequenceTimeout, func() {
+				_ = a.screen.PostEvent(tcell.NewEventInterrupt(hotKeyTimeout(seq)))
+			})
+			return false, true
+		}
+	}
+	if _, ok := a.hotKeys[notation]; ok {
+		return a.runHotKey(notation), true
+	}
+	if len(keys) == 1 {
+		return false, false
+	}
+	// the sequence is not bound, process the typed keys as if there was no longer binding
+	return a.replayKeys(keys), true
+}
+
+// replayKeys runs the command bound to the longest beginning of the keys, or processes the first key
+// as a built-in one if no beginning is bound, then processes the rest of keys as typed anew.
+func (a *App) replayKeys(keys []Key) bool {
+	n := len(keys)
+	for n > 0 {
+		if _, ok := a.hotKeys[FormatKeys(keys[:n])]; ok {
+			break
+		}
+		n--
+	}
+	var stop bool
+	if n > 0 {
+		stop = a.runHotKey(FormatKeys(keys[:n]))
+	} else {
+		n = 1
+		stop = a.processBuiltInKey(keys[0].event())
+	}
+	for _, k := range keys[n:] {
+		if stop {
+			return true
+		}
+		var ok bool
+		if stop, ok = a.processHotKey(k); !ok {
+			stop = a.processBuiltInKey(k.event())
+		}
+	}
+	return stop
+}
+
+// hotKeyTimeout is posted when the time to type the next key of a sequence expires.
+type hotKeyTimeout int
+
+// onHotKeyTimeout processes the keys typed so far as there are no more keys in the sequence.
+func (a *App) onHotKeyTimeout(seq hotKeyTimeout) bool {
+	if int(seq) != a.pendingSeq || a.pendingKeys == nil {
+		// more keys have been typed since then
+		return false
+	}
+	defer a.dropSelectionOnMove(a.doc.CurrentSheet, a.doc.CurrentSheet.Cursor)
+	defer a.showNoteOnMove(a.doc.CurrentSheet, a.doc.CurrentSheet.Cursor)
+	keys := a.pendingKeys
+	a.pendingKeys = nil
+	stop := a.replayKeys(keys)
+	a.output.RefreshView()
+	return stop
+}
+
+// Interrupted drops the typed keys if the time to finish the sequence expires while the editor is open.
+func (a *App) Interrupted(data interface{}) {
+	if seq, ok := data.(hotKeyTimeout); ok && int(seq) == a.pendingSeq {
+		a.pendingKeys = nil
+	}
+}
+
+// runHotKey runs the command bound to the key sequence notation.
+func (a *App) runHotKey(notation string) bool {
+	c, ok := a.hotKeys[notation]
 	if !ok {
 		return false
 	}
+	a.motion = motionState{}
 	a.output.SetStatus("", 0)
 	stop := a.processCommand(c)
 	a.output.SetDirty(ui.DirtyStatusLine)
@@ -0,0 +1,67 @@
+package app
+
+import (
+	"xl/document"
+	"xl/document/sheet"
+	"xl/ui"
+
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// editResult is what the fake editor returns for one EditCellValue call.
+type editResult struct {
+	value     string
+	cancelled bool
+}
+
+// fakeOutput replays edits and records the status, other methods of OutputInterface are not expected to be called.
+type fakeOutput struct {
+	ui.OutputInterface
+	edits  []editResult
+	status string
+}
+
+func (o *fakeOutput) SetDataDelegate(ui.DataDelegateInterface) {}
+func (o *fakeOutput) RefreshView()                             {}
+func (o *fakeOutput) SetDirty(ui.DirtyFlag)                    {}
+func (o *fakeOutput) SetStatus(msg string, _ int)              { o.status = msg }
+func (o *fakeOutput) ViewportHeight() int                      { return 20 }
+func (o *fakeOutput) ViewportWidth() int                       { return 10 }
+
+func (o *fakeOutput) EditCellValue(value string, _ int) (string, bool, error) {
+	e := o.edits[0]
+	o.edits = o.edits[1:]
+	if e.cancelled {
+		return value, true, nil
+	}
+	return e.value, false, nil
+}
+
+func TestEditCell(t *testing.T) {
+	testCases := []struct {
+		name   string
+		edits  []editResult
+		value  string
+		status string
+	}{
+		{"enter", []editResult{{value: "=1+1"}}, "=1+1", ""},
+		{"cancel", []editResult{{cancelled: true}}, "old", ""},
+		{"fix syntax error", []editResult{{value: "=1+"}, {value: "=1+2"}}, "=1+2", ""},
+		{"cancel after syntax error", []editResult{{value: "=1+"}, {cancelled: true}}, "old", ""},
+		{"keep syntax error", []editResult{{value: "=1+"}, {value: "=1+"}}, "=1+", "syntax error at 4: unexpected end of formula, expected value"},
+	}
+	for _, c := range testCases {
+		out := &fakeOutput{edits: c.edits}
+		a := &App{output: out, logger: zap.NewNop(), hotKeys: make(map[string]string)}
+		a.doc = document.NewWithEmptySheet()
+		a.resetWindows()
+		a.doc.CurrentSheet.SetCell(0, 0, sheet.NewCellUntyped("old"))
+		a.editCell()
+		assert.Emptyf(t, out.edits, "case %s", c.name)
+		assert.Equalf(t, c.value, a.doc.CurrentSheet.Cell(0, 0).RawValue(), "case %s", c.name)
+		assert.Equalf(t, c.status, out.status, "case %s", c.name)
+	}
+}
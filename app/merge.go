@@ -0,0 +1,59 @@
+package app
+
+import (
+	"xl/document"
+	"xl/document/sheet"
+	"xl/ui"
+
+	"fmt"
+	"strings"
+)
+
+// cmdMerge merges cells of the range like "A1:C2" or of the selection into one showing the value of the top left
+// cell. Values of other cells are kept and shown again after unmerging.
+func (a *App) cmdMerge(cells string) {
+	s := a.doc.CurrentSheet
+	r, err := a.cellRange(cells)
+	if err != nil {
+		a.showError(err)
+		return
+	}
+	if !s.Merge(r) {
+		a.output.SetStatus("select several cells not overlapping merged ones", ui.StatusFlagError)
+		return
+	}
+	a.moveCursorTo(s.Cursor.X, s.Cursor.Y)
+}
+
+// cmdUnmerge splits merged cells in the range like "A1:C2", in the selection or under cursor.
+func (a *App) cmdUnmerge(cells string) {
+	r, err := a.cellRange(cells)
+	if err != nil {
+		a.showError(err)
+		return
+	}
+	if !a.doc.CurrentSheet.Unmerge(r) {
+		a.output.SetStatus("no merged cells", ui.StatusFlagError)
+		return
+	}
+	a.output.SetDirty(ui.DirtyGrid)
+}
+
+// cellRange parses the range like "A1:C2" or "B3". If it is empty, returns the selection or the cell under cursor.
+func (a *App) cellRange(r string) (sheet.Rect, error) {
+	if r == "" {
+		return a.selectedRect(), nil
+	}
+	parts := strings.SplitN(strings.ToUpper(r), ":", 2)
+	x1, y1, err := document.CellAxis(parts[0])
+	if err != nil {
+		return sheet.Rect{}, fmt.Errorf("invalid range %s", r)
+	}
+	x2, y2 := x1, y1
+	if len(parts) == 2 {
+		if x2, y2, err = document.CellAxis(parts[1]); err != nil {
+			return sheet.Rect{}, fmt.Errorf("invalid range %s", r)
+		}
+	}
+	return sheet.RectFromCorners(x1, y1, x2, y2), nil
+}
@@ -0,0 +1,173 @@
+package app
+
+import (
+	"xl/ui"
+
+	"math"
+
+	"github.com/gdamore/tcell"
+)
+
+// pendingWindowCommand marks Ctrl-W typed to be followed by a window command.
+const pendingWindowCommand = '\x17'
+
+// motionState keeps the count and the first key of a two-key motion typed so far.
+type motionState struct {
+	count   int
+	pending rune
+}
+
+// processMotion moves the cursor with vim-like motions prefixed by optional count.
+// Returns false if the key is not a motion.
+func (a *App) processMotion(event ui.KeyEvent) bool {
+	m := &a.motion
+	count := m.count
+	if count == 0 {
+		count = 1
+	}
+
+	switch event.Key {
+	case tcell.KeyCtrlU:
+		a.scroll(-count * a.output.ViewportHeight() / 2)
+	case tcell.KeyCtrlD:
+		a.scroll(count * a.output.ViewportHeight() / 2)
+	case tcell.KeyCtrlB:
+		a.scroll(-count * a.output.ViewportHeight())
+	case tcell.KeyCtrlF:
+		a.scroll(count * a.output.ViewportHeight())
+	case tcell.KeyCtrlW:
+		if m.pending != pendingWindowCommand {
+			m.pending = pendingWindowCommand
+			return true
+		}
+		// Ctrl-W twice goes to the next window
+		m.pending = 0
+		a.windowCommand('w')
+	case tcell.KeyEsc:
+		// cancel typed count
+	case tcell.KeyRune:
+		if event.Mod&(tcell.ModAlt|tcell.ModCtrl) != 0 {
+			return false
+		}
+		if m.pending == pendingWindowCommand {
+			m.pending = 0
+			m.count = 0
+			a.windowCommand(event.Ch)
+			return true
+		}
+		if event.Ch >= '1' && event.Ch <= '9' || event.Ch == '0' && m.count > 0 {
+			m.count = m.count*10 + int(event.Ch-'0')
+			m.pending = 0
+			return true
+		}
+		if m.pending == 'g' {
+			m.pending = 0
+			if event.Ch != 'g' {
+				m.count = 0
+				return true
+			}
+			// gg goes to the first row or to the row given by count
+			a.moveCursorTo(a.doc.CurrentSheet.Cursor.X, count-1)
+			m.count = 0
+			return true
+		}
+		if !a.runeMotion(event.Ch, count) {
+			return false
+		}
+	default:
+		return false
+	}
+	m.count = 0
+	return true
+}
+
+// runeMotion does motions bound to printable characters.
+func (a *App) runeMotion(ch rune, count int) bool {
+	s := a.doc.CurrentSheet
+	x, y := s.Cursor.X, s.Cursor.Y
+	switch ch {
+	case 'g':
+		a.motion.pending = 'g'
+		return true
+	case 'G':
+		y = s.LastRow()
+		if a.motion.count > 0 {
+			y = count - 1
+		}
+	case 'h':
+		x = s.NextVisibleCol(x, -count)
+	case 'l':
+		x, _ = a.cursorEdge()
+		x = s.NextVisibleCol(x, count)
+	case 'k':
+		y = s.NextVisibleRow(y, -count)
+	case 'j':
+		_, y = a.cursorEdge()
+		y = s.NextVisibleRow(y, count)
+	case '0':
+		x = s.NextCellInRow(-1, y, 1)
+	case '$':
+		x = s.NextCellInRow(math.MaxInt32, y, -1)
+	case 'w', 'b':
+		dir := 1
+		if ch == 'b' {
+			dir = -1
+		}
+		for i := 0; i < count; i++ {
+			next := s.NextCellInRow(x, y, dir)
+			for next >= 0 && s.ColHidden(next) {
+				next = s.NextCellInRow(next, y, dir)
+			}
+			if next < 0 {
+				break
+			}
+			x = next
+		}
+	case 'H':
+		y = s.NextVisibleRow(s.NextVisibleRow(s.Viewport.Top, 0), count-1)
+	case 'M':
+		y = s.NextVisibleRow(s.NextVisibleRow(s.Viewport.Top, 0), (a.output.ViewportHeight()-1)/2)
+	case 'L':
+		y = s.NextVisibleRow(s.NextVisibleRow(s.Viewport.Top, 0), a.output.ViewportHeight()-count)
+	default:
+		return false
+	}
+	a.moveCursorTo(maxInt(x, 0), maxInt(y, 0))
+	return true
+}
+
+// scroll moves both the viewport and the cursor down on given number of rows, or up if it is negative.
+// Frozen rows stay in place, so does the cursor on them.
+func (a *App) scroll(rows int) {
+	s := a.doc.CurrentSheet
+	s.Viewport.Top = maxInt(s.NextVisibleRow(s.Viewport.Top, rows), s.Viewport.FrozenRows)
+	if s.Cursor.Y >= s.Viewport.FrozenRows {
+		s.Cursor.Y = maxInt(s.NextVisibleRow(s.Cursor.Y, rows), s.Viewport.FrozenRows)
+	}
+	a.output.SetDirty(ui.DirtyHRuler | ui.DirtyVRuler | ui.DirtyGrid | ui.DirtyFormulaLine)
+}
+
+// scrollCols moves both the viewport and the cursor right on given number of columns, or left if it is negative.
+// Frozen columns stay in place, so does the cursor on them.
+func (a *App) scrollCols(cols int) {
+	s := a.doc.CurrentSheet
+	s.Viewport.Left = maxInt(s.NextVisibleCol(s.Viewport.Left, cols), s.Viewport.FrozenCols)
+	if s.Cursor.X >= s.Viewport.FrozenCols {
+		s.Cursor.X = maxInt(s.NextVisibleCol(s.Cursor.X, cols), s.Viewport.FrozenCols)
+	}
+	a.output.SetDirty(ui.DirtyHRuler | ui.DirtyVRuler | ui.DirtyGrid | ui.DirtyFormulaLine)
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,106 @@
+package app
+
+import (
+	"xl/document/sheet"
+	"xl/ui"
+
+	"github.com/gdamore/tcell"
+)
+
+// wheelScrollRows is the number of rows scrolled by one turn of mouse wheel.
+const wheelScrollRows = 3
+
+// mouseState keeps the state of the left mouse button being pressed.
+type mouseState struct {
+	pressed bool
+	// area where the button has been pressed
+	area ui.ScreenArea
+	// screen X where column resizing started and the column width in pixels at that moment
+	startX    int
+	startSize int
+}
+
+// processMouseEvent selects cells, scrolls the sheet, switches sheets and resizes columns with mouse.
+func (a *App) processMouseEvent(event ui.MouseEvent) {
+	defer a.showNoteOnMove(a.doc.CurrentSheet, a.doc.CurrentSheet.Cursor)
+	switch {
+	case event.Buttons&tcell.WheelUp != 0:
+		a.scroll(-wheelScrollRows)
+	case event.Buttons&tcell.WheelDown != 0:
+		a.scroll(wheelScrollRows)
+	case event.Buttons&tcell.WheelLeft != 0:
+		a.scrollCols(-1)
+	case event.Buttons&tcell.WheelRight != 0:
+		a.scrollCols(1)
+	case event.Buttons&tcell.Button1 != 0:
+		if a.mouse.pressed {
+			a.mouseDrag(event)
+		} else {
+			a.mousePress(event)
+		}
+	default:
+		a.mouse.pressed = false
+		return
+	}
+	a.output.RefreshView()
+}
+
+// mousePress handles pressing of the left button.
+func (a *App) mousePress(event ui.MouseEvent) {
+	area := a.output.ScreenArea(event.X, event.Y)
+	a.mouse = mouseState{pressed: true, area: area}
+	if area.Kind == ui.AreaGrid || area.Kind == ui.AreaHRuler || area.Kind == ui.AreaVRuler {
+		a.activateWindow(area.Window)
+	}
+	s := a.doc.CurrentSheet
+	switch area.Kind {
+	case ui.AreaGrid:
+		a.dropSelection()
+		a.moveCursorTo(area.X, area.Y)
+	case ui.AreaHRuler:
+		if area.ColBorder {
+			a.mouse.startX = event.X
+			a.mouse.startSize = s.ColSize(area.X)
+			return
+		}
+		a.moveCursorTo(area.X, s.Cursor.Y)
+	case ui.AreaVRuler:
+		a.moveCursorTo(s.Cursor.X, area.Y)
+	case ui.AreaSheetTab:
+		a.switchSheet(area.Sheet)
+	}
+}
+
+// mouseDrag handles moving mouse with the left button pressed.
+func (a *App) mouseDrag(event ui.MouseEvent) {
+	s := a.doc.CurrentSheet
+	switch a.mouse.area.Kind {
+	case ui.AreaGrid:
+		area := a.output.ScreenArea(event.X, event.Y)
+		if area.Kind != ui.AreaGrid || area.Window != a.mouse.area.Window {
+			return
+		}
+		// the cursor stays in the cell where selecting started
+		s.Selection = sheet.RectFromCorners(s.Cursor.X, s.Cursor.Y, area.X, area.Y)
+		if s.Selection.Width == 1 && s.Selection.Height == 1 {
+			s.Selection = sheet.Rect{}
+		}
+		a.output.SetDirty(ui.DirtyGrid)
+	case ui.AreaHRuler:
+		if !a.mouse.area.ColBorder {
+			return
+		}
+		width := maxInt(a.mouse.area.Width+event.X-a.mouse.startX, 1)
+		s.SetColSize(a.mouse.area.X, a.mouse.startSize*width/a.mouse.area.Width)
+		a.output.SetDirty(ui.DirtyHRuler | ui.DirtyGrid)
+	}
+}
+
+// dropSelection clears the selected range.
+func (a *App) dropSelection() {
+	if a.doc.CurrentSheet.Selection.Width == 0 {
+		return
+	}
+	a.doc.CurrentSheet.Selection = sheet.Rect{}
+	a.output.SetDirty(ui.DirtyGrid)
+}
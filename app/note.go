@@ -0,0 +1,64 @@
+package app
+
+import (
+	"xl/document/sheet"
+	"xl/ui"
+
+	"strings"
+)
+
+// cmdNote attaches the note to the cell under cursor, with no text it shows the note of the cell.
+func (a *App) cmdNote(text string) {
+	s := a.doc.CurrentSheet
+	if text == "" {
+		note := s.Note(s.Cursor.X, s.Cursor.Y)
+		if note == "" {
+			a.output.SetStatus("no note", 0)
+			return
+		}
+		a.output.SetStatus(noteStatus(note), 0)
+		return
+	}
+	s.SetNote(s.Cursor.X, s.Cursor.Y, text)
+	a.output.SetDirty(ui.DirtyGrid)
+}
+
+// cmdDeleteNote deletes notes of cells in the range like "A1:C2", in the selection or under cursor.
+func (a *App) cmdDeleteNote(cells string) {
+	r, err := a.cellRange(cells)
+	if err != nil {
+		a.showError(err)
+		return
+	}
+	s := a.doc.CurrentSheet
+	found := false
+	for _, n := range s.Notes() {
+		if r.Contains(n.X, n.Y) {
+			s.SetNote(n.X, n.Y, "")
+			found = true
+		}
+	}
+	if !found {
+		a.output.SetStatus("no notes", ui.StatusFlagError)
+		return
+	}
+	a.output.SetDirty(ui.DirtyGrid)
+}
+
+// showNoteOnMove shows the note of the cell under cursor once the cursor has been moved from given position
+// or to another sheet.
+func (a *App) showNoteOnMove(s *sheet.Sheet, cursor sheet.Cursor) {
+	if s == a.doc.CurrentSheet && s.Cursor == cursor {
+		return
+	}
+	cur := a.doc.CurrentSheet.Cursor
+	if note := a.doc.CurrentSheet.Note(cur.X, cur.Y); note != "" {
+		a.output.SetStatus(noteStatus(note), 0)
+		a.output.RefreshView()
+	}
+}
+
+// noteStatus makes the note fit into the status line.
+func noteStatus(note string) string {
+	return strings.Join(strings.Fields(note), " ")
+}
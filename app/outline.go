@@ -0,0 +1,127 @@
+package app
+
+import (
+	"xl/document"
+	"xl/document/sheet"
+	"xl/ui"
+
+	"fmt"
+	"strings"
+)
+
+// cmdHide hides or shows rows (columns if rows is not set) given like "3:5" ("B:D") or selected ones,
+// the row (column) under cursor by default. Showing with no range given also shows hidden rows (columns)
+// next to the cursor.
+func (a *App) cmdHide(rows, hidden bool, args []string) {
+	s := a.doc.CurrentSheet
+	o, _ := a.outlineAtCursor(rows)
+	from, to, err := a.outlineRange(rows, arg1(args))
+	if err != nil {
+		a.showError(err)
+		return
+	}
+	if !hidden && len(args) == 0 && s.Selection.Width == 0 {
+		for from > 0 && o.Hidden(from-1) {
+			from--
+		}
+		for o.Hidden(to + 1) {
+			to++
+		}
+	}
+	for n := from; n <= to; n++ {
+		o.SetHidden(n, hidden)
+	}
+	// the cursor must not stay on a hidden row or column
+	a.moveCursorTo(s.Cursor.X, s.Cursor.Y)
+}
+
+// cmdGroup adds rows (columns if rows is not set) to a group or removes them from the innermost one
+// depending on the sign of delta. The range is given like in cmdHide.
+func (a *App) cmdGroup(rows bool, delta int, args []string) {
+	o, _ := a.outlineAtCursor(rows)
+	from, to, err := a.outlineRange(rows, arg1(args))
+	if err != nil {
+		a.showError(err)
+		return
+	}
+	for n := from; n <= to; n++ {
+		if delta > 0 && o.Level(n) >= sheet.OutlineMaxLevel {
+			a.output.SetStatus(fmt.Sprintf("groups can be nested up to %d levels", sheet.OutlineMaxLevel), ui.StatusFlagError)
+			return
+		}
+	}
+	for n := from; n <= to; n++ {
+		o.SetLevel(n, o.Level(n)+delta)
+	}
+}
+
+// cmdCollapse hides or shows rows (columns if rows is not set) of the group under cursor.
+// On the row (column) right after a group it works on that group.
+func (a *App) cmdCollapse(rows, collapse bool) {
+	s := a.doc.CurrentSheet
+	o, pos := a.outlineAtCursor(rows)
+	from, to, ok := o.Group(pos)
+	if !ok {
+		a.output.SetStatus("no group under cursor", ui.StatusFlagError)
+		return
+	}
+	for n := from; n <= to; n++ {
+		o.SetHidden(n, collapse)
+	}
+	// the cursor goes to the summary row (column) after the collapsed group
+	a.moveCursorTo(s.Cursor.X, s.Cursor.Y)
+}
+
+// outlineAtCursor returns row or column outline of the current sheet and the cursor position in it.
+func (a *App) outlineAtCursor(rows bool) (*sheet.Outline, int) {
+	s := a.doc.CurrentSheet
+	if rows {
+		return s.Rows, s.Cursor.Y
+	}
+	return s.Cols, s.Cursor.X
+}
+
+// outlineRange parses the range of rows like "3:5" or columns like "B:D". If it is empty, returns
+// rows (columns) of the selection or the one under cursor.
+func (a *App) outlineRange(rows bool, r string) (int, int, error) {
+	s := a.doc.CurrentSheet
+	if r == "" {
+		switch {
+		case s.Selection.Width == 0 && rows:
+			return s.Cursor.Y, s.Cursor.Y, nil
+		case s.Selection.Width == 0:
+			return s.Cursor.X, s.Cursor.X, nil
+		case rows:
+			return s.Selection.Y, s.Selection.MaxY(), nil
+		}
+		return s.Selection.X, s.Selection.MaxX(), nil
+	}
+	parse := columnByName
+	if rows {
+		parse = rowByName
+	}
+	parts := strings.SplitN(r, ":", 2)
+	from, err := parse(parts[0])
+	if err != nil {
+		return 0, 0, err
+	}
+	to := from
+	if len(parts) == 2 {
+		if to, err = parse(parts[1]); err != nil {
+			return 0, 0, err
+		}
+	}
+	if to < from {
+		from, to = to, from
+	}
+	return from, to, nil
+}
+
+// rowByName returns Y of the row with given name like "3".
+func rowByName(name string) (int, error) {
+	x, y, err := document.CellAxis("A" + name)
+	if err != nil || x != 0 {
+		return 0, fmt.Errorf("invalid row %s", name)
+	}
+	return y, nil
+}
@@ -0,0 +1,177 @@
+package app
+
+import (
+	"xl/document"
+	"xl/document/eval"
+	"xl/document/sheet"
+	"xl/ui"
+
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// searchState keeps the last search pattern to be repeated by n and N.
+type searchState struct {
+	source   string
+	pattern  *regexp.Regexp
+	backward bool
+	// matches are highlighted in the grid until nohlsearch command
+	highlight bool
+}
+
+// inputSearch reads the search pattern and moves the cursor to the next matching cell.
+// Empty pattern repeats the last search.
+func (a *App) inputSearch(backward bool) {
+	prompt := "/"
+	if backward {
+		prompt = "?"
+	}
+	source, err := a.output.InputCommand(prompt)
+	if err != nil {
+		a.showError(err)
+		return
+	}
+	if source != "" {
+		if err := a.setSearchPattern(source, a.options.ignoreCase); err != nil {
+			a.showError(err)
+			return
+		}
+	}
+	a.search.backward = backward
+	a.searchNext(false)
+}
+
+// setSearchPattern compiles the pattern and makes it the current one.
+func (a *App) setSearchPattern(source string, ignoreCase bool) error {
+	re, err := document.SearchPattern(source, a.options.regex, ignoreCase)
+	if err != nil {
+		return err
+	}
+	a.search.source = source
+	a.search.pattern = re
+	a.search.highlight = true
+	a.output.SetDirty(ui.DirtyGrid)
+	return nil
+}
+
+// searchNext moves the cursor to the next cell matching the last pattern, in the opposite direction if reverse is set.
+func (a *App) searchNext(reverse bool) {
+	if a.search.pattern == nil {
+		a.output.SetStatus("no previous search pattern", ui.StatusFlagError)
+		return
+	}
+	a.search.highlight = true
+	a.output.SetDirty(ui.DirtyGrid)
+	s := a.doc.CurrentSheet
+	from := eval.Cell{SheetIdx: s.Idx, X: s.Cursor.X, Y: s.Cursor.Y}
+	c, ok := a.doc.Find(a.search.pattern, from, a.search.backward != reverse, a.options.search)
+	if !ok {
+		a.output.SetStatus(fmt.Sprintf("pattern not found: %s", a.search.source), ui.StatusFlagError)
+		return
+	}
+	for i, s := range a.doc.Sheets {
+		if s.Idx == c.SheetIdx && s != a.doc.CurrentSheet {
+			a.switchSheet(i)
+		}
+	}
+	a.moveCursorTo(c.X, c.Y)
+}
+
+// cellMatches checks if the cell with given display value matches the search pattern to be highlighted.
+func (a *App) cellMatches(c *sheet.Cell, displayValue string) bool {
+	if !a.search.highlight || a.search.pattern == nil || c == nil || c.RawValue() == "" {
+		return false
+	}
+	if a.options.search.Formulas {
+		return a.search.pattern.MatchString(c.RawValue())
+	}
+	return a.search.pattern.MatchString(displayValue)
+}
+
+// cmdSubstitute replaces text in raw cell values: "s/old/new/flags" works on the selection or the current sheet,
+// "%s/old/new/flags" works on all sheets. Flags are "g" to replace all occurrences in a cell
+// and "i" to ignore case.
+func (a *App) cmdSubstitute(line string) {
+	allSheets := strings.HasPrefix(line, "%")
+	parts := splitSubstitute(line[strings.IndexByte(line, '/')+1:])
+	if len(parts) < 2 || len(parts) > 3 || parts[0] == "" {
+		a.output.SetStatus("usage: s/pattern/replacement/flags", ui.StatusFlagError)
+		return
+	}
+	var all, ignoreCase bool
+	if len(parts) == 3 {
+		for _, f := range parts[2] {
+			switch f {
+			case 'g':
+				all = true
+			case 'i':
+				ignoreCase = true
+			default:
+				a.output.SetStatus(fmt.Sprintf("unknown flag %c", f), ui.StatusFlagError)
+				return
+			}
+		}
+	}
+	if err := a.setSearchPattern(parts[0], a.options.ignoreCase || ignoreCase); err != nil {
+		a.showError(err)
+		return
+	}
+	re, replacement := a.search.pattern, parts[1]
+	replace := func(v string) string {
+		loc := re.FindStringSubmatchIndex(v)
+		if loc == nil {
+			return v
+		}
+		if all {
+			if a.options.regex {
+				return re.ReplaceAllString(v, replacement)
+			}
+			return re.ReplaceAllLiteralString(v, replacement)
+		}
+		r := replacement
+		if a.options.regex {
+			r = string(re.ExpandString(nil, replacement, v, loc))
+		}
+		return v[:loc[0]] + r + v[loc[1]:]
+	}
+
+	sheets := []*sheet.Sheet{a.doc.CurrentSheet}
+	area := a.doc.CurrentSheet.Selection
+	if allSheets {
+		sheets = a.doc.Sheets
+		area = sheet.Rect{}
+	}
+	n := a.doc.Replace(re, replace, sheets, area)
+	if n == 0 {
+		a.output.SetStatus(fmt.Sprintf("pattern not found: %s", parts[0]), ui.StatusFlagError)
+		return
+	}
+	a.output.SetStatus(fmt.Sprintf("%d cells changed", n), 0)
+	a.output.SetDirty(ui.DirtyGrid | ui.DirtyFormulaLine)
+}
+
+// splitSubstitute splits "old/new/flags" by slashes, "\/" stands for a slash itself.
+func splitSubstitute(s string) []string {
+	var parts []string
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && i+1 < len(s) && s[i+1] == '/':
+			b.WriteByte('/')
+			i++
+		case s[i] == '/':
+			parts = append(parts, b.String())
+			b.Reset()
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return append(parts, b.String())
+}
+
+// cmdNoHighlight stops highlighting cells matching the search pattern until the next search.
+func (a *App) cmdNoHighlight() {
+	a.search.highlight = false
+	a.output.SetDirty(ui.DirtyGrid)
+}
@@ -0,0 +1,173 @@
+package app
+
+import (
+	"xl/document"
+	"xl/document/eval"
+	"xl/document/sheet"
+	"xl/ui"
+
+	"fmt"
+	"strings"
+)
+
+// cmdValidate manages validations of entered values: "validate [RANGE] [warn] [message=TEXT] KIND ARGS..." adds
+// the validation for the range or the selection, "validate clear [RANGE]" removes validations, with no arguments
+// the validation of the cell under cursor is shown. KIND with ARGS is "list VALUES...", "list =$A$1:$A$5",
+// "number CONDITIONS...", "whole CONDITIONS...", "date CONDITIONS..." with conditions like ">=1" or "<=2024-12-31",
+// or "formula =B2>C2" written for the top left cell of the range. With "warn" invalid values are kept after
+// the warning, otherwise they are rejected.
+func (a *App) cmdValidate(args []string) {
+	s := a.doc.CurrentSheet
+	if len(args) == 0 {
+		v := s.Validation(s.Cursor.X, s.Cursor.Y)
+		if v == nil {
+			a.output.SetStatus("no validation", 0)
+			return
+		}
+		a.output.SetStatus(validationDescription(v), 0)
+		return
+	}
+	if args[0] == "clear" {
+		r, err := a.cellRange(arg1(args[1:]))
+		if err != nil {
+			a.showError(err)
+			return
+		}
+		if !s.RemoveValidations(r) {
+			a.output.SetStatus("no validations", ui.StatusFlagError)
+		}
+		return
+	}
+	v, err := a.parseValidation(args)
+	if err != nil {
+		a.showError(err)
+		return
+	}
+	s.AddValidation(v)
+}
+
+// parseValidation parses arguments of cmdValidate.
+func (a *App) parseValidation(args []string) (*sheet.Validation, error) {
+	v := &sheet.Validation{Range: a.selectedRect()}
+	if r, err := a.cellRange(args[0]); err == nil {
+		v.Range = r
+		args = args[1:]
+	}
+	for len(args) > 0 && (args[0] == "warn" || strings.HasPrefix(args[0], "message=")) {
+		if args[0] == "warn" {
+			v.Warning = true
+		} else {
+			v.Message = strings.TrimPrefix(args[0], "message=")
+		}
+		args = args[1:]
+	}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("kind of validation expected")
+	}
+	kind, params := args[0], args[1:]
+	switch kind {
+	case "list":
+		if len(params) == 0 {
+			return nil, fmt.Errorf("values expected")
+		}
+		v.Kind = sheet.ValidateList
+		if len(params) == 1 && strings.HasPrefix(params[0], "=") {
+			v.Source = params[0]
+		} else {
+			v.Values = params
+		}
+	case "number", "whole", "date":
+		v.Kind = map[string]int{"number": sheet.ValidateDecimal, "whole": sheet.ValidateWhole, "date": sheet.ValidateDate}[kind]
+		for _, p := range params {
+			if !sheet.IsFilterCondition(p) {
+				return nil, fmt.Errorf("invalid condition %s", p)
+			}
+			v.Conditions = append(v.Conditions, sheet.ParseFilterCondition(p))
+		}
+	case "formula":
+		if len(params) != 1 || !strings.HasPrefix(params[0], "=") {
+			return nil, fmt.Errorf("formula expected")
+		}
+		v.Kind = sheet.ValidateFormula
+		v.Formula = params[0]
+	default:
+		return nil, fmt.Errorf("unknown kind of validation %s", kind)
+	}
+	return v, nil
+}
+
+// validationDescription describes the validation to show in the status line.
+func validationDescription(v *sheet.Validation) string {
+	desc := rangeName(v.Range) + ": " + document.ValidationDescription(v)
+	if v.Warning {
+		desc += " (warning)"
+	}
+	return desc
+}
+
+// checkValidation validates the value just entered into the cell under cursor. Invalid values are replaced
+// with the previous value unless the validation only warns about them.
+func (a *App) checkValidation(previous string) {
+	s := a.doc.CurrentSheet
+	err := a.doc.ValidateCell(s, s.Cursor.X, s.Cursor.Y)
+	if err == nil {
+		return
+	}
+	if s.Validation(s.Cursor.X, s.Cursor.Y).Warning {
+		a.output.SetStatus("warning: "+err.Error(), ui.StatusFlagError)
+		return
+	}
+	s.CellUnderCursor().SetValueUntyped(previous)
+	a.doc.UpdateSpill(eval.Cell{SheetIdx: s.Idx, X: s.Cursor.X, Y: s.Cursor.Y})
+	a.output.SetStatus(err.Error(), ui.StatusFlagError)
+}
+
+// pickValue lets user choose the value of the cell under cursor from the list allowed by its validation.
+func (a *App) pickValue() {
+	s := a.doc.CurrentSheet
+	cur := s.Cursor
+	v := s.Validation(cur.X, cur.Y)
+	if v == nil || v.Kind != sheet.ValidateList {
+		a.output.SetStatus("cell has no list of values", ui.StatusFlagError)
+		return
+	}
+	ref := eval.Cell{SheetIdx: s.Idx, X: cur.X, Y: cur.Y}
+	cell := s.Cell(cur.X, cur.Y)
+	if cell == nil {
+		cell = sheet.NewCellEmpty()
+	}
+	if cell.RawValue() == "" && a.doc.Spilled(ref) {
+		a.output.SetStatus("cell is a part of array, change the formula it comes from", ui.StatusFlagError)
+		return
+	}
+	values, err := a.doc.ValidationValues(s, v)
+	if err != nil {
+		a.showError(err)
+		return
+	}
+	if len(values) == 0 {
+		a.output.SetStatus("list of values is empty", ui.StatusFlagError)
+		return
+	}
+	selected := -1
+	for i, value := range values {
+		if strings.EqualFold(value, cell.RawValue()) {
+			selected = i
+		}
+	}
+	// the picker is placed under the cursor drawn last time
+	a.output.SetDirty(ui.DirtyGrid)
+	a.output.RefreshView()
+	i, err := a.output.PickValue(values, selected)
+	if err != nil {
+		a.showError(err)
+		return
+	}
+	if i < 0 {
+		return
+	}
+	cell.SetValueUntyped(values[i])
+	s.SetCell(cur.X, cur.Y, cell)
+	a.doc.UpdateSpill(ref)
+	a.output.SetDirty(ui.DirtyGrid | ui.DirtyFormulaLine)
+}
@@ -0,0 +1,312 @@
+package app
+
+import (
+	"xl/document/sheet"
+	"xl/ui"
+
+	"fmt"
+)
+
+// window shows a sheet in a part of the grid area. The cursor, the viewport and the selection of the active window
+// are kept in its sheet where all the commands work with them, other windows keep them here until activated.
+type window struct {
+	sheet     *sheet.Sheet
+	cursor    sheet.Cursor
+	viewport  sheet.Viewport
+	selection sheet.Rect
+}
+
+// resetWindows leaves the only window showing the current sheet.
+func (a *App) resetWindows() {
+	a.windows = []*window{{sheet: a.doc.CurrentSheet}}
+	a.activeWindow = 0
+	a.layout = &ui.WindowLayout{}
+}
+
+// saveWindow remembers the state of the active window.
+func (a *App) saveWindow() {
+	s := a.doc.CurrentSheet
+	w := a.windows[a.activeWindow]
+	w.sheet, w.cursor, w.viewport, w.selection = s, s.Cursor, s.Viewport, s.Selection
+}
+
+// shiftWindows moves the cursor, the viewport and the selection of other windows showing the current sheet
+// after the row (or the column if rows is false) at n has been inserted (delta 1) or deleted (delta -1).
+func (a *App) shiftWindows(rows bool, n, delta int) {
+	shift := func(v int) int {
+		if v > n || (v == n && delta > 0) {
+			return v + delta
+		}
+		return v
+	}
+	for i, w := range a.windows {
+		if i == a.activeWindow || w.sheet != a.doc.CurrentSheet {
+			continue
+		}
+		if rows {
+			w.cursor.Y, w.viewport.Top = shift(w.cursor.Y), shift(w.viewport.Top)
+			w.selection.Y, w.selection.Height = shiftRange(w.selection.Y, w.selection.Height, n, delta)
+		} else {
+			w.cursor.X, w.viewport.Left = shift(w.cursor.X), shift(w.viewport.Left)
+			w.selection.X, w.selection.Width = shiftRange(w.selection.X, w.selection.Width, n, delta)
+		}
+		if w.selection.Width == 0 || w.selection.Height == 0 {
+			w.selection = sheet.Rect{}
+		}
+	}
+}
+
+// shiftRange moves the range of size items starting from start, or resizes it if it contains n.
+func shiftRange(start, size, n, delta int) (int, int) {
+	switch {
+	case size == 0:
+		return start, size
+	case start > n || (start == n && delta > 0):
+		return start + delta, size
+	case start+size > n:
+		return start, size + delta
+	}
+	return start, size
+}
+
+// followSortedRows moves the cursor of other windows showing the sheet to the new position of its row.
+func (a *App) followSortedRows(s *sheet.Sheet, area sheet.Rect, newY map[int]int) {
+	for i, w := range a.windows {
+		if i == a.activeWindow || w.sheet != s || w.cursor.X < area.X || w.cursor.X > area.MaxX() {
+			continue
+		}
+		if y, ok := newY[w.cursor.Y]; ok {
+			w.cursor.Y = y
+		}
+	}
+}
+
+// activateWindow makes the window with given index the active one.
+func (a *App) activateWindow(n int) {
+	if n == a.activeWindow {
+		return
+	}
+	a.saveWindow()
+	a.activeWindow = n
+	w := a.windows[n]
+	for i, s := range a.doc.Sheets {
+		if s == w.sheet {
+			a.doc.CurrentSheetN = i
+		}
+	}
+	a.doc.CurrentSheet = w.sheet
+	w.sheet.Cursor, w.sheet.Viewport, w.sheet.Selection = w.cursor, w.viewport, w.selection
+	a.output.SetDirty(ui.DirtyHRuler | ui.DirtyVRuler | ui.DirtyGrid | ui.DirtyFormulaLine | ui.DirtyStatusLine)
+}
+
+// cmdSplit splits the active window into two, one above another or side by side if vertical is set.
+// The new window becomes active and shows the sheet with given title or the same part of the current sheet.
+func (a *App) cmdSplit(vertical bool, title string) {
+	s := a.doc.CurrentSheet
+	if title != "" {
+		s = nil
+		for _, ds := range a.doc.Sheets {
+			if ds.Title == title {
+				s = ds
+			}
+		}
+		if s == nil {
+			a.output.SetStatus(fmt.Sprintf("sheet %s does not exist", title), ui.StatusFlagError)
+			return
+		}
+	}
+	a.saveWindow()
+	w := *a.windows[a.activeWindow]
+	w.selection = sheet.Rect{}
+	if s != w.sheet {
+		w = window{sheet: s, cursor: s.Cursor, viewport: s.Viewport}
+	}
+	a.windows = append(a.windows, &w)
+
+	leaf, parent := findLeaf(a.layout, nil, a.activeWindow)
+	newLeaf := &ui.WindowLayout{Window: len(a.windows) - 1}
+	switch {
+	case parent != nil && parent.Vertical == vertical:
+		// the new window goes before the active one, like in vim
+		i := childIndex(parent, leaf)
+		parent.Children = append(parent.Children[:i], append([]*ui.WindowLayout{newLeaf}, parent.Children[i:]...)...)
+	default:
+		*leaf = ui.WindowLayout{
+			Vertical: vertical,
+			Children: []*ui.WindowLayout{newLeaf, {Window: leaf.Window}},
+		}
+	}
+	a.activateWindow(len(a.windows) - 1)
+}
+
+// cmdClose closes the active window, the next one becomes active. Returns false if it is the last window.
+func (a *App) cmdClose() bool {
+	if len(a.windows) <= 1 {
+		return false
+	}
+	closed := a.activeWindow
+	leaves := windowOrder(a.layout)
+	next := leaves[0]
+	for i, n := range leaves {
+		if n == closed && i+1 < len(leaves) {
+			next = leaves[i+1]
+		} else if n == closed && i > 0 {
+			next = leaves[i-1]
+		}
+	}
+	a.activateWindow(next)
+
+	leaf, parent := findLeaf(a.layout, nil, closed)
+	i := childIndex(parent, leaf)
+	parent.Children = append(parent.Children[:i], parent.Children[i+1:]...)
+	if len(parent.Children) == 1 {
+		*parent = *parent.Children[0]
+	}
+	a.windows = append(a.windows[:closed], a.windows[closed+1:]...)
+	renumberWindows(a.layout, closed)
+	if a.activeWindow > closed {
+		a.activeWindow--
+	}
+	a.output.SetDirty(ui.DirtyHRuler | ui.DirtyVRuler | ui.DirtyGrid)
+	return true
+}
+
+// cmdOnly closes all the windows but the active one.
+func (a *App) cmdOnly() {
+	a.saveWindow()
+	a.windows = []*window{a.windows[a.activeWindow]}
+	a.activeWindow = 0
+	a.layout = &ui.WindowLayout{}
+	a.output.SetDirty(ui.DirtyHRuler | ui.DirtyVRuler | ui.DirtyGrid)
+}
+
+// windowCommand does the job of vim-like window commands typed after Ctrl-W: h, j, k, l move to the window
+// at the left, below, above or at the right, w and W go to the next and the previous window, s and v split
+// the window, c closes it and o closes all the others.
+func (a *App) windowCommand(ch rune) {
+	switch ch {
+	case 'h', 'j', 'k', 'l':
+		if n, ok := a.neighbourWindow(ch); ok {
+			a.activateWindow(n)
+		}
+	case 'w', 'W':
+		leaves := windowOrder(a.layout)
+		for i, n := range leaves {
+			if n != a.activeWindow {
+				continue
+			}
+			if ch == 'w' {
+				a.activateWindow(leaves[(i+1)%len(leaves)])
+			} else {
+				a.activateWindow(leaves[(i+len(leaves)-1)%len(leaves)])
+			}
+			return
+		}
+	case 's':
+		a.cmdSplit(false, "")
+	case 'v':
+		a.cmdSplit(true, "")
+	case 'c':
+		if !a.cmdClose() {
+			a.output.SetStatus("cannot close the last window", ui.StatusFlagError)
+		}
+	case 'o':
+		a.cmdOnly()
+	}
+}
+
+// neighbourWindow finds the window next to the active one in the direction of h, j, k or l key.
+func (a *App) neighbourWindow(dir rune) (int, bool) {
+	vertical := dir == 'h' || dir == 'l'
+	step := 1
+	if dir == 'h' || dir == 'k' {
+		step = -1
+	}
+	path := layoutPath(a.layout, a.activeWindow)
+	// the nearest node dividing the area in the direction having a child next to the one on the path
+	for i := len(path) - 2; i >= 0; i-- {
+		node := path[i]
+		if node.Vertical != vertical {
+			continue
+		}
+		j := childIndex(node, path[i+1]) + step
+		if j < 0 || j >= len(node.Children) {
+			continue
+		}
+		// go down to the nearest window of the neighbour area
+		l := node.Children[j]
+		for len(l.Children) > 0 {
+			if l.Vertical == vertical && step < 0 {
+				l = l.Children[len(l.Children)-1]
+			} else {
+				l = l.Children[0]
+			}
+		}
+		return l.Window, true
+	}
+	return 0, false
+}
+
+// findLeaf returns the leaf of the layout showing the window and its parent, nil for the root.
+func findLeaf(l, parent *ui.WindowLayout, n int) (*ui.WindowLayout, *ui.WindowLayout) {
+	if len(l.Children) == 0 {
+		if l.Window == n {
+			return l, parent
+		}
+		return nil, nil
+	}
+	for _, c := range l.Children {
+		if leaf, p := findLeaf(c, l, n); leaf != nil {
+			return leaf, p
+		}
+	}
+	return nil, nil
+}
+
+// layoutPath returns nodes of the layout from the root to the leaf showing the window.
+func layoutPath(l *ui.WindowLayout, n int) []*ui.WindowLayout {
+	if len(l.Children) == 0 {
+		if l.Window == n {
+			return []*ui.WindowLayout{l}
+		}
+		return nil
+	}
+	for _, c := range l.Children {
+		if path := layoutPath(c, n); path != nil {
+			return append([]*ui.WindowLayout{l}, path...)
+		}
+	}
+	return nil
+}
+
+// childIndex returns the position of the child in the node, or -1.
+func childIndex(node, child *ui.WindowLayout) int {
+	for i, c := range node.Children {
+		if c == child {
+			return i
+		}
+	}
+	return -1
+}
+
+// windowOrder lists windows from left to right and from top to bottom.
+func windowOrder(l *ui.WindowLayout) []int {
+	if len(l.Children) == 0 {
+		return []int{l.Window}
+	}
+	var res []int
+	for _, c := range l.Children {
+		res = append(res, windowOrder(c)...)
+	}
+	return res
+}
+
+// renumberWindows updates leaves of the layout after the window with given index has been removed.
+func renumberWindows(l *ui.WindowLayout, removed int) {
+	if len(l.Children) == 0 && l.Window > removed {
+		l.Window--
+	}
+	for _, c := range l.Children {
+		renumberWindows(c, removed)
+	}
+}
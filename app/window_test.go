@@ -0,0 +1,59 @@
+package app
+
+import (
+	"xl/document"
+	"xl/document/sheet"
+
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestShiftWindows(t *testing.T) {
+	testCases := []struct {
+		name      string
+		cmd       func(a *App)
+		cursor    sheet.Cursor
+		viewport  sheet.Viewport
+		selection sheet.Rect
+	}{
+		{"insert row above", func(a *App) { a.cmdInsertRow(0) }, sheet.Cursor{X: 2, Y: 6}, sheet.Viewport{Left: 1, Top: 4},
+			sheet.Rect{X: 1, Y: 5, Width: 2, Height: 3}},
+		{"insert row inside", func(a *App) { a.moveCursorTo(0, 5); a.cmdInsertRow(0) }, sheet.Cursor{X: 2, Y: 6},
+			sheet.Viewport{Left: 1, Top: 3}, sheet.Rect{X: 1, Y: 4, Width: 2, Height: 4}},
+		{"insert row below", func(a *App) { a.moveCursorTo(0, 6); a.cmdInsertRow(1) }, sheet.Cursor{X: 2, Y: 5},
+			sheet.Viewport{Left: 1, Top: 3}, sheet.Rect{X: 1, Y: 4, Width: 2, Height: 3}},
+		{"delete row above", func(a *App) { a.cmdDeleteRow() }, sheet.Cursor{X: 2, Y: 4}, sheet.Viewport{Left: 1, Top: 2},
+			sheet.Rect{X: 1, Y: 3, Width: 2, Height: 3}},
+		{"delete row inside", func(a *App) { a.moveCursorTo(0, 4); a.cmdDeleteRow() }, sheet.Cursor{X: 2, Y: 4},
+			sheet.Viewport{Left: 1, Top: 3}, sheet.Rect{X: 1, Y: 4, Width: 2, Height: 2}},
+		{"insert col", func(a *App) { a.cmdInsertCol(0) }, sheet.Cursor{X: 3, Y: 5}, sheet.Viewport{Left: 2, Top: 3},
+			sheet.Rect{X: 2, Y: 4, Width: 2, Height: 3}},
+		{"delete col", func(a *App) { a.moveCursorTo(1, 0); a.cmdDeleteCol() }, sheet.Cursor{X: 1, Y: 5},
+			sheet.Viewport{Left: 1, Top: 3}, sheet.Rect{X: 1, Y: 4, Width: 1, Height: 3}},
+		{"sort", func(a *App) { a.cmdSort([]string{"-C"}) }, sheet.Cursor{X: 2, Y: 2}, sheet.Viewport{Left: 1, Top: 3},
+			sheet.Rect{X: 1, Y: 4, Width: 2, Height: 3}},
+	}
+	for _, c := range testCases {
+		a := &App{output: &fakeOutput{}, logger: zap.NewNop(), hotKeys: make(map[string]string)}
+		a.doc = document.NewWithEmptySheet()
+		a.resetWindows()
+		s := a.doc.CurrentSheet
+		for y := 0; y < 8; y++ {
+			s.SetCell(2, y, sheet.NewCellUntyped(strconv.Itoa(y)))
+		}
+		s.Cursor = sheet.Cursor{X: 2, Y: 5}
+		s.Viewport = sheet.Viewport{Left: 1, Top: 3}
+		s.Selection = sheet.Rect{X: 1, Y: 4, Width: 2, Height: 3}
+		a.cmdSplit(false, "")
+		a.moveCursorTo(0, 1)
+		c.cmd(a)
+
+		a.activateWindow(0)
+		assert.Equalf(t, c.cursor, s.Cursor, "case %s", c.name)
+		assert.Equalf(t, c.viewport, s.Viewport, "case %s", c.name)
+		assert.Equalf(t, c.selection, s.Selection, "case %s", c.name)
+	}
+}
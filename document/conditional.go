@@ -0,0 +1,243 @@
+package document
+
+import (
+	"xl/document/eval"
+	"xl/document/sheet"
+	"xl/formula"
+
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+var relativeCellPattern = regexp.MustCompile(`^(\$?)([A-Za-z]+)(\$?)([0-9]+)$`)
+
+// ConditionalStyle returns the style given to the cell by conditional formatting rules of its sheet.
+// Settings of earlier rules override the ones of later rules.
+func (d *Document) ConditionalStyle(s *sheet.Sheet, x, y int) sheet.Style {
+	var st sheet.Style
+	ec := eval.NewContext(d, s.Idx)
+	for i := len(s.Rules) - 1; i >= 0; i-- {
+		r := s.Rules[i]
+		if !r.Range.Contains(x, y) {
+			continue
+		}
+		if r.Kind == sheet.RuleColorScale {
+			if bg, ok := d.scaleColor(ec, s, r, x, y); ok {
+				st.Bg = bg
+			}
+			continue
+		}
+		if d.ruleMatches(ec, s, r, x, y) {
+			st = st.Overlay(r.Style)
+		}
+	}
+	return st
+}
+
+// ruleMatches checks if the cell meets the condition of the rule.
+func (d *Document) ruleMatches(ec *eval.Context, s *sheet.Sheet, r *sheet.ConditionalRule, x, y int) bool {
+	cell := eval.Cell{SheetIdx: s.Idx, X: x, Y: y}
+	switch r.Kind {
+	case sheet.RuleCompare:
+		v, err := d.StringValue(ec, cell)
+		return err == nil && r.Condition.Matches(v)
+	case sheet.RuleTop, sheet.RuleBottom:
+		v, ok := d.numberValue(ec, cell)
+		if !ok {
+			return false
+		}
+		// the number is among N greatest if less than N numbers are greater
+		numbers := d.rangeStats(ec, s, r).numbers
+		n := len(numbers) - sort.Search(len(numbers), func(i int) bool { return numbers[i].GreaterThan(v) })
+		if r.Kind == sheet.RuleBottom {
+			n = sort.Search(len(numbers), func(i int) bool { return numbers[i].GreaterThanOrEqual(v) })
+		}
+		return n < r.N
+	case sheet.RuleDuplicate:
+		v, err := d.StringValue(ec, cell)
+		return err == nil && v != "" && d.rangeStats(ec, s, r).counts[strings.ToLower(v)] > 1
+	case sheet.RuleFormula:
+		c := sheet.NewCellUntyped(relativeFormula(r.Formula, s.Title, x-r.Range.X, y-r.Range.Y))
+		defer c.Free()
+		res, err := c.BoolValue(ec)
+		return err == nil && res
+	}
+	return false
+}
+
+// scaleColor returns the color of the cell for the color scale rule, the cell must have a number.
+func (d *Document) scaleColor(ec *eval.Context, s *sheet.Sheet, r *sheet.ConditionalRule, x, y int) (string, bool) {
+	v, ok := d.numberValue(ec, eval.Cell{SheetIdx: s.Idx, X: x, Y: y})
+	if !ok {
+		return "", false
+	}
+	// the cell is in the range, so there is at least one number
+	numbers := d.rangeStats(ec, s, r).numbers
+	min, max := numbers[0], numbers[len(numbers)-1]
+	pos := 0.5
+	if !min.Equal(max) {
+		pos, _ = v.Sub(min).Div(max.Sub(min)).Float64()
+	}
+	return blendColors(r.MinColor, r.MaxColor, pos), true
+}
+
+// numberValue returns the value of the cell if it is a number.
+func (d *Document) numberValue(ec *eval.Context, cell eval.Cell) (decimal.Decimal, bool) {
+	v, err := d.Value(ec, cell)
+	if err != nil {
+		return decimal.Zero, false
+	}
+	if t, err := v.Type(ec); err != nil || t != eval.TypeDecimal {
+		return decimal.Zero, false
+	}
+	n, err := v.DecimalValue(ec)
+	return n, err == nil
+}
+
+// ruleStats describes values of the conditional formatting rule range.
+type ruleStats struct {
+	// numbers of the range in ascending order, other values are skipped
+	numbers []decimal.Decimal
+	// counts of lower-cased non-empty values
+	counts map[string]int
+}
+
+// rangeStats returns values of the rule range collected once until ResetRuleStats is called.
+func (d *Document) rangeStats(ec *eval.Context, s *sheet.Sheet, r *sheet.ConditionalRule) *ruleStats {
+	if st, ok := d.ruleStats[r]; ok {
+		return st
+	}
+	st := &ruleStats{counts: make(map[string]int)}
+	for x := r.Range.X; x <= r.Range.MaxX(); x++ {
+		for y := r.Range.Y; y <= r.Range.MaxY(); y++ {
+			cell := eval.Cell{SheetIdx: s.Idx, X: x, Y: y}
+			if r.Kind == sheet.RuleDuplicate {
+				if v, err := d.StringValue(ec, cell); err == nil && v != "" {
+					st.counts[strings.ToLower(v)]++
+				}
+			} else if n, ok := d.numberValue(ec, cell); ok {
+				st.numbers = append(st.numbers, n)
+			}
+		}
+	}
+	sort.Slice(st.numbers, func(i, j int) bool { return st.numbers[i].LessThan(st.numbers[j]) })
+	if d.ruleStats == nil {
+		d.ruleStats = make(map[*sheet.ConditionalRule]*ruleStats)
+	}
+	d.ruleStats[r] = st
+	return st
+}
+
+// ResetRuleStats forgets values of conditional formatting ranges collected so far, it must be called
+// once cells have been changed.
+func (d *Document) ResetRuleStats() {
+	d.ruleStats = nil
+}
+
+// relativeFormula moves references of the formula on dx columns and dy rows except parts fixed with "$",
+// like in copied formulas. References get the sheet title, so the formula does not depend on the current sheet.
+func relativeFormula(source, sheetTitle string, dx, dy int) string {
+	return mapFormulaCells(source, func(ref string, qualified bool) string {
+		x, y, fixedX, fixedY, ok := parseCellRef(ref)
+		if !ok {
+			return ref
+		}
+		if !fixedX {
+			x += dx
+		}
+		if !fixedY {
+			y += dy
+		}
+		if !qualified {
+			return QuoteSheetTitle(sheetTitle) + "!" + cellRef(x, y, fixedX, fixedY)
+		}
+		return cellRef(x, y, fixedX, fixedY)
+	})
+}
+
+// moveRuleRefs updates references to the current sheet in formulas of its conditional formatting rules
+// and validations after the row (the column if rows is not set) N is inserted (delta is 1) or deleted (delta is -1).
+func (d *Document) moveRuleRefs(rows bool, n, delta int) {
+	move := func(source string) string {
+		return mapFormulaCells(source, func(ref string, qualified bool) string {
+			x, y, fixedX, fixedY, ok := parseCellRef(ref)
+			if !ok || qualified {
+				return ref
+			}
+			pos := &x
+			if rows {
+				pos = &y
+			}
+			if *pos > n || (*pos == n && delta > 0) {
+				*pos += delta
+			}
+			return cellRef(x, y, fixedX, fixedY)
+		})
+	}
+	for _, r := range d.CurrentSheet.Rules {
+		if r.Kind == sheet.RuleFormula {
+			r.Formula = move(r.Formula)
+		}
+	}
+	for _, v := range d.CurrentSheet.Validations {
+		v.Formula = move(v.Formula)
+		v.Source = move(v.Source)
+	}
+}
+
+// mapFormulaCells replaces cell references of the formula with results of f, qualified is set for references
+// with a sheet title.
+func mapFormulaCells(source string, f func(ref string, qualified bool) string) string {
+	var b strings.Builder
+	pos := 0
+	tokens := formula.Tokenize(source)
+	for i, t := range tokens {
+		if t.Type != formula.OutputTypeCell {
+			continue
+		}
+		b.WriteString(source[pos:t.Offset])
+		b.WriteString(f(t.Text, i > 0 && tokens[i-1].Type == formula.OutputTypeSheet))
+		pos = t.Offset + len(t.Text)
+	}
+	b.WriteString(source[pos:])
+	return b.String()
+}
+
+// parseCellRef parses the cell reference like "B2" or "$B2", fixedX and fixedY are set for parts fixed with "$".
+func parseCellRef(ref string) (x, y int, fixedX, fixedY, ok bool) {
+	m := relativeCellPattern.FindStringSubmatch(ref)
+	if m == nil {
+		return 0, 0, false, false, false
+	}
+	x, y, err := CellAxis(strings.ToUpper(m[2] + m[4]))
+	return x, y, m[1] != "", m[3] != "", err == nil
+}
+
+// cellRef returns the cell reference with parts fixed with "$" if fixedX or fixedY are set.
+func cellRef(x, y int, fixedX, fixedY bool) string {
+	ref := ColName(x)
+	if fixedX {
+		ref = "$" + ref
+	}
+	if fixedY {
+		return ref + "$" + RowName(y)
+	}
+	return ref + RowName(y)
+}
+
+// blendColors returns the color between colors like "#ff0000" at the position from 0 to 1.
+func blendColors(from, to string, pos float64) string {
+	var rgb [3]int64
+	for i := range rgb {
+		a, _ := strconv.ParseInt(from[1+i*2:3+i*2], 16, 64)
+		b, _ := strconv.ParseInt(to[1+i*2:3+i*2], 16, 64)
+		rgb[i] = a + int64(math.Round(float64(b-a)*pos))
+	}
+	return fmt.Sprintf("#%02x%02x%02x", rgb[0], rgb[1], rgb[2])
+}
@@ -24,12 +24,23 @@ type Document struct {
 
 	eval.RefRegistryInterface
 	refRegistry []*eval.CellRef
+
+	// Named ranges and constants, the key is upper-cased name.
+	names map[string]*definedName
+
+	// Ranges filled by array formulas.
+	spills []*spillRange
+
+	// Values of conditional formatting ranges collected since the last ResetRuleStats.
+	ruleStats map[*sheet.ConditionalRule]*ruleStats
 }
 
 var cellNamePattern = regexp.MustCompile(`^\$?([A-Z]+)\$?([0-9]+)$`)
 
 func New() *Document {
-	return &Document{}
+	return &Document{
+		names: make(map[string]*definedName),
+	}
 }
 
 func NewWithEmptySheet() *Document {
@@ -39,6 +50,7 @@ func NewWithEmptySheet() *Document {
 		CurrentSheet:  s,
 		CurrentSheetN: 0,
 		maxSheetIdx:   1,
+		names:         make(map[string]*definedName),
 	}
 }
 
@@ -71,6 +83,7 @@ func (d *Document) InsertEmptyRow(n int) {
 	d.CurrentSheet.Cursor.Y += n
 	d.CurrentSheet.InsertEmptyRow(d.CurrentSheet.Cursor.Y)
 	d.moveRefsDown(d.CurrentSheet.Cursor.Y)
+	d.moveRuleRefs(true, d.CurrentSheet.Cursor.Y, 1)
 }
 
 // InsertEmptyCol inserts new empty column at position of cursor plus N.
@@ -78,18 +91,21 @@ func (d *Document) InsertEmptyCol(n int) {
 	d.CurrentSheet.Cursor.X += n
 	d.CurrentSheet.InsertEmptyCol(d.CurrentSheet.Cursor.X)
 	d.moveRefsRight(d.CurrentSheet.Cursor.X)
+	d.moveRuleRefs(false, d.CurrentSheet.Cursor.X, 1)
 }
 
 // DeleteRow deletes row under cursor.
 func (d *Document) DeleteRow() {
 	d.CurrentSheet.DeleteRow(d.CurrentSheet.Cursor.Y)
 	d.moveRefsUp(d.CurrentSheet.Cursor.Y)
+	d.moveRuleRefs(true, d.CurrentSheet.Cursor.Y, -1)
 }
 
 // DeleteCol deletes column under cursor.
 func (d *Document) DeleteCol() {
 	d.CurrentSheet.DeleteCol(d.CurrentSheet.Cursor.X)
 	d.moveRefsLeft(d.CurrentSheet.Cursor.X)
+	d.moveRuleRefs(false, d.CurrentSheet.Cursor.X, -1)
 }
 
 // FindCell finds position of the cell with given name.
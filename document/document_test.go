@@ -4,6 +4,7 @@ import (
 	"xl/document/eval"
 	"xl/document/sheet"
 
+	"strconv"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -155,3 +156,338 @@ func TestCellRangeRefWithCircularRef(t *testing.T) {
 	_, err := d.CurrentSheet.Cell(0, 2).StringValue(eval.NewContext(d, d.CurrentSheet.Idx))
 	assert.EqualError(t, err, "circular reference")
 }
+
+func TestNamedRangeAndConstant(t *testing.T) {
+	d := NewWithEmptySheet()
+	d.CurrentSheet.AddStaticSegment(0, 0, 1, 3, [][]sheet.Cell{
+		{*sheet.NewCellUntyped("1"), *sheet.NewCellUntyped("2"), *sheet.NewCellUntyped("3")},
+	})
+	d.CurrentSheet.SetCell(1, 0, sheet.NewCellUntyped("=SUM(Revenue)*TaxRate"))
+
+	assert.NoError(t, d.SetName("Revenue", "A1:A3"))
+	assert.NoError(t, d.SetName("TaxRate", "0.5"))
+	assert.Error(t, d.SetName("B2", "A1"))
+
+	v, err := d.CurrentSheet.Cell(1, 0).StringValue(eval.NewContext(d, d.CurrentSheet.Idx))
+	assert.NoError(t, err)
+	assert.Equal(t, "3", v)
+
+	// name follows the range when rows are inserted
+	d.CurrentSheet.Cursor.Y = 0
+	d.InsertEmptyRow(0)
+	def, err := d.NameDefinition("revenue")
+	assert.NoError(t, err)
+	assert.Equal(t, "'Sheet 1'!$A$2:$A$4", def)
+
+	assert.NoError(t, d.DeleteName("TaxRate"))
+	_, err = d.CurrentSheet.Cell(1, 1).StringValue(eval.NewContext(d, d.CurrentSheet.Idx))
+	assert.EqualError(t, err, "name TaxRate is not defined")
+}
+
+func TestArrayFormulaSpill(t *testing.T) {
+	d := NewWithEmptySheet()
+	d.CurrentSheet.SetCell(0, 0, sheet.NewCellUntyped("=SEQUENCE(3)"))
+	d.CurrentSheet.SetCell(1, 0, sheet.NewCellUntyped("=SUM(A1:A3)"))
+	d.UpdateSpills()
+
+	ec := eval.NewContext(d, d.CurrentSheet.Idx)
+	v, err := d.StringValue(ec, eval.Cell{SheetIdx: d.CurrentSheet.Idx, X: 0, Y: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, "3", v)
+	assert.True(t, d.Spilled(eval.Cell{SheetIdx: d.CurrentSheet.Idx, X: 0, Y: 1}))
+
+	v, err = d.StringValue(ec, eval.Cell{SheetIdx: d.CurrentSheet.Idx, X: 1, Y: 0})
+	assert.NoError(t, err)
+	assert.Equal(t, "6", v)
+
+	// collision
+	d.CurrentSheet.SetCell(0, 1, sheet.NewCellUntyped("x"))
+	_, err = d.StringValue(ec, eval.Cell{SheetIdx: d.CurrentSheet.Idx, X: 0, Y: 0})
+	assert.EqualError(t, err, "#SPILL!")
+	assert.False(t, d.Spilled(eval.Cell{SheetIdx: d.CurrentSheet.Idx, X: 0, Y: 2}))
+}
+
+func TestFindAndReplace(t *testing.T) {
+	d := NewWithEmptySheet()
+	s1 := d.CurrentSheet
+	s1.SetCell(0, 0, sheet.NewCellUntyped("apple"))
+	s1.SetCell(2, 0, sheet.NewCellUntyped("=1+1"))
+	s1.SetCell(1, 3, sheet.NewCellUntyped("Pineapple"))
+	s2, _ := d.NewSheet("")
+	s2.SetCell(1, 1, sheet.NewCellUntyped("apple pie"))
+
+	re, err := SearchPattern("apple", false, false)
+	assert.NoError(t, err)
+	from := eval.Cell{SheetIdx: s1.Idx, X: 0, Y: 0}
+	c, ok := d.Find(re, from, false, SearchOptions{})
+	assert.True(t, ok)
+	assert.Equal(t, eval.Cell{SheetIdx: s1.Idx, X: 1, Y: 3}, c)
+	c, _ = d.Find(re, c, false, SearchOptions{})
+	assert.Equal(t, from, c, "wraps around the sheet")
+	c, _ = d.Find(re, from, true, SearchOptions{})
+	assert.Equal(t, eval.Cell{SheetIdx: s1.Idx, X: 1, Y: 3}, c, "backward")
+	c, _ = d.Find(re, eval.Cell{SheetIdx: s1.Idx, X: 1, Y: 3}, false, SearchOptions{AllSheets: true})
+	assert.Equal(t, eval.Cell{SheetIdx: s2.Idx, X: 1, Y: 1}, c, "continues on the next sheet")
+
+	// displayed values and formulas
+	re, _ = SearchPattern("^2$", true, false)
+	_, ok = d.Find(re, from, false, SearchOptions{})
+	assert.True(t, ok)
+	_, ok = d.Find(re, from, false, SearchOptions{Formulas: true})
+	assert.False(t, ok)
+	s1.SetCell(3, 2, sheet.NewCellUntyped("1234"))
+	s1.SetColFormat(3, "#,##0.00")
+	re, _ = SearchPattern("1,234", false, false)
+	c, ok = d.Find(re, from, false, SearchOptions{})
+	assert.True(t, ok, "formatted numbers match")
+	assert.Equal(t, eval.Cell{SheetIdx: s1.Idx, X: 3, Y: 2}, c)
+
+	re, _ = SearchPattern("APPLE", false, true)
+	n := d.Replace(re, func(v string) string { return re.ReplaceAllString(v, "pear") }, []*sheet.Sheet{s1}, sheet.Rect{})
+	assert.Equal(t, 2, n)
+	assert.Equal(t, "Pinepear", s1.Cell(1, 3).RawValue())
+	assert.Equal(t, "apple pie", s2.Cell(1, 1).RawValue())
+}
+
+func TestSortRows(t *testing.T) {
+	d := NewWithEmptySheet()
+	s := d.CurrentSheet
+	rows := [][]string{
+		{"name", "date", "n"},
+		{"b", "2020-03-01", "10"},
+		{"a", "2019-12-31", "9"},
+		{"c", "", "10"},
+		{"a", "2021-01-15", "100"},
+	}
+	for y, row := range rows {
+		for x, v := range row {
+			s.SetCell(x, y, sheet.NewCellUntyped(v))
+		}
+		// formula referencing the row follows it
+		s.SetCell(3, y, sheet.NewCellUntyped("=A"+strconv.Itoa(y+1)))
+	}
+	s.SetCell(4, 0, sheet.NewCellUntyped("=C2"))
+	s.SetCell(4, 1, sheet.NewCellUntyped("=SUM(C2:C5)"))
+	ec := eval.NewContext(d, s.Idx)
+	column := func(x int) []string {
+		var res []string
+		for y := 1; y < len(rows); y++ {
+			v, _ := d.StringValue(ec, eval.Cell{SheetIdx: s.Idx, X: x, Y: y})
+			res = append(res, v)
+		}
+		return res
+	}
+
+	newY, err := d.SortRows(s, sheet.Rect{X: 0, Y: 0, Width: 4, Height: 5}, []SortKey{{Col: 0}, {Col: 2, Descending: true}}, true)
+	assert.NoError(t, err)
+	assert.Equal(t, map[int]int{1: 3, 2: 2, 3: 4, 4: 1}, newY)
+	assert.Equal(t, "name", s.Cell(0, 0).RawValue())
+	assert.Equal(t, []string{"a", "a", "b", "c"}, column(0))
+	assert.Equal(t, []string{"100", "9", "10", "10"}, column(2))
+	assert.Equal(t, []string{"a", "a", "b", "c"}, column(3))
+	v, _ := d.StringValue(ec, eval.Cell{SheetIdx: s.Idx, X: 4, Y: 0})
+	assert.Equal(t, "10", v, "reference follows the cell")
+	v, _ = d.StringValue(ec, eval.Cell{SheetIdx: s.Idx, X: 4, Y: 1})
+	assert.Equal(t, "129", v, "range stays in place")
+
+	// dates, empty values are the last ones
+	_, err = d.SortRows(s, sheet.Rect{X: 0, Y: 1, Width: 4, Height: 4}, []SortKey{{Col: 1, Descending: true}}, false)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"2021-01-15", "2020-03-01", "2019-12-31", ""}, column(1))
+
+	_, err = d.SortRows(s, sheet.Rect{X: 0, Y: 1, Width: 2, Height: 4}, []SortKey{{Col: 2}}, false)
+	assert.Error(t, err)
+}
+
+func TestApplyFilter(t *testing.T) {
+	d := NewWithEmptySheet()
+	s := d.CurrentSheet
+	for y, row := range [][]string{{"fruit", "n"}, {"apple", "5"}, {"pear", "=A2"}, {"plum", "20"}, {"apple", "15"}} {
+		for x, v := range row {
+			s.SetCell(x, y, sheet.NewCellUntyped(v))
+		}
+	}
+	s.Filter = sheet.NewAutoFilter(sheet.Rect{X: 0, Y: 0, Width: 2, Height: 4})
+	s.Filter.Criteria[0] = &sheet.FilterCriteria{Values: []string{"apple", "PEAR"}}
+	s.Filter.Criteria[1] = &sheet.FilterCriteria{Conditions: []sheet.FilterCondition{sheet.ParseFilterCondition(">=5")}}
+	d.ApplyFilter(s)
+	hidden := func() []int {
+		var rows []int
+		for y := 0; y < 8; y++ {
+			if s.RowHidden(y) {
+				rows = append(rows, y)
+			}
+		}
+		return rows
+	}
+	assert.Equal(t, []int{2, 3}, hidden(), "the range grows to include the last row")
+	assert.Equal(t, 4, s.NextVisibleRow(1, 1))
+	assert.Equal(t, 1, s.NextVisibleRow(4, -1))
+	assert.Equal(t, []string{"apple", "pear", "plum"}, d.FilterValues(s, 0))
+
+	s.Cursor.Y = 1
+	d.InsertEmptyRow(0)
+	assert.Equal(t, []int{3, 4}, hidden(), "hidden rows move down")
+	assert.Equal(t, sheet.Rect{X: 0, Y: 0, Width: 2, Height: 6}, s.Filter.Range)
+	d.ApplyFilter(s)
+	assert.Equal(t, []int{1, 3, 4}, hidden(), "the empty row does not match")
+
+	s.Cursor.Y = 2
+	d.DeleteRow()
+	assert.Equal(t, []int{1, 2, 3}, hidden(), "hidden rows move up")
+
+	s.Cursor.X = 0
+	d.DeleteCol()
+	assert.Equal(t, sheet.Rect{X: 0, Y: 0, Width: 1, Height: 5}, s.Filter.Range)
+	assert.Len(t, s.Filter.Criteria, 1)
+	assert.Equal(t, ">=5", s.Filter.Criteria[0].Conditions[0].String(), "criteria move left")
+	d.ApplyFilter(s)
+	assert.Equal(t, []int{1, 2}, hidden(), "the moved formula refers to itself")
+}
+
+func TestApplyFilterFormatted(t *testing.T) {
+	d := NewWithEmptySheet()
+	s := d.CurrentSheet
+	for y, v := range []string{"n", "1", "2.5", "1000"} {
+		s.SetCell(0, y, sheet.NewCellUntyped(v))
+	}
+	s.SetColFormat(0, "#,##0.00")
+	s.Filter = sheet.NewAutoFilter(sheet.Rect{X: 0, Y: 0, Width: 1, Height: 4})
+	assert.Equal(t, []string{"1.00", "2.50", "1,000.00"}, d.FilterValues(s, 0))
+	s.Filter.Criteria[0] = &sheet.FilterCriteria{Values: []string{"1,000.00", "1.00"}}
+	d.ApplyFilter(s)
+	assert.Equal(t, 3, s.NextVisibleRow(1, 1), "listed values match the displayed text")
+	s.Filter.Criteria[0] = &sheet.FilterCriteria{Conditions: []sheet.FilterCondition{sheet.ParseFilterCondition(">2")}}
+	d.ApplyFilter(s)
+	assert.Equal(t, 2, s.NextVisibleRow(0, 1), "conditions compare numbers")
+	assert.Equal(t, 3, s.NextVisibleRow(2, 1))
+}
+
+func TestConditionalStyle(t *testing.T) {
+	d := NewWithEmptySheet()
+	s := d.CurrentSheet
+	for y, v := range []string{"5", "20", "7", "20", "1"} {
+		s.SetCell(0, y, sheet.NewCellUntyped(v))
+	}
+	s.SetCell(1, 0, sheet.NewCellUntyped("6"))
+	all := sheet.Rect{X: 0, Y: 0, Width: 1, Height: 5}
+	s.Rules = []*sheet.ConditionalRule{
+		{Range: all, Kind: sheet.RuleCompare, Condition: sheet.ParseFilterCondition(">6"), Style: sheet.Style{Bold: true}},
+		{Range: all, Kind: sheet.RuleBottom, N: 2, Style: sheet.Style{Fg: "#ff0000"}},
+		{Range: all, Kind: sheet.RuleDuplicate, Style: sheet.Style{Italic: true}},
+		{Range: all, Kind: sheet.RuleFormula, Formula: "=A1<$B$1", Style: sheet.Style{Bold: false, Underline: true}},
+		{Range: all, Kind: sheet.RuleColorScale, MinColor: "#ffffff", MaxColor: "#008000"},
+	}
+	assert.Equal(t, sheet.Style{Fg: "#ff0000", Underline: true, Bg: "#c9e4c9"}, d.ConditionalStyle(s, 0, 0))
+	assert.Equal(t, sheet.Style{Bold: true, Italic: true, Bg: "#008000"}, d.ConditionalStyle(s, 0, 1))
+	assert.Equal(t, sheet.Style{Bold: true, Bg: "#aed7ae"}, d.ConditionalStyle(s, 0, 2))
+	assert.Equal(t, sheet.Style{Fg: "#ff0000", Underline: true, Bg: "#ffffff"}, d.ConditionalStyle(s, 0, 4))
+	assert.Equal(t, sheet.Style{}, d.ConditionalStyle(s, 1, 0))
+
+	s.SetCell(0, 2, sheet.NewCellUntyped("40"))
+	d.ResetRuleStats()
+	assert.Equal(t, sheet.Style{Bold: true, Bg: "#008000"}, d.ConditionalStyle(s, 0, 2))
+	assert.Equal(t, sheet.Style{Bold: true, Italic: true, Bg: "#83c183"}, d.ConditionalStyle(s, 0, 1))
+
+	s.Cursor.Y = 0
+	d.InsertEmptyRow(0)
+	assert.Equal(t, "=A2<$B$2", s.Rules[3].Formula)
+	assert.Equal(t, sheet.Rect{X: 0, Y: 1, Width: 1, Height: 5}, s.Rules[3].Range)
+}
+
+func TestValidateCell(t *testing.T) {
+	d := NewWithEmptySheet()
+	s := d.CurrentSheet
+	for y, v := range []string{"red", "green", "blue"} {
+		s.SetCell(4, y, sheet.NewCellUntyped(v))
+	}
+	column := func(x int) sheet.Rect { return sheet.Rect{X: x, Y: 0, Width: 1, Height: 5} }
+	s.AddValidation(&sheet.Validation{Range: column(0), Kind: sheet.ValidateList, Source: "=$E$1:$E$3"})
+	s.AddValidation(&sheet.Validation{Range: column(1), Kind: sheet.ValidateWhole,
+		Conditions: []sheet.FilterCondition{sheet.ParseFilterCondition(">=1"), sheet.ParseFilterCondition("<=10")}})
+	s.AddValidation(&sheet.Validation{Range: column(2), Kind: sheet.ValidateDate,
+		Conditions: []sheet.FilterCondition{sheet.ParseFilterCondition(">=2024-01-01")}})
+	s.AddValidation(&sheet.Validation{Range: column(3), Kind: sheet.ValidateFormula, Formula: "=D1>B1", Message: "too small"})
+	testCases := []struct {
+		x, y  int
+		value string
+		err   string
+	}{
+		{0, 0, "Green", ""},
+		{0, 1, "pink", "value must be one of values of $E$1:$E$3"},
+		{1, 0, "10", ""},
+		{1, 1, "2.5", "value must be a whole number >=1 and <=10"},
+		{1, 2, "text", "value must be a whole number >=1 and <=10"},
+		{2, 0, "2024-03-01", ""},
+		{2, 1, "45000", "value must be a date >=2024-01-01"},
+		{3, 0, "11", ""},
+		{3, 1, "1", "too small"},
+		{3, 2, "", ""},
+	}
+	for _, tc := range testCases {
+		s.SetCell(tc.x, tc.y, sheet.NewCellUntyped(tc.value))
+	}
+	for _, tc := range testCases {
+		err := d.ValidateCell(s, tc.x, tc.y)
+		if tc.err == "" {
+			assert.NoError(t, err, tc.value)
+		} else {
+			assert.EqualError(t, err, tc.err, tc.value)
+		}
+	}
+
+	s.Cursor.Y = 0
+	d.InsertEmptyRow(0)
+	values, err := d.ValidationValues(s, s.Validation(0, 1))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"red", "green", "blue"}, values)
+	assert.Equal(t, "=D2>B2", s.Validation(3, 5).Formula)
+	assert.Nil(t, s.Validation(3, 0))
+}
+
+// benchSheetRows is a number of rows in the sheet used by benchmarks.
+const benchSheetRows = 1000
+
+// newBenchDocument makes a document with a column of numbers, a column of formulas referencing them
+// and a total at the bottom.
+func newBenchDocument() *Document {
+	d := NewWithEmptySheet()
+	cells := make([][]sheet.Cell, 2)
+	for x := range cells {
+		cells[x] = make([]sheet.Cell, benchSheetRows)
+	}
+	for y := 0; y < benchSheetRows; y++ {
+		row := strconv.Itoa(y + 1)
+		cells[0][y] = *sheet.NewCellUntyped(strconv.Itoa(y))
+		cells[1][y] = *sheet.NewCellUntyped("=A" + row + "*2+IF(A" + row + ">10; 1; 0)")
+	}
+	d.CurrentSheet.AddStaticSegment(0, 0, 2, benchSheetRows, cells)
+	d.CurrentSheet.SetCell(2, 0, sheet.NewCellUntyped("=SUM(B1:B"+strconv.Itoa(benchSheetRows)+")"))
+	return d
+}
+
+func BenchmarkLoadFormulaSheet(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		d := newBenchDocument()
+		ec := eval.NewContext(d, d.CurrentSheet.Idx)
+		// the first evaluation parses formulas
+		for y := 0; y < benchSheetRows; y++ {
+			if _, err := d.CurrentSheet.Cell(1, y).StringValue(ec); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkEvalFormulaSheet(b *testing.B) {
+	d := newBenchDocument()
+	ec := eval.NewContext(d, d.CurrentSheet.Idx)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := d.CurrentSheet.Cell(2, 0).StringValue(ec); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
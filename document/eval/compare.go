@@ -0,0 +1,70 @@
+package eval
+
+import "strings"
+
+// typeOrder defines how values of different types are ordered: numbers < text < booleans < empty.
+var typeOrder = map[int]int{
+	TypeDecimal: 0,
+	TypeString:  1,
+	TypeBool:    2,
+	TypeEmpty:   3,
+}
+
+// CompareValues compares two values the way spreadsheet sorting does.
+// Numbers are compared numerically, text case-insensitively, FALSE < TRUE,
+// and empty values are always the last ones.
+// Returns -1, 0 or 1 like strings.Compare does.
+func CompareValues(ec *Context, a, b Value) (int, error) {
+	ta, err := a.Type(ec)
+	if err != nil {
+		return 0, err
+	}
+	tb, err := b.Type(ec)
+	if err != nil {
+		return 0, err
+	}
+	if ta != tb {
+		if typeOrder[ta] < typeOrder[tb] {
+			return -1, nil
+		}
+		return 1, nil
+	}
+	switch ta {
+	case TypeDecimal:
+		da, err := a.DecimalValue(ec)
+		if err != nil {
+			return 0, err
+		}
+		db, err := b.DecimalValue(ec)
+		if err != nil {
+			return 0, err
+		}
+		return da.Cmp(db), nil
+	case TypeString:
+		sa, err := a.StringValue(ec)
+		if err != nil {
+			return 0, err
+		}
+		sb, err := b.StringValue(ec)
+		if err != nil {
+			return 0, err
+		}
+		return strings.Compare(strings.ToLower(sa), strings.ToLower(sb)), nil
+	case TypeBool:
+		ba, err := a.BoolValue(ec)
+		if err != nil {
+			return 0, err
+		}
+		bb, err := b.BoolValue(ec)
+		if err != nil {
+			return 0, err
+		}
+		if ba == bb {
+			return 0, nil
+		} else if bb {
+			return -1, nil
+		}
+		return 1, nil
+	}
+	return 0, nil
+}
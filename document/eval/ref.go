@@ -15,6 +15,8 @@ type CellRef struct {
 
 	Cell       Cell
 	UsageCount int // TODO: garbage collecting
+	// RangeEnd marks bounds of ranges, they stay in place when rows are sorted.
+	RangeEnd bool
 }
 
 func NewCellRef(cell Cell) *CellRef {
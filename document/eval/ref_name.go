@@ -0,0 +1,54 @@
+package eval
+
+import "github.com/shopspring/decimal"
+
+// NameRef is a reference to a document-level name (named range or named constant).
+// The name is resolved on every evaluation, so redefining it affects all formulas using it.
+type NameRef struct {
+	Value
+
+	Name string
+}
+
+func NewNameRef(name string) *NameRef {
+	return &NameRef{
+		Name: name,
+	}
+}
+
+// Resolve returns the value the name currently refers to.
+func (r *NameRef) Resolve(ec *Context) (Value, error) {
+	return ec.DataProvider.NameValue(ec, r.Name)
+}
+
+func (r *NameRef) Type(ec *Context) (int, error) {
+	v, err := r.Resolve(ec)
+	if err != nil {
+		return 0, err
+	}
+	return v.Type(ec)
+}
+
+func (r *NameRef) BoolValue(ec *Context) (bool, error) {
+	v, err := r.Resolve(ec)
+	if err != nil {
+		return false, err
+	}
+	return v.BoolValue(ec)
+}
+
+func (r *NameRef) DecimalValue(ec *Context) (decimal.Decimal, error) {
+	v, err := r.Resolve(ec)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	return v.DecimalValue(ec)
+}
+
+func (r *NameRef) StringValue(ec *Context) (string, error) {
+	v, err := r.Resolve(ec)
+	if err != nil {
+		return "", err
+	}
+	return v.StringValue(ec)
+}
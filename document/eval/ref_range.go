@@ -47,6 +47,31 @@ func (r *RangeRef) iterate(ec *Context, f func(Cell) error) error {
 	return nil
 }
 
+// Array evaluates all cells of the range into array.
+func (r *RangeRef) Array(ec *Context) (*ArrayValue, error) {
+	x1, y1, x2, y2 := r.CellFromRef.Cell.X, r.CellFromRef.Cell.Y, r.CellToRef.Cell.X, r.CellToRef.Cell.Y
+	if x1 > x2 || y1 > y2 {
+		return nil, NewError(ErrorKindRef, "invalid range")
+	}
+	a := NewArrayValueSized(x2-x1+1, y2-y1+1)
+	err := r.iterate(ec, func(cell Cell) error {
+		v, err := ec.DataProvider.Value(ec, cell)
+		if err != nil {
+			return err
+		}
+		v, err = NewStaticValue(ec, v)
+		if err != nil {
+			return err
+		}
+		a.Set(cell.X-x1, cell.Y-y1, v)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
 func (r *RangeRef) IterateBoolValues(ec *Context, f func(bool) error) error {
 	return r.iterate(ec, func(cell Cell) error {
 		v, err := ec.DataProvider.BoolValue(ec, cell)
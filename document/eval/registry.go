@@ -13,4 +13,5 @@ type RefRegistryInterface interface {
 	BoolValue(ec *Context, cell Cell) (bool, error)
 	DecimalValue(ec *Context, cell Cell) (decimal.Decimal, error)
 	StringValue(ec *Context, cell Cell) (string, error)
+	NameValue(ec *Context, name string) (Value, error)
 }
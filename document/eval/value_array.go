@@ -0,0 +1,103 @@
+package eval
+
+import "github.com/shopspring/decimal"
+
+// ArrayValue is a two-dimensional array of values, e.g. an array constant {1;2;3} or a result of SEQUENCE().
+// Being casted to a single value, it gives its top left element.
+type ArrayValue struct {
+	Value
+
+	// values stored row by row
+	rows [][]Value
+}
+
+// NewArrayValue creates array from rows. All rows must have the same length.
+func NewArrayValue(rows [][]Value) *ArrayValue {
+	return &ArrayValue{
+		rows: rows,
+	}
+}
+
+// NewArrayValueSized creates array of given size filled with empty values.
+func NewArrayValueSized(width, height int) *ArrayValue {
+	rows := make([][]Value, height)
+	for y := range rows {
+		rows[y] = make([]Value, width)
+		for x := range rows[y] {
+			rows[y][x] = NewEmptyValue()
+		}
+	}
+	return NewArrayValue(rows)
+}
+
+// Width returns number of columns.
+func (a *ArrayValue) Width() int {
+	if len(a.rows) == 0 {
+		return 0
+	}
+	return len(a.rows[0])
+}
+
+// Height returns number of rows.
+func (a *ArrayValue) Height() int {
+	return len(a.rows)
+}
+
+// At returns element in column X and row Y.
+func (a *ArrayValue) At(x, y int) Value {
+	return a.rows[y][x]
+}
+
+// Set replaces element in column X and row Y.
+func (a *ArrayValue) Set(x, y int, v Value) {
+	a.rows[y][x] = v
+}
+
+// Row returns all elements of the row Y.
+func (a *ArrayValue) Row(y int) []Value {
+	return a.rows[y]
+}
+
+// first returns top left element.
+func (a *ArrayValue) first() Value {
+	if a.Width() == 0 {
+		return NewEmptyValue()
+	}
+	return a.rows[0][0]
+}
+
+func (a *ArrayValue) Type(ec *Context) (int, error) {
+	return a.first().Type(ec)
+}
+
+func (a *ArrayValue) BoolValue(ec *Context) (bool, error) {
+	return a.first().BoolValue(ec)
+}
+
+func (a *ArrayValue) DecimalValue(ec *Context) (decimal.Decimal, error) {
+	return a.first().DecimalValue(ec)
+}
+
+func (a *ArrayValue) StringValue(ec *Context) (string, error) {
+	return a.first().StringValue(ec)
+}
+
+// NewStaticValue evaluates any value (e.g. reference) into a constant one of the same type.
+func NewStaticValue(ec *Context, v Value) (Value, error) {
+	t, err := v.Type(ec)
+	if err != nil {
+		return NewEmptyValue(), err
+	}
+	switch t {
+	case TypeBool:
+		b, err := v.BoolValue(ec)
+		return NewBoolValue(b), err
+	case TypeDecimal:
+		d, err := v.DecimalValue(ec)
+		return NewDecimalValue(d), err
+	case TypeString:
+		s, err := v.StringValue(ec)
+		return NewStringValue(s), err
+	}
+	return NewEmptyValue(), nil
+}
@@ -0,0 +1,62 @@
+package document
+
+import (
+	"xl/document/eval"
+	"xl/document/sheet"
+)
+
+// ApplyFilter hides rows of the sheet not matching its autofilter criteria. The filter range
+// grows to include rows added below it.
+func (d *Document) ApplyFilter(s *sheet.Sheet) {
+	f := s.Filter
+	if f == nil {
+		s.SetFilteredRows(nil)
+		return
+	}
+	if used := s.UsedRect(); used.Width > 0 && used.MaxY() > f.Range.MaxY() {
+		f.Range.Height = used.MaxY() - f.Range.Y + 1
+	}
+	hidden := make(map[int]bool)
+	ec := eval.NewContext(d, s.Idx)
+	for y := f.Range.Y + 1; y <= f.Range.MaxY(); y++ {
+		for x, c := range f.Criteria {
+			cell := eval.Cell{SheetIdx: s.Idx, X: x, Y: y}
+			value, err := d.StringValue(ec, cell)
+			if err != nil {
+				value = err.Error()
+			}
+			if !c.Matches(value, d.displayValue(ec, cell)) {
+				hidden[y] = true
+				break
+			}
+		}
+	}
+	s.SetFilteredRows(hidden)
+}
+
+// FilterValues returns distinct displayed values of the filter column in order of appearance.
+func (d *Document) FilterValues(s *sheet.Sheet, x int) []string {
+	if s.Filter == nil {
+		return nil
+	}
+	var values []string
+	seen := make(map[string]bool)
+	ec := eval.NewContext(d, s.Idx)
+	for y := s.Filter.Range.Y + 1; y <= s.Filter.Range.MaxY(); y++ {
+		v := d.displayValue(ec, eval.Cell{SheetIdx: s.Idx, X: x, Y: y})
+		if !seen[v] {
+			seen[v] = true
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// displayValue returns the cell value formatted as it is displayed, including error messages.
+func (d *Document) displayValue(ec *eval.Context, cell eval.Cell) string {
+	v, _, err := d.FormattedValue(ec, cell)
+	if err != nil {
+		return err.Error()
+	}
+	return v
+}
@@ -0,0 +1,45 @@
+package document
+
+import (
+	"xl/document/eval"
+	"xl/document/sheet"
+)
+
+// FormattedValue returns the cell value as it is displayed and the type of the value: numbers and text are formatted
+// with the number format of the cell or of its column.
+func (d *Document) FormattedValue(ec *eval.Context, cell eval.Cell) (string, int, error) {
+	c, v, err := d.cellValue(ec, cell)
+	if err != nil {
+		return "", eval.TypeEmpty, err
+	}
+	if c != nil {
+		if v, err = c.Value(ec); err != nil {
+			return "", eval.TypeEmpty, err
+		}
+	}
+	t, err := v.Type(ec)
+	if err != nil {
+		return "", eval.TypeEmpty, err
+	}
+	code := d.sheetByIdx(cell.SheetIdx).CellFormat(cell.X, cell.Y)
+	switch {
+	case code == "" && c != nil:
+		// plain values are shown as they were entered
+		s, err := c.StringValue(ec)
+		return s, t, err
+	case code != "" && t == eval.TypeDecimal:
+		dv, err := v.DecimalValue(ec)
+		if err != nil {
+			return "", t, err
+		}
+		return sheet.FormatNumber(dv, code), t, nil
+	}
+	s, err := v.StringValue(ec)
+	if err != nil {
+		return "", t, err
+	}
+	if code != "" && t == eval.TypeString {
+		s = sheet.FormatText(s, code)
+	}
+	return s, t, nil
+}
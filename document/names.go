@@ -0,0 +1,159 @@
+package document
+
+import (
+	"xl/document/eval"
+	"xl/document/sheet"
+	"xl/formula"
+
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// definedName is a document-level name referring to a cell, a range or a constant.
+type definedName struct {
+	// name as it was entered
+	name string
+	// *eval.CellRef, *eval.RangeRef or a constant value
+	value eval.Value
+}
+
+var referencePattern = regexp.MustCompile(`^(?:([A-Za-z0-9_]+|'(?:[^']|'')*')!)?(\$?[A-Za-z]+\$?[0-9]+)(?::(\$?[A-Za-z]+\$?[0-9]+))?$`)
+
+// SetName defines a new name or redefines the existing one.
+// Definition is either a cell (B2), a range (A2:A40), both optionally prefixed with sheet title, or a constant.
+func (d *Document) SetName(name, definition string) error {
+	if !formula.IsName(name) {
+		return eval.NewError(eval.ErrorKindName, "invalid name %s", name)
+	}
+	v, err := d.parseNameDefinition(strings.TrimPrefix(definition, "="))
+	if err != nil {
+		return err
+	}
+	_ = d.DeleteName(name)
+	d.names[strings.ToUpper(name)] = &definedName{
+		name:  name,
+		value: v,
+	}
+	return nil
+}
+
+// DeleteName removes the name from the document.
+func (d *Document) DeleteName(name string) error {
+	key := strings.ToUpper(name)
+	dn, ok := d.names[key]
+	if !ok {
+		return eval.NewError(eval.ErrorKindName, "name %s is not defined", name)
+	}
+	switch r := dn.value.(type) {
+	case *eval.CellRef:
+		r.UsageCount--
+	case *eval.RangeRef:
+		r.CellFromRef.UsageCount--
+		r.CellToRef.UsageCount--
+	}
+	delete(d.names, key)
+	return nil
+}
+
+// Names returns all names defined in the document, sorted alphabetically.
+func (d *Document) Names() []string {
+	names := make([]string, 0, len(d.names))
+	for _, dn := range d.names {
+		names = append(names, dn.name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NameDefinition returns the definition of the name in the form it can be passed to SetName.
+// References are always absolute and prefixed with sheet title.
+func (d *Document) NameDefinition(name string) (string, error) {
+	dn, ok := d.names[strings.ToUpper(name)]
+	if !ok {
+		return "", eval.NewError(eval.ErrorKindName, "name %s is not defined", name)
+	}
+	switch r := dn.value.(type) {
+	case *eval.CellRef:
+		return d.absoluteCellName(r.Cell)
+	case *eval.RangeRef:
+		from, err := d.absoluteCellName(r.CellFromRef.Cell)
+		if err != nil {
+			return "", err
+		}
+		to := "$" + ColName(r.CellToRef.Cell.X) + "$" + RowName(r.CellToRef.Cell.Y)
+		return from + ":" + to, nil
+	}
+	ec := eval.NewContext(d, 0)
+	t, _ := dn.value.Type(ec)
+	s, err := dn.value.StringValue(ec)
+	if err != nil {
+		return "", err
+	}
+	if t == eval.TypeString {
+		return `"` + strings.Replace(s, `"`, `""`, -1) + `"`, nil
+	}
+	return s, nil
+}
+
+func (d *Document) NameValue(ec *eval.Context, name string) (eval.Value, error) {
+	dn, ok := d.names[strings.ToUpper(name)]
+	if !ok {
+		return eval.NewEmptyValue(), eval.NewError(eval.ErrorKindName, "name %s is not defined", name)
+	}
+	return dn.value, nil
+}
+
+// parseNameDefinition makes a reference or a constant value from name definition.
+func (d *Document) parseNameDefinition(definition string) (eval.Value, error) {
+	if definition == "" {
+		return nil, eval.NewError(eval.ErrorKindName, "name definition is empty")
+	}
+	if res := referencePattern.FindStringSubmatch(definition); res != nil {
+		sheetTitle := unquoteSheetTitle(res[1])
+		if sheetTitle == "" && d.CurrentSheet == nil {
+			return nil, eval.NewError(eval.ErrorKindName, "sheet is not specified")
+		}
+		if res[3] != "" {
+			return d.NewRangeRef(sheetTitle, strings.ToUpper(res[2]), strings.ToUpper(res[3]))
+		}
+		return d.NewCellRef(sheetTitle, strings.ToUpper(res[2]))
+	}
+	if definition[0] == '=' {
+		return nil, eval.NewError(eval.ErrorKindName, "formulas are not supported in name definitions")
+	}
+	// string constants are quoted in XLSX
+	l := len(definition)
+	if l >= 2 && definition[0] == '"' && definition[l-1] == '"' {
+		return eval.NewStringValue(strings.Replace(definition[1:l-1], `""`, `"`, -1)), nil
+	}
+	return sheet.NewCellUntyped(definition).Value(eval.NewContext(d, 0))
+}
+
+// absoluteCellName returns cell name with sheet title and with both column and row fixed.
+func (d *Document) absoluteCellName(cell eval.Cell) (string, error) {
+	title, err := d.SheetTitle(cell.SheetIdx)
+	if err != nil {
+		return "", err
+	}
+	return QuoteSheetTitle(title) + "!$" + ColName(cell.X) + "$" + RowName(cell.Y), nil
+}
+
+// QuoteSheetTitle wraps sheet title into single quotes if it is required to use it in references.
+func QuoteSheetTitle(title string) string {
+	for _, r := range title {
+		if !(r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '_') {
+			return "'" + strings.Replace(title, "'", "''", -1) + "'"
+		}
+	}
+	return title
+}
+
+// unquoteSheetTitle is the reverse for QuoteSheetTitle.
+func unquoteSheetTitle(title string) string {
+	l := len(title)
+	if l >= 2 && title[0] == '\'' && title[l-1] == '\'' {
+		return strings.Replace(title[1:l-1], "''", "'", -1)
+	}
+	return title
+}
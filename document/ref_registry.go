@@ -8,6 +8,15 @@ import (
 )
 
 func (d *Document) NewCellRef(sheetTitle, cellName string) (*eval.CellRef, error) {
+	cell, err := d.resolveCell(sheetTitle, cellName)
+	if err != nil {
+		return nil, err
+	}
+	return d.cellRef(cell, false), nil
+}
+
+// resolveCell finds the cell by its name on the sheet with given title, or on the current sheet if title is empty.
+func (d *Document) resolveCell(sheetTitle, cellName string) (eval.Cell, error) {
 	var s *sheet.Sheet
 	if sheetTitle != "" {
 		for i := range d.Sheets {
@@ -18,40 +27,47 @@ func (d *Document) NewCellRef(sheetTitle, cellName string) (*eval.CellRef, error
 		}
 		// sheet not found
 		if s == nil {
-			return nil, eval.NewError(eval.ErrorKindName, "sheet does not exist")
+			return eval.Cell{}, eval.NewError(eval.ErrorKindName, "sheet does not exist")
 		}
 	} else {
 		s = d.CurrentSheet
 	}
 	x, y, err := CellAxis(cellName)
 	if err != nil {
-		return nil, err
+		return eval.Cell{}, err
 	}
+	return eval.Cell{SheetIdx: s.Idx, X: x, Y: y}, nil
+}
+
+// cellRef returns registered reference to the cell, registers a new one if necessary.
+// Bounds of ranges are registered separately from references to single cells.
+func (d *Document) cellRef(cell eval.Cell, rangeEnd bool) *eval.CellRef {
 	// existing link?
 	for _, r := range d.refRegistry {
-		if r.Cell.SheetIdx == s.Idx && r.Cell.X == x && r.Cell.Y == y {
+		if r.Cell == cell && r.RangeEnd == rangeEnd {
 			r.UsageCount++
-			return r, nil
+			return r
 		}
 	}
 	// not found? create new one
-	r := eval.NewCellRef(eval.Cell{SheetIdx: s.Idx, X: x, Y: y})
+	r := eval.NewCellRef(cell)
+	r.RangeEnd = rangeEnd
 	d.refRegistry = append(d.refRegistry, r)
-	return r, nil
+	return r
 }
 
 func (d *Document) NewRangeRef(sheetTitle, cellFromName, cellToName string) (*eval.RangeRef, error) {
-	fromRef, err := d.NewCellRef(sheetTitle, cellFromName)
+	from, err := d.resolveCell(sheetTitle, cellFromName)
 	if err != nil {
 		return nil, err
 	}
-	toRef, err := d.NewCellRef(sheetTitle, cellToName)
+	to, err := d.resolveCell(sheetTitle, cellToName)
 	if err != nil {
 		return nil, err
 	}
 	rr := &eval.RangeRef{
-		CellFromRef: fromRef,
-		CellToRef:   toRef,
+		CellFromRef: d.cellRef(from, true),
+		CellToRef:   d.cellRef(to, true),
 	}
 	return rr, nil
 }
@@ -69,51 +85,76 @@ func (d *Document) CellName(cell eval.Cell) (string, error) {
 }
 
 func (d *Document) Value(ec *eval.Context, cell eval.Cell) (eval.Value, error) {
-	s := d.sheetByIdx(cell.SheetIdx)
-	if s == nil {
-		return eval.NewEmptyValue(), eval.NewError(eval.ErrorKindName, "sheet does not exist")
+	c, v, err := d.cellValue(ec, cell)
+	if err != nil {
+		return eval.NewEmptyValue(), err
 	}
-	c := s.Cell(cell.X, cell.Y)
-	if c == nil {
-		return eval.NewEmptyValue(), nil
+	if c != nil {
+		return c.Value(ec)
 	}
-	return c.Value(ec)
+	return v, nil
 }
 
 func (d *Document) BoolValue(ec *eval.Context, cell eval.Cell) (bool, error) {
-	s := d.sheetByIdx(cell.SheetIdx)
-	if s == nil {
-		return false, eval.NewError(eval.ErrorKindName, "sheet does not exist")
+	c, v, err := d.cellValue(ec, cell)
+	if err != nil {
+		return false, err
 	}
-	c := s.Cell(cell.X, cell.Y)
-	if c == nil {
-		return false, nil
+	if c != nil {
+		return c.BoolValue(ec)
 	}
-	return c.BoolValue(ec)
+	return v.BoolValue(ec)
 }
 
 func (d *Document) DecimalValue(ec *eval.Context, cell eval.Cell) (decimal.Decimal, error) {
-	s := d.sheetByIdx(cell.SheetIdx)
-	if s == nil {
-		return decimal.Zero, eval.NewError(eval.ErrorKindName, "sheet does not exist")
+	c, v, err := d.cellValue(ec, cell)
+	if err != nil {
+		return decimal.Zero, err
 	}
-	c := s.Cell(cell.X, cell.Y)
-	if c == nil {
-		return decimal.Zero, nil
+	if c != nil {
+		return c.DecimalValue(ec)
 	}
-	return c.DecimalValue(ec)
+	return v.DecimalValue(ec)
 }
 
 func (d *Document) StringValue(ec *eval.Context, cell eval.Cell) (string, error) {
+	c, v, err := d.cellValue(ec, cell)
+	if err != nil {
+		return "", err
+	}
+	if c != nil {
+		return c.StringValue(ec)
+	}
+	return v.StringValue(ec)
+}
+
+// cellValue returns either the cell itself if it contains a plain value,
+// or the evaluated value for formulas, empty and spilled cells.
+func (d *Document) cellValue(ec *eval.Context, cell eval.Cell) (*sheet.Cell, eval.Value, error) {
 	s := d.sheetByIdx(cell.SheetIdx)
 	if s == nil {
-		return "", eval.NewError(eval.ErrorKindName, "sheet does not exist")
+		return nil, nil, eval.NewError(eval.ErrorKindName, "sheet does not exist")
 	}
 	c := s.Cell(cell.X, cell.Y)
-	if c == nil {
-		return "", nil
+	if c == nil || c.RawValue() == "" {
+		v, err := d.spilledValue(ec, cell)
+		return nil, v, err
+	}
+	if !c.IsFormula(ec) {
+		return c, nil, nil
+	}
+	v, err := c.Value(ec)
+	if err != nil {
+		return nil, nil, err
+	}
+	a, err := arrayResult(ec, v)
+	if err != nil {
+		return nil, nil, err
+	}
+	if a != nil {
+		v, err = d.spill(s, cell, a)
 	}
-	return c.StringValue(ec)
+	return nil, v, err
 }
 
 func (d *Document) moveRefsRight(n int) {
@@ -0,0 +1,129 @@
+package document
+
+import (
+	"xl/document/eval"
+	"xl/document/sheet"
+
+	"regexp"
+)
+
+// SearchOptions defines how cells are matched by Find and Matches.
+type SearchOptions struct {
+	// Formulas makes search look at raw values (formulas source) instead of displayed ones.
+	Formulas bool
+	// AllSheets makes search continue on other sheets.
+	AllSheets bool
+}
+
+// SearchPattern compiles the search pattern. Unless regex is set the pattern matches literally.
+func SearchPattern(pattern string, regex, ignoreCase bool) (*regexp.Regexp, error) {
+	if !regex {
+		pattern = regexp.QuoteMeta(pattern)
+	}
+	if ignoreCase {
+		pattern = "(?i)" + pattern
+	}
+	return regexp.Compile(pattern)
+}
+
+// Matches checks if the cell value, formatted as it is displayed, matches the pattern.
+func (d *Document) Matches(re *regexp.Regexp, cell eval.Cell, opts SearchOptions) bool {
+	s := d.sheetByIdx(cell.SheetIdx)
+	if s == nil {
+		return false
+	}
+	c := s.Cell(cell.X, cell.Y)
+	if c == nil || c.RawValue() == "" {
+		return false
+	}
+	if opts.Formulas {
+		return re.MatchString(c.RawValue())
+	}
+	v, _, err := d.FormattedValue(eval.NewContext(d, cell.SheetIdx), cell)
+	return err == nil && re.MatchString(v)
+}
+
+// Find returns the next cell after the given one matching the pattern, rows are searched from left to right
+// and from top to bottom, or in reverse order if backward is set. Search wraps around the sheet
+// (or the document if AllSheets option is set).
+func (d *Document) Find(re *regexp.Regexp, from eval.Cell, backward bool, opts SearchOptions) (eval.Cell, bool) {
+	dir := 1
+	if backward {
+		dir = -1
+	}
+	start := 0
+	for i, s := range d.Sheets {
+		if s.Idx == from.SheetIdx {
+			start = i
+		}
+	}
+	if c, ok := d.findInSheet(re, d.Sheets[start], &from, dir, opts); ok {
+		return c, true
+	}
+	if opts.AllSheets {
+		for i := 1; i < len(d.Sheets); i++ {
+			s := d.Sheets[(start+i*dir+len(d.Sheets))%len(d.Sheets)]
+			if c, ok := d.findInSheet(re, s, nil, dir, opts); ok {
+				return c, true
+			}
+		}
+	}
+	// wrap around
+	return d.findInSheet(re, d.Sheets[start], nil, dir, opts)
+}
+
+// findInSheet finds the nearest matching cell after the given one, or the first one if after is nil.
+func (d *Document) findInSheet(re *regexp.Regexp, s *sheet.Sheet, after *eval.Cell, dir int, opts SearchOptions) (eval.Cell, bool) {
+	var res eval.Cell
+	found := false
+	// before checks if cell a goes before cell b in the search order
+	before := func(a, b eval.Cell) bool {
+		if a.Y != b.Y {
+			return (a.Y-b.Y)*dir < 0
+		}
+		return (a.X-b.X)*dir < 0
+	}
+	for _, segment := range s.Segments {
+		size := segment.Size()
+		for x := size.X; x <= size.MaxX(); x++ {
+			for y := size.Y; y <= size.MaxY(); y++ {
+				c := eval.Cell{SheetIdx: s.Idx, X: x, Y: y}
+				if after != nil && !before(*after, c) || found && !before(c, res) {
+					continue
+				}
+				if d.Matches(re, c, opts) {
+					res, found = c, true
+				}
+			}
+		}
+	}
+	return res, found
+}
+
+// Replace changes raw values of the cells within the area of given sheets (all cells if the area is empty)
+// with the result of replace function for cells matching the pattern. Returns the number of changed cells.
+func (d *Document) Replace(re *regexp.Regexp, replace func(string) string, sheets []*sheet.Sheet, area sheet.Rect) int {
+	n := 0
+	for _, s := range sheets {
+		for _, segment := range s.Segments {
+			size := segment.Size()
+			for x := size.X; x <= size.MaxX(); x++ {
+				for y := size.Y; y <= size.MaxY(); y++ {
+					if area.Width > 0 && !area.Contains(x, y) {
+						continue
+					}
+					c := segment.Cell(x, y)
+					if c.RawValue() == "" || !re.MatchString(c.RawValue()) {
+						continue
+					}
+					if v := replace(c.RawValue()); v != c.RawValue() {
+						c.SetValueUntyped(v)
+						d.UpdateSpill(eval.Cell{SheetIdx: s.Idx, X: x, Y: y})
+						n++
+					}
+				}
+			}
+		}
+	}
+	return n
+}
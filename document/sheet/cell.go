@@ -32,6 +32,11 @@ type Cell struct {
 	// formula params
 	expression *formula.Expression
 	refs       []eval.Value
+
+	// Excel number format code, empty for General
+	format string
+	// nil for the default style
+	style *Style
 }
 
 func NewCellEmpty() *Cell {
@@ -72,6 +77,26 @@ func (c *Cell) RawValue() string {
 	return c.rawValue
 }
 
+// Format returns the number format code of the cell, empty if the cell has no own format.
+func (c *Cell) Format() string {
+	return c.format
+}
+
+// SetFormat sets the number format code like "#,##0.00", it is kept when the value changes.
+func (c *Cell) SetFormat(code string) {
+	c.format = code
+}
+
+// IsFormula checks if the cell value is a formula.
+func (c *Cell) IsFormula(ec *eval.Context) bool {
+	if c.valueType == CellValueUntyped {
+		if err := c.evaluateType(ec); err != nil {
+			return false
+		}
+	}
+	return c.valueType == CellValueTypeFormula
+}
+
 func (c *Cell) Expression(ec *eval.Context) *formula.Expression {
 	if c.valueType == CellValueUntyped {
 		if err := c.evaluateType(ec); err != nil {
@@ -243,6 +268,10 @@ func guessCellType(v string) (int, interface{}) {
 func makeRefs(vars []*formula.Variable, ec *eval.Context) ([]eval.Value, error) {
 	values := make([]eval.Value, len(vars))
 	for i := range vars {
+		if vars[i].Name != nil {
+			values[i] = eval.NewNameRef(string(*vars[i].Name))
+			continue
+		}
 		c := vars[i].Cell
 		var s string
 		if c.Sheet != nil {
@@ -286,6 +315,8 @@ func updateVars(ec *eval.Context, x *formula.Expression, refs []eval.Value) erro
 			v.Cell.Cell = cellName
 		case *eval.RangeRef:
 			// TODO
+		case *eval.NameRef:
+			// names do not depend on position
 		default:
 			panic("unexpected value type")
 		}
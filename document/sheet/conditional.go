@@ -0,0 +1,47 @@
+package sheet
+
+const (
+	// RuleCompare matches values meeting the condition like ">10".
+	RuleCompare = iota
+	// RuleTop and RuleBottom match N greatest or least numbers of the range.
+	RuleTop
+	RuleBottom
+	// RuleDuplicate matches values occurring in the range more than once.
+	RuleDuplicate
+	// RuleColorScale colors the background from MinColor for the least number of the range to MaxColor
+	// for the greatest one.
+	RuleColorScale
+	// RuleFormula matches cells for which the formula is true.
+	RuleFormula
+)
+
+// ConditionalRule styles cells of the range depending on their values.
+type ConditionalRule struct {
+	Range Rect
+	Kind  int
+	// Condition of RuleCompare.
+	Condition FilterCondition
+	// N of RuleTop and RuleBottom.
+	N int
+	// Formula of RuleFormula is written for the top left cell of the range, its relative references
+	// move with the cell like in copied formulas.
+	Formula string
+	// Style is applied to matching cells.
+	Style Style
+	// Colors of RuleColorScale.
+	MinColor string
+	MaxColor string
+}
+
+// shiftRules moves ranges of conditional formatting rules when a row (a column if rows is not set)
+// is inserted or deleted.
+func (s *Sheet) shiftRules(rows bool, n, delta int) {
+	rules := s.Rules[:0]
+	for _, r := range s.Rules {
+		shiftRect(&r.Range, rows, n, delta)
+		if r.Range.Width > 0 && r.Range.Height > 0 {
+			rules = append(rules, r)
+		}
+	}
+	s.Rules = rules
+}
@@ -0,0 +1,162 @@
+package sheet
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// AutoFilter hides rows of the range not matching criteria of its columns.
+// The first row of the range is a header and is never hidden.
+type AutoFilter struct {
+	Range Rect
+	// Criteria by column X.
+	Criteria map[int]*FilterCriteria
+}
+
+// FilterCriteria defines values shown in a column: one of listed values if there are any,
+// otherwise values meeting all the conditions.
+type FilterCriteria struct {
+	Values     []string
+	Conditions []FilterCondition
+}
+
+// FilterCondition compares a value with the operand using one of =, <>, <, <=, >, >= operators.
+// Text operands of = and <> may contain * and ? wildcards.
+type FilterCondition struct {
+	Op      string
+	Operand string
+}
+
+var filterOps = []string{"<>", "<=", ">=", "=", "<", ">"}
+
+// NewAutoFilter creates a filter for the range with no criteria.
+func NewAutoFilter(r Rect) *AutoFilter {
+	return &AutoFilter{
+		Range:    r,
+		Criteria: make(map[int]*FilterCriteria),
+	}
+}
+
+// ParseFilterCondition parses conditions like ">10", "<>done" or "=a*". Text without operator means equality.
+func ParseFilterCondition(s string) FilterCondition {
+	for _, op := range filterOps {
+		if strings.HasPrefix(s, op) {
+			return FilterCondition{op, s[len(op):]}
+		}
+	}
+	return FilterCondition{"=", s}
+}
+
+// IsFilterCondition checks if the text starts with a comparison operator.
+func IsFilterCondition(s string) bool {
+	return s != "" && strings.ContainsAny(s[:1], "<>=")
+}
+
+func (c FilterCondition) String() string {
+	return c.Op + c.Operand
+}
+
+// Matches checks if the value meets the criteria: listed values are compared with the text displayed
+// for the value, conditions are checked for the value itself.
+func (f *FilterCriteria) Matches(value, text string) bool {
+	if len(f.Values) > 0 {
+		for _, v := range f.Values {
+			if strings.EqualFold(v, text) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, c := range f.Conditions {
+		if !c.Matches(value) {
+			return false
+		}
+	}
+	return true
+}
+
+// Matches checks if the displayed value meets the condition. Numbers are compared numerically,
+// texts are compared case-insensitively, numbers and texts are never equal.
+func (c FilterCondition) Matches(value string) bool {
+	vn, errV := decimal.NewFromString(value)
+	on, errO := decimal.NewFromString(c.Operand)
+	var cmp int
+	switch {
+	case errV == nil && errO == nil:
+		cmp = vn.Cmp(on)
+	case (errV == nil) != (errO == nil):
+		return c.Op == "<>"
+	case c.Op == "=" || c.Op == "<>":
+		return wildcardMatch(c.Operand, value) == (c.Op == "=")
+	default:
+		cmp = strings.Compare(strings.ToLower(value), strings.ToLower(c.Operand))
+	}
+	switch c.Op {
+	case "=":
+		return cmp == 0
+	case "<>":
+		return cmp != 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	}
+	return false
+}
+
+// wildcardMatch matches the whole text against the pattern with * and ? wildcards ignoring case.
+func wildcardMatch(pattern, text string) bool {
+	p := regexp.QuoteMeta(pattern)
+	p = strings.ReplaceAll(p, `\*`, ".*")
+	p = strings.ReplaceAll(p, `\?`, ".")
+	re, err := regexp.Compile("(?is)^" + p + "$")
+	if err != nil {
+		return false
+	}
+	return re.MatchString(text)
+}
+
+// shiftFilter moves the autofilter range, its criteria and filtered rows when a row (a column if rows is not set)
+// is inserted or deleted.
+func (s *Sheet) shiftFilter(rows bool, n, delta int) {
+	f := s.Filter
+	if f == nil {
+		return
+	}
+	if rows {
+		filtered := make(map[int]bool, len(s.filteredRows))
+		for y := range s.filteredRows {
+			if y == n && delta < 0 {
+				continue
+			}
+			if y >= n {
+				y += delta
+			}
+			filtered[y] = true
+		}
+		s.filteredRows = filtered
+	} else {
+		criteria := make(map[int]*FilterCriteria, len(f.Criteria))
+		for x, c := range f.Criteria {
+			if x == n && delta < 0 {
+				continue
+			}
+			if x >= n {
+				x += delta
+			}
+			criteria[x] = c
+		}
+		f.Criteria = criteria
+	}
+	shiftRect(&f.Range, rows, n, delta)
+	if f.Range.Width == 0 || f.Range.Height == 0 {
+		s.Filter = nil
+		s.filteredRows = nil
+	}
+}
@@ -0,0 +1,56 @@
+package sheet
+
+// Merge joins cells of the rect into one showing the value of its top left cell, values of other cells
+// are kept but not shown. Returns false if the rect has just one cell or overlaps merged cells.
+func (s *Sheet) Merge(r Rect) bool {
+	if r.Width*r.Height < 2 {
+		return false
+	}
+	for _, m := range s.merges {
+		if m.Intersects(r) {
+			return false
+		}
+	}
+	s.merges = append(s.merges, r)
+	return true
+}
+
+// Unmerge splits merged cells intersecting the rect. Returns false if there are no such cells.
+func (s *Sheet) Unmerge(r Rect) bool {
+	merges := s.merges[:0]
+	for _, m := range s.merges {
+		if !m.Intersects(r) {
+			merges = append(merges, m)
+		}
+	}
+	found := len(merges) < len(s.merges)
+	s.merges = merges
+	return found
+}
+
+// MergedRect returns the merged cells containing the cell.
+func (s *Sheet) MergedRect(x, y int) (Rect, bool) {
+	for _, m := range s.merges {
+		if m.Contains(x, y) {
+			return m, true
+		}
+	}
+	return Rect{}, false
+}
+
+// Merges returns all the merged cells of the sheet.
+func (s *Sheet) Merges() []Rect {
+	return s.merges
+}
+
+// shiftMerges moves merged cells when a row (a column if rows is not set) is inserted or deleted.
+func (s *Sheet) shiftMerges(rows bool, n, delta int) {
+	merges := s.merges[:0]
+	for _, m := range s.merges {
+		shiftRect(&m, rows, n, delta)
+		if m.Width*m.Height > 1 {
+			merges = append(merges, m)
+		}
+	}
+	s.merges = merges
+}
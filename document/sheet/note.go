@@ -0,0 +1,81 @@
+package sheet
+
+import (
+	"sort"
+)
+
+// Note is the text attached to the cell.
+type Note struct {
+	X, Y int
+	Text string
+}
+
+// cellPos is the position of a cell, it keys notes.
+type cellPos struct {
+	x, y int
+}
+
+// Note returns the note of the cell or an empty string.
+func (s *Sheet) Note(x, y int) string {
+	return s.notes[cellPos{x, y}]
+}
+
+// SetNote attaches the note to the cell, empty text removes the note.
+func (s *Sheet) SetNote(x, y int, text string) {
+	if text == "" {
+		delete(s.notes, cellPos{x, y})
+		return
+	}
+	s.notes[cellPos{x, y}] = text
+}
+
+// Notes returns all the notes of the sheet sorted by rows and columns.
+func (s *Sheet) Notes() []Note {
+	notes := make([]Note, 0, len(s.notes))
+	for p, text := range s.notes {
+		notes = append(notes, Note{p.x, p.y, text})
+	}
+	sort.Slice(notes, func(i, j int) bool {
+		if notes[i].Y != notes[j].Y {
+			return notes[i].Y < notes[j].Y
+		}
+		return notes[i].X < notes[j].X
+	})
+	return notes
+}
+
+// shiftNotes moves notes when a row (a column if rows is not set) is inserted before n (delta is 1)
+// or the row n is deleted (delta is -1) with its notes.
+func (s *Sheet) shiftNotes(rows bool, n, delta int) {
+	notes := make(map[cellPos]string, len(s.notes))
+	for p, text := range s.notes {
+		pos := &p.x
+		if rows {
+			pos = &p.y
+		}
+		if *pos == n && delta < 0 {
+			continue
+		}
+		if *pos >= n {
+			*pos += delta
+		}
+		notes[p] = text
+	}
+	s.notes = notes
+}
+
+// permuteNotes moves notes of the rect along with rows permuted like PermuteRows does.
+func (s *Sheet) permuteNotes(r Rect, order []int) {
+	newY := make(map[int]int, len(order))
+	for i, n := range order {
+		newY[r.Y+n] = r.Y + i
+	}
+	notes := make(map[cellPos]string, len(s.notes))
+	for p, text := range s.notes {
+		if r.Contains(p.x, p.y) {
+			p.y = newY[p.y]
+		}
+		notes[p] = text
+	}
+	s.notes = notes
+}
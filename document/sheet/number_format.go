@@ -0,0 +1,521 @@
+package sheet
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	formatTokenLiteral = iota
+	formatTokenDigit   // 0, # or ?
+	formatTokenPoint
+	formatTokenComma
+	formatTokenPercent
+	formatTokenExp     // E+ or E-
+	formatTokenDate    // runs of y, m, d, h or s
+	formatTokenAmPm    // AM/PM or A/P
+	formatTokenElapsed // [h], [mm] or [ss]
+	formatTokenGeneral
+	formatTokenText // @
+)
+
+type formatToken struct {
+	kind int
+	text string
+}
+
+// FormatNumber formats the number with Excel format code like "#,##0.00", "0%", "$#,##0", "0.00E+00"
+// or "dd.mm.yyyy". Empty code or "General" leave the number as is.
+func FormatNumber(v decimal.Decimal, code string) string {
+	if code == "" || strings.EqualFold(code, "General") {
+		return v.String()
+	}
+	// sections are for positive numbers, negative numbers, zeros and text
+	sections := splitFormatSections(code)
+	section := sections[0]
+	switch {
+	case v.IsZero() && len(sections) > 2:
+		section = sections[2]
+	case v.IsNegative() && len(sections) > 1:
+		section = sections[1]
+		v = v.Abs()
+	}
+	tokens := parseFormatSection(section)
+	for _, t := range tokens {
+		if t.kind == formatTokenDate || t.kind == formatTokenAmPm || t.kind == formatTokenElapsed {
+			return formatDate(v, tokens)
+		}
+	}
+	return formatDigits(v, tokens)
+}
+
+// FormatText formats the text with the fourth section of the format code or with the only section
+// having "@" in it. Otherwise the text is returned as is.
+func FormatText(text, code string) string {
+	sections := splitFormatSections(code)
+	var tokens []formatToken
+	switch {
+	case len(sections) > 3:
+		tokens = parseFormatSection(sections[3])
+	case len(sections) == 1:
+		tokens = parseFormatSection(sections[0])
+		found := false
+		for _, t := range tokens {
+			found = found || t.kind == formatTokenText
+		}
+		if !found {
+			return text
+		}
+	default:
+		return text
+	}
+	var b strings.Builder
+	for _, t := range tokens {
+		if t.kind == formatTokenText {
+			b.WriteString(text)
+		} else {
+			b.WriteString(t.text)
+		}
+	}
+	return b.String()
+}
+
+// splitFormatSections splits the format code by semicolons which are not quoted, escaped or in brackets.
+func splitFormatSections(code string) []string {
+	var sections []string
+	start, quoted, bracket, escaped := 0, false, false, false
+	for i, r := range code {
+		switch {
+		case escaped:
+			escaped = false
+		case quoted:
+			quoted = r != '"'
+		case bracket:
+			bracket = r != ']'
+		case r == '\\':
+			escaped = true
+		case r == '"':
+			quoted = true
+		case r == '[':
+			bracket = true
+		case r == ';':
+			sections = append(sections, code[start:i])
+			start = i + 1
+		}
+	}
+	return append(sections, code[start:])
+}
+
+// parseFormatSection splits the section of the format code into tokens. Colors, conditions and fill characters
+// are not supported and skipped.
+func parseFormatSection(section string) []formatToken {
+	var tokens []formatToken
+	add := func(kind int, text string) {
+		tokens = append(tokens, formatToken{kind, text})
+	}
+	rs := []rune(section)
+	for i := 0; i < len(rs); i++ {
+		r := rs[i]
+		rest := string(rs[i:])
+		switch {
+		case r == '"':
+			j := i + 1
+			for j < len(rs) && rs[j] != '"' {
+				j++
+			}
+			add(formatTokenLiteral, string(rs[i+1:minInt(j, len(rs))]))
+			i = j
+		case r == '\\' && i+1 < len(rs):
+			add(formatTokenLiteral, string(rs[i+1]))
+			i++
+		case r == '_' && i+1 < len(rs):
+			// the space as wide as the next character
+			add(formatTokenLiteral, " ")
+			i++
+		case r == '*' && i+1 < len(rs):
+			i++
+		case r == '[':
+			j := i + 1
+			for j < len(rs) && rs[j] != ']' {
+				j++
+			}
+			inner := string(rs[i+1 : minInt(j, len(rs))])
+			i = j
+			switch {
+			case strings.HasPrefix(inner, "$"):
+				// currency like [$€-407]
+				if k := strings.IndexByte(inner, '-'); k >= 0 {
+					inner = inner[:k]
+				}
+				add(formatTokenLiteral, inner[1:])
+			case isElapsedFormat(strings.ToLower(inner)):
+				add(formatTokenElapsed, strings.ToLower(inner))
+			}
+		case r == '0' || r == '#' || r == '?':
+			add(formatTokenDigit, string(r))
+		case r == '.':
+			add(formatTokenPoint, ".")
+		case r == ',':
+			add(formatTokenComma, ",")
+		case r == '%':
+			add(formatTokenPercent, "%")
+		case (r == 'E' || r == 'e') && i+1 < len(rs) && (rs[i+1] == '+' || rs[i+1] == '-'):
+			add(formatTokenExp, string(rs[i:i+2]))
+			i++
+		case hasPrefixFold(rest, "AM/PM"):
+			add(formatTokenAmPm, string(rs[i:i+5]))
+			i += 4
+		case hasPrefixFold(rest, "A/P"):
+			add(formatTokenAmPm, string(rs[i:i+3]))
+			i += 2
+		case hasPrefixFold(rest, "General"):
+			add(formatTokenGeneral, "")
+			i += 6
+		case strings.ContainsRune("ymdhs", unicode.ToLower(r)):
+			j := i
+			for j < len(rs) && unicode.ToLower(rs[j]) == unicode.ToLower(r) {
+				j++
+			}
+			add(formatTokenDate, string(rs[i:j]))
+			i = j - 1
+		case r == '@':
+			add(formatTokenText, "@")
+		default:
+			add(formatTokenLiteral, string(r))
+		}
+	}
+	// letters of a number section like "0 pcs" are shown as is, they are not parts of a date
+	digits := false
+	for _, t := range tokens {
+		digits = digits || t.kind == formatTokenDigit
+	}
+	for i, t := range tokens {
+		switch {
+		case digits && (t.kind == formatTokenDate || t.kind == formatTokenAmPm):
+			tokens[i].kind = formatTokenLiteral
+		case t.kind == formatTokenDate:
+			tokens[i].text = strings.ToLower(t.text)
+		}
+	}
+	return tokens
+}
+
+// formatDigits formats the number by digit placeholders of the section.
+func formatDigits(v decimal.Decimal, tokens []formatToken) string {
+	var intDigits, fracDigits, expDigits []int
+	part, expAt, percents, scale, grouping := 0, -1, 0, 0, false
+	for i, t := range tokens {
+		switch t.kind {
+		case formatTokenDigit:
+			switch part {
+			case 0:
+				intDigits = append(intDigits, i)
+			case 1:
+				fracDigits = append(fracDigits, i)
+			default:
+				expDigits = append(expDigits, i)
+			}
+		case formatTokenPoint:
+			if part == 0 {
+				part = 1
+			} else {
+				tokens[i].kind = formatTokenLiteral
+			}
+		case formatTokenExp:
+			if part < 2 {
+				part, expAt = 2, i
+			} else {
+				tokens[i].kind = formatTokenLiteral
+			}
+		case formatTokenPercent:
+			percents++
+		case formatTokenComma:
+			// a comma between digits groups thousands, commas after digits divide the number by 1000
+			if i == 0 || (tokens[i-1].kind != formatTokenDigit && tokens[i-1].kind != formatTokenComma) {
+				tokens[i].kind = formatTokenLiteral
+				continue
+			}
+			j := i + 1
+			for j < len(tokens) && tokens[j].kind == formatTokenComma {
+				j++
+			}
+			if j < len(tokens) && tokens[j].kind == formatTokenDigit {
+				grouping = grouping || part == 0
+			} else {
+				scale++
+			}
+		}
+	}
+	v = v.Shift(int32(2*percents - 3*scale))
+	negative := v.IsNegative()
+	v = v.Abs()
+
+	exp := 0
+	if expAt >= 0 && !v.IsZero() {
+		f, _ := v.Float64()
+		exp = int(math.Floor(math.Log10(f)))
+		n := maxInt(len(intDigits), 1)
+		step := 1
+		if n > 1 && tokens[intDigits[0]].text == "#" {
+			// engineering notation like ##0.0E+0 keeps the exponent multiple of the number of integer digits
+			step = n
+			exp = int(math.Floor(float64(exp)/float64(n))) * n
+		} else {
+			exp -= n - 1
+		}
+		v = v.Shift(int32(-exp))
+		if v.Round(int32(len(fracDigits))).Cmp(decimal.New(1, int32(n))) >= 0 {
+			v = v.Shift(int32(-step))
+			exp += step
+		}
+	}
+	v = v.Round(int32(len(fracDigits)))
+	negative = negative && !v.IsZero()
+	parts := strings.SplitN(v.StringFixed(int32(len(fracDigits))), ".", 2)
+	intStr := strings.TrimLeft(parts[0], "0")
+
+	out := make([]string, len(tokens))
+	if grouping {
+		// zeros are padded up to the first 0 placeholder, then the whole number goes to the first placeholder
+		for i, n := range intDigits {
+			if tokens[n].text == "0" {
+				for len(intStr) < len(intDigits)-i {
+					intStr = "0" + intStr
+				}
+				break
+			}
+		}
+		out[intDigits[0]] = groupThousands(intStr)
+	} else {
+		// digits fill placeholders from the right, the first one takes all the rest
+		digits := intStr
+		for j := len(intDigits) - 1; j >= 0; j-- {
+			var s string
+			switch ph := tokens[intDigits[j]].text; {
+			case digits != "":
+				s, digits = digits[len(digits)-1:], digits[:len(digits)-1]
+			case ph == "0":
+				s = "0"
+			case ph == "?":
+				s = " "
+			}
+			if j == 0 {
+				s = digits + s
+			}
+			out[intDigits[j]] = s
+		}
+	}
+	if len(parts) > 1 {
+		frac := []byte(parts[1])
+		// trailing zeros are dropped for # and replaced with spaces for ?
+	trailing:
+		for j := len(frac) - 1; j >= 0 && frac[j] == '0'; j-- {
+			switch tokens[fracDigits[j]].text {
+			case "#":
+				frac[j] = 0
+			case "?":
+				frac[j] = ' '
+			default:
+				break trailing
+			}
+		}
+		for j, n := range fracDigits {
+			if frac[j] != 0 {
+				out[n] = string(frac[j])
+			}
+		}
+	}
+	if expAt >= 0 {
+		s := strconv.Itoa(absInt(exp))
+		for len(s) < len(expDigits) {
+			s = "0" + s
+		}
+		sign := ""
+		if exp < 0 {
+			sign = "-"
+		} else if tokens[expAt].text[1] == '+' {
+			sign = "+"
+		}
+		out[expAt] = tokens[expAt].text[:1] + sign + s
+	}
+
+	var b strings.Builder
+	if negative {
+		b.WriteByte('-')
+	}
+	for i, t := range tokens {
+		switch t.kind {
+		case formatTokenDigit, formatTokenExp:
+			b.WriteString(out[i])
+		case formatTokenPoint:
+			if len(intDigits) == 0 {
+				b.WriteString(intStr)
+			}
+			b.WriteString(".")
+		case formatTokenLiteral, formatTokenPercent:
+			b.WriteString(t.text)
+		case formatTokenGeneral, formatTokenText:
+			b.WriteString(v.String())
+		}
+	}
+	return b.String()
+}
+
+// formatDate formats the number as a date and time: the integer part is the number of days since 1900-01-00
+// and the fractional part is the time.
+func formatDate(v decimal.Decimal, tokens []formatToken) string {
+	if v.IsNegative() {
+		return "#####"
+	}
+	f, _ := v.Float64()
+	days := math.Floor(f)
+	seconds := math.Round((f - days) * 86400)
+	// Excel counts the nonexistent 1900-02-29, so dates after it are shifted
+	epoch := time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+	if days < 61 {
+		epoch = epoch.AddDate(0, 0, 1)
+	}
+	t := epoch.AddDate(0, 0, int(days)).Add(time.Duration(seconds) * time.Second)
+
+	hour12 := false
+	var dates []int
+	for i, tok := range tokens {
+		switch tok.kind {
+		case formatTokenAmPm:
+			hour12 = true
+		case formatTokenDate, formatTokenElapsed:
+			dates = append(dates, i)
+		}
+	}
+	// "m" means minutes after hours or before seconds, otherwise it is the month
+	minutes := make(map[int]bool)
+	for j, n := range dates {
+		if tokens[n].text[0] != 'm' || len(tokens[n].text) > 2 || tokens[n].kind != formatTokenDate {
+			continue
+		}
+		minutes[n] = (j > 0 && tokens[dates[j-1]].text[0] == 'h') || (j+1 < len(dates) && tokens[dates[j+1]].text[0] == 's')
+	}
+
+	var b strings.Builder
+	for i, tok := range tokens {
+		switch tok.kind {
+		case formatTokenDate:
+			b.WriteString(formatDatePart(t, tok.text, minutes[i], hour12))
+		case formatTokenElapsed:
+			total := f * 24
+			switch tok.text[0] {
+			case 'm':
+				total = f * 1440
+			case 's':
+				total = math.Round(f * 86400)
+			}
+			fmt.Fprintf(&b, "%0*d", len(tok.text), int(total))
+		case formatTokenAmPm:
+			am, pm := "AM", "PM"
+			if strings.EqualFold(tok.text, "A/P") {
+				am, pm = "A", "P"
+			}
+			if t.Hour() < 12 {
+				b.WriteString(am)
+			} else {
+				b.WriteString(pm)
+			}
+		default:
+			b.WriteString(tok.text)
+		}
+	}
+	return b.String()
+}
+
+// formatDatePart formats a part of the date like "yyyy", "mmm" or "hh".
+func formatDatePart(t time.Time, part string, minute, hour12 bool) string {
+	n := len(part)
+	switch part[0] {
+	case 'y':
+		if n <= 2 {
+			return fmt.Sprintf("%02d", t.Year()%100)
+		}
+		return fmt.Sprintf("%04d", t.Year())
+	case 'm':
+		switch {
+		case minute:
+			return fmt.Sprintf("%0*d", n, t.Minute())
+		case n <= 2:
+			return fmt.Sprintf("%0*d", n, int(t.Month()))
+		case n == 3:
+			return t.Month().String()[:3]
+		case n == 4:
+			return t.Month().String()
+		}
+		return t.Month().String()[:1]
+	case 'd':
+		switch {
+		case n <= 2:
+			return fmt.Sprintf("%0*d", n, t.Day())
+		case n == 3:
+			return t.Weekday().String()[:3]
+		}
+		return t.Weekday().String()
+	case 'h':
+		h := t.Hour()
+		if hour12 {
+			h %= 12
+			if h == 0 {
+				h = 12
+			}
+		}
+		return fmt.Sprintf("%0*d", minInt(n, 2), h)
+	}
+	return fmt.Sprintf("%0*d", minInt(n, 2), t.Second())
+}
+
+// isElapsedFormat checks if the bracketed part of the format code like "h" or "mm" means elapsed time.
+func isElapsedFormat(s string) bool {
+	if s == "" || !strings.ContainsRune("hms", rune(s[0])) {
+		return false
+	}
+	return strings.Count(s, s[:1]) == len(s)
+}
+
+// groupThousands inserts commas between groups of three digits.
+func groupThousands(digits string) string {
+	var b strings.Builder
+	for i, r := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func hasPrefixFold(s, prefix string) bool {
+	return len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix)
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func absInt(a int) int {
+	if a < 0 {
+		return -a
+	}
+	return a
+}
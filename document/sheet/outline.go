@@ -0,0 +1,123 @@
+package sheet
+
+import (
+	"sort"
+)
+
+// OutlineMaxLevel is the deepest level of nested groups supported by XLSX.
+const OutlineMaxLevel = 7
+
+// Outline keeps hidden state and group levels of rows or columns.
+type Outline struct {
+	hidden map[int]bool
+	levels map[int]int
+}
+
+func newOutline() *Outline {
+	return &Outline{
+		hidden: make(map[int]bool),
+		levels: make(map[int]int),
+	}
+}
+
+// Hidden checks if the row or column is hidden explicitly.
+func (o *Outline) Hidden(n int) bool {
+	return o.hidden[n]
+}
+
+// SetHidden hides or shows the row or column.
+func (o *Outline) SetHidden(n int, hidden bool) {
+	if hidden {
+		o.hidden[n] = true
+	} else {
+		delete(o.hidden, n)
+	}
+}
+
+// Level returns the group level of the row or column, 0 if it belongs to no group.
+func (o *Outline) Level(n int) int {
+	return o.levels[n]
+}
+
+// SetLevel sets the group level, it is limited by 0 and OutlineMaxLevel.
+func (o *Outline) SetLevel(n, level int) {
+	switch {
+	case level <= 0:
+		delete(o.levels, n)
+	case level > OutlineMaxLevel:
+		o.levels[n] = OutlineMaxLevel
+	default:
+		o.levels[n] = level
+	}
+}
+
+// HiddenItems returns sorted numbers of hidden rows or columns.
+func (o *Outline) HiddenItems() []int {
+	items := make([]int, 0, len(o.hidden))
+	for n := range o.hidden {
+		items = append(items, n)
+	}
+	sort.Ints(items)
+	return items
+}
+
+// GroupedItems returns sorted numbers of rows or columns having group level.
+func (o *Outline) GroupedItems() []int {
+	items := make([]int, 0, len(o.levels))
+	for n := range o.levels {
+		items = append(items, n)
+	}
+	sort.Ints(items)
+	return items
+}
+
+// Group returns the bounds of the group to collapse or expand at n. If the item before n belongs
+// to a deeper group, n is the summary row or column of that group, otherwise it is the innermost group containing n.
+func (o *Outline) Group(n int) (from, to int, ok bool) {
+	if n > 0 && o.levels[n-1] > o.levels[n] {
+		n--
+	}
+	level := o.levels[n]
+	if level == 0 {
+		return 0, 0, false
+	}
+	from, to = n, n
+	for from > 0 && o.levels[from-1] >= level {
+		from--
+	}
+	for o.levels[to+1] >= level {
+		to++
+	}
+	return from, to, true
+}
+
+// insert shifts state of items starting from n forward to make room for a new item.
+func (o *Outline) insert(n int) {
+	o.shift(n, 1)
+}
+
+// remove drops state of the item n and shifts state of the following items back.
+func (o *Outline) remove(n int) {
+	delete(o.hidden, n)
+	delete(o.levels, n)
+	o.shift(n+1, -1)
+}
+
+// shift moves state of items starting from n on delta.
+func (o *Outline) shift(n, delta int) {
+	hidden := make(map[int]bool, len(o.hidden))
+	for k, v := range o.hidden {
+		if k >= n {
+			k += delta
+		}
+		hidden[k] = v
+	}
+	levels := make(map[int]int, len(o.levels))
+	for k, v := range o.levels {
+		if k >= n {
+			k += delta
+		}
+		levels[k] = v
+	}
+	o.hidden, o.levels = hidden, levels
+}
@@ -1,5 +1,9 @@
 package sheet
 
+import (
+	"sort"
+)
+
 const (
 	CellDefaultWidth  = 80
 	CellDefaultHeight = 10
@@ -12,9 +16,14 @@ type Cursor struct {
 	Y int
 }
 
+// Viewport is the top left cell of the scrolled area. Rows above FrozenRows and columns left of FrozenCols
+// are always shown before it.
 type Viewport struct {
 	Left int
 	Top  int
+
+	FrozenRows int
+	FrozenCols int
 }
 
 type Rect struct {
@@ -34,6 +43,42 @@ func (r *Rect) MaxY() int {
 	return r.Y + r.Height - 1
 }
 
+// Contains checks if given X and Y belong to rect.
+func (r *Rect) Contains(x, y int) bool {
+	return x >= r.X && x <= r.MaxX() && y >= r.Y && y <= r.MaxY()
+}
+
+// Intersects checks if the rects have common cells.
+func (r *Rect) Intersects(o Rect) bool {
+	return r.X <= o.MaxX() && o.X <= r.MaxX() && r.Y <= o.MaxY() && o.Y <= r.MaxY()
+}
+
+// shiftRect moves the rect when a row (a column if rows is not set) is inserted before n (delta is 1)
+// or the row n is deleted (delta is -1). The rect containing the row grows or shrinks.
+func shiftRect(r *Rect, rows bool, n, delta int) {
+	pos, size := &r.X, &r.Width
+	if rows {
+		pos, size = &r.Y, &r.Height
+	}
+	switch {
+	case *pos > n || (*pos == n && delta > 0):
+		*pos += delta
+	case *pos+*size > n:
+		*size += delta
+	}
+}
+
+// RectFromCorners returns rect having given cells at opposite corners.
+func RectFromCorners(x1, y1, x2, y2 int) Rect {
+	if x2 < x1 {
+		x1, x2 = x2, x1
+	}
+	if y2 < y1 {
+		y1, y2 = y2, y1
+	}
+	return Rect{x1, y1, x2 - x1 + 1, y2 - y1 + 1}
+}
+
 type Sheet struct {
 	Idx      int
 	Title    string
@@ -42,8 +87,29 @@ type Sheet struct {
 	Size     Rect
 	Segments []Segment
 
+	// Selection is a range of cells selected with mouse, it is empty if nothing is selected.
+	Selection Rect
+	// Filter is nil if the sheet has no autofilter.
+	Filter *AutoFilter
+	// Rows and Cols keep explicitly hidden and grouped rows and columns.
+	Rows *Outline
+	Cols *Outline
+	// Rules of conditional formatting, the first one has the highest priority.
+	Rules []*ConditionalRule
+	// Validations of entered values.
+	Validations []*Validation
+
 	colSizes map[int]int
 	rowSizes map[int]int
+	// rows hidden by the filter
+	filteredRows map[int]bool
+	// number formats of whole columns
+	colFormats map[int]string
+	// styles shared by cells
+	styles map[Style]*Style
+	merges []Rect
+	// notes of cells are kept aside, most cells have none
+	notes map[cellPos]string
 }
 
 func New(idx int, name string) *Sheet {
@@ -51,11 +117,17 @@ func New(idx int, name string) *Sheet {
 		Idx:      idx,
 		Title:    name,
 		Cursor:   Cursor{0, 0},
-		Viewport: Viewport{0, 0},
+		Viewport: Viewport{0, 0, 0, 0},
 		Size:     Rect{0, 0, 0, 0},
+		Rows:     newOutline(),
+		Cols:     newOutline(),
 
 		colSizes: make(map[int]int),
 		rowSizes: make(map[int]int),
+
+		colFormats: make(map[int]string),
+		styles:     make(map[Style]*Style),
+		notes:      make(map[cellPos]string),
 	}
 }
 
@@ -83,6 +155,96 @@ func (s *Sheet) RowSize(n int) int {
 	return CellDefaultHeight
 }
 
+// SetRowSize sets the new height for a row in pixels.
+func (s *Sheet) SetRowSize(n, size int) {
+	if size < 1 || size > CellMaxHeight {
+		return
+	}
+	s.rowSizes[n] = size
+}
+
+// ColFormat returns the number format code of a column, empty for General.
+func (s *Sheet) ColFormat(n int) string {
+	return s.colFormats[n]
+}
+
+// SetColFormat sets the number format code of a column, empty code resets it to General.
+func (s *Sheet) SetColFormat(n int, code string) {
+	if code == "" {
+		delete(s.colFormats, n)
+		return
+	}
+	s.colFormats[n] = code
+}
+
+// FormattedCols returns sorted numbers of columns having number formats.
+func (s *Sheet) FormattedCols() []int {
+	cols := make([]int, 0, len(s.colFormats))
+	for n := range s.colFormats {
+		cols = append(cols, n)
+	}
+	sort.Ints(cols)
+	return cols
+}
+
+// CellFormat returns the number format code of the cell or of its column if the cell has no own format.
+func (s *Sheet) CellFormat(x, y int) string {
+	if c := s.Cell(x, y); c != nil && c.Format() != "" {
+		return c.Format()
+	}
+	return s.colFormats[x]
+}
+
+// RowHidden checks if the row is hidden explicitly or by the filter.
+func (s *Sheet) RowHidden(n int) bool {
+	return s.filteredRows[n] || s.Rows.Hidden(n)
+}
+
+// ColHidden checks if the column is hidden.
+func (s *Sheet) ColHidden(n int) bool {
+	return s.Cols.Hidden(n)
+}
+
+// SetFilteredRows sets rows hidden by the filter.
+func (s *Sheet) SetFilteredRows(rows map[int]bool) {
+	s.filteredRows = rows
+}
+
+// NextVisibleRow returns the row which is n visible rows below the row y (above if n is negative).
+// Stops at the first row. If the row y itself is hidden and n is zero, returns the nearest visible row below.
+func (s *Sheet) NextVisibleRow(y, n int) int {
+	return nextVisible(s.RowHidden, y, n)
+}
+
+// NextVisibleCol returns the column which is n visible columns right of the column x (left if n is negative).
+// Stops at the first column. If the column x itself is hidden and n is zero, returns the nearest visible column right.
+func (s *Sheet) NextVisibleCol(x, n int) int {
+	return nextVisible(s.ColHidden, x, n)
+}
+
+func nextVisible(hidden func(int) bool, pos, n int) int {
+	for hidden(pos) && n == 0 {
+		pos++
+	}
+	for ; n > 0; n-- {
+		pos++
+		for hidden(pos) {
+			pos++
+		}
+	}
+	for ; n < 0; n++ {
+		prev := pos - 1
+		for prev >= 0 && hidden(prev) {
+			prev--
+		}
+		if prev < 0 {
+			break
+		}
+		pos = prev
+	}
+	return pos
+}
+
 // AddStaticSegment creates a new Static segment and will with the given cells matrix.
 // TODO: check intersections. Will be such a case?
 // TODO: new segment need to be merged with the existing if possible
@@ -135,6 +297,98 @@ func (s *Sheet) SetCell(x, y int, cell *Cell) {
 	}
 }
 
+// NextCellInRow returns the column of the nearest non-empty cell in row y to the right (dir > 0)
+// or to the left (dir < 0) of column x, or -1 if there is no such cell.
+func (s *Sheet) NextCellInRow(x, y, dir int) int {
+	res := -1
+	for _, segment := range s.Segments {
+		if !segment.ContainsY(y) {
+			continue
+		}
+		size := segment.Size()
+		for cx := size.X; cx <= size.MaxX(); cx++ {
+			if (cx-x)*dir <= 0 || (res >= 0 && (cx-res)*dir >= 0) {
+				continue
+			}
+			if c := segment.Cell(cx, y); c != nil && c.RawValue() != "" {
+				res = cx
+			}
+		}
+	}
+	return res
+}
+
+// LastRow returns the last row having non-empty cells or -1 if the sheet is empty.
+func (s *Sheet) LastRow() int {
+	res := -1
+	for _, segment := range s.Segments {
+		size := segment.Size()
+		for y := size.MaxY(); y > res && y >= size.Y; y-- {
+			for x := size.X; x <= size.MaxX(); x++ {
+				if c := segment.Cell(x, y); c != nil && c.RawValue() != "" {
+					res = y
+					break
+				}
+			}
+		}
+	}
+	return res
+}
+
+// UsedRect returns the smallest rect containing all non-empty cells, it is empty if the sheet is empty.
+func (s *Sheet) UsedRect() Rect {
+	minX, minY, maxX, maxY := -1, -1, -1, -1
+	for _, segment := range s.Segments {
+		size := segment.Size()
+		for x := size.X; x <= size.MaxX(); x++ {
+			for y := size.Y; y <= size.MaxY(); y++ {
+				if c := segment.Cell(x, y); c == nil || c.RawValue() == "" {
+					continue
+				}
+				if minX < 0 || x < minX {
+					minX = x
+				}
+				if minY < 0 || y < minY {
+					minY = y
+				}
+				if x > maxX {
+					maxX = x
+				}
+				if y > maxY {
+					maxY = y
+				}
+			}
+		}
+	}
+	if minX < 0 {
+		return Rect{}
+	}
+	return RectFromCorners(minX, minY, maxX, maxY)
+}
+
+// PermuteRows reorders rows within the rect: row r.Y+i gets cells of row r.Y+order[i].
+func (s *Sheet) PermuteRows(r Rect, order []int) {
+	s.permuteNotes(r, order)
+	for x := r.X; x <= r.MaxX(); x++ {
+		cells := make([]Cell, len(order))
+		for i, n := range order {
+			if c := s.Cell(x, r.Y+n); c != nil {
+				cells[i] = *c
+			} else {
+				cells[i] = *NewCellEmpty()
+			}
+		}
+		for i := range cells {
+			if c := s.Cell(x, r.Y+i); c != nil {
+				// cells are moved, not copied, so their references are not freed
+				*c = cells[i]
+			} else if cells[i].RawValue() != "" {
+				s.SetCell(x, r.Y+i, &cells[i])
+			}
+		}
+	}
+}
+
 // FindSegment iterates over segments to find one containing cell with given X and Y.
 func (s *Sheet) FindSegment(x, y int) Segment {
 	for _, segment := range s.Segments {
@@ -146,6 +400,12 @@ func (s *Sheet) FindSegment(x, y int) Segment {
 }
 
 func (s *Sheet) InsertEmptyRow(y int) {
+	s.Rows.insert(y)
+	s.shiftMerges(true, y, 1)
+	s.shiftRules(true, y, 1)
+	s.shiftValidations(true, y, 1)
+	s.shiftNotes(true, y, 1)
+	s.shiftFilter(true, y, 1)
 	for _, segment := range s.Segments {
 		size := segment.Size()
 		if segment.ContainsY(y) {
@@ -159,6 +419,13 @@ func (s *Sheet) InsertEmptyRow(y int) {
 }
 
 func (s *Sheet) InsertEmptyCol(x int) {
+	s.Cols.insert(x)
+	s.shiftMerges(false, x, 1)
+	s.shiftRules(false, x, 1)
+	s.shiftValidations(false, x, 1)
+	s.shiftNotes(false, x, 1)
+	s.shiftFilter(false, x, 1)
+	s.shiftColFormats(x, 1)
 	for _, segment := range s.Segments {
 		size := segment.Size()
 		if segment.ContainsX(x) {
@@ -172,6 +439,12 @@ func (s *Sheet) InsertEmptyCol(x int) {
 }
 
 func (s *Sheet) DeleteRow(y int) {
+	s.Rows.remove(y)
+	s.shiftMerges(true, y, -1)
+	s.shiftRules(true, y, -1)
+	s.shiftValidations(true, y, -1)
+	s.shiftNotes(true, y, -1)
+	s.shiftFilter(true, y, -1)
 	for _, segment := range s.Segments {
 		size := segment.Size()
 		if segment.ContainsY(y) {
@@ -185,6 +458,14 @@ func (s *Sheet) DeleteRow(y int) {
 }
 
 func (s *Sheet) DeleteCol(x int) {
+	s.Cols.remove(x)
+	s.shiftMerges(false, x, -1)
+	s.shiftRules(false, x, -1)
+	s.shiftValidations(false, x, -1)
+	s.shiftNotes(false, x, -1)
+	s.shiftFilter(false, x, -1)
+	delete(s.colFormats, x)
+	s.shiftColFormats(x+1, -1)
 	for _, segment := range s.Segments {
 		size := segment.Size()
 		if segment.ContainsX(x) {
@@ -196,3 +477,15 @@ func (s *Sheet) DeleteCol(x int) {
 		}
 	}
 }
+
+// shiftColFormats moves number formats of columns starting from x on delta.
+func (s *Sheet) shiftColFormats(x, delta int) {
+	formats := make(map[int]string, len(s.colFormats))
+	for n, code := range s.colFormats {
+		if n >= x {
+			n += delta
+		}
+		formats[n] = code
+	}
+	s.colFormats = formats
+}
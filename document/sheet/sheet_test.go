@@ -0,0 +1,195 @@
+package sheet
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNextCellInRow(t *testing.T) {
+	s := New(0, "Sheet1")
+	for _, x := range []int{1, 2, 5} {
+		s.SetCell(x, 3, NewCellUntyped("v"))
+	}
+	s.SetCell(8, 7, NewCellUntyped("v"))
+	testCases := []struct {
+		x, y, dir int
+		expected  int
+	}{
+		{0, 3, 1, 1},
+		{1, 3, 1, 2},
+		{2, 3, 1, 5},
+		{5, 3, 1, -1},
+		{5, 3, -1, 2},
+		{1, 3, -1, -1},
+		{100, 3, -1, 5},
+		{0, 4, 1, -1},
+	}
+	for _, c := range testCases {
+		assert.Equalf(t, c.expected, s.NextCellInRow(c.x, c.y, c.dir), "case %d %d %d", c.x, c.y, c.dir)
+	}
+	assert.Equal(t, 7, s.LastRow())
+	assert.Equal(t, -1, New(1, "Sheet2").LastRow())
+}
+
+func TestFilterConditionMatches(t *testing.T) {
+	testCases := []struct {
+		condition string
+		value     string
+		expected  bool
+	}{
+		{`>10`, `11`, true},
+		{`>10`, `9.5`, false},
+		{`>10`, `abc`, false},
+		{`<>10`, `abc`, true},
+		{`<=b`, `A`, true},
+		{`=a*`, `Apple`, true},
+		{`=a?`, `Apple`, false},
+		{`<>*pie`, `apple pie`, false},
+		{`done`, `DONE`, true},
+		{`=`, ``, true},
+	}
+	for _, c := range testCases {
+		assert.Equalf(t, c.expected, ParseFilterCondition(c.condition).Matches(c.value), "case %s %s", c.condition, c.value)
+	}
+}
+
+func TestFormatNumber(t *testing.T) {
+	testCases := []struct {
+		value    string
+		code     string
+		expected string
+	}{
+		{`0.3333333333333333`, ``, `0.3333333333333333`},
+		{`0.3333333333333333`, `0.00`, `0.33`},
+		{`1234567.891`, `#,##0.00`, `1,234,567.89`},
+		{`-1234.5`, `#,##0`, `-1,235`},
+		{`0.256`, `0%`, `26%`},
+		{`0.256`, `0.0%`, `25.6%`},
+		{`1234.5`, `$#,##0.00`, `$1,234.50`},
+		{`-1234.5`, `$#,##0.00;($#,##0.00)`, `($1,234.50)`},
+		{`0`, `#,##0;-#,##0;"zero"`, `zero`},
+		{`12345`, `0.00E+00`, `1.23E+04`},
+		{`0.00012`, `0.0E+00`, `1.2E-04`},
+		{`12345`, `##0.0E+0`, `12.3E+3`},
+		{`1.5`, `0.##`, `1.5`},
+		{`0.5`, `#.00`, `.50`},
+		{`1234567`, `#,##0,`, `1,235`},
+		{`5551234`, `000-0000`, `555-1234`},
+		{`12.5`, `[Red]0.0 "kg"`, `12.5 kg`},
+		{`5`, `0 pcs`, `5 pcs`},
+		{`5`, `0 Days`, `5 Days`},
+		{`1500`, `#,##0 "m" AM/PM`, `1,500 m AM/PM`},
+		{`45306`, `yyyy-mm-dd`, `2024-01-15`},
+		{`45306`, `d mmm yy, dddd`, `15 Jan 24, Monday`},
+		{`45306.75`, `h:mm AM/PM`, `6:00 PM`},
+		{`45306.5104166667`, `hh:mm:ss`, `12:15:00`},
+		{`1.5`, `[h]:mm`, `36:00`},
+		{`1`, `mm/dd/yyyy`, `01/01/1900`},
+	}
+	for _, c := range testCases {
+		v := decimal.RequireFromString(c.value)
+		assert.Equalf(t, c.expected, FormatNumber(v, c.code), "case %s %s", c.value, c.code)
+	}
+	assert.Equal(t, "Total: abc", FormatText("abc", `0;0;0;"Total: "@`))
+	assert.Equal(t, "abc", FormatText("abc", `0.00`))
+}
+
+func TestOutline(t *testing.T) {
+	s := New(0, "Sheet1")
+	// rows 2-7 are grouped, rows 3-4 are nested in the group
+	for y := 2; y <= 7; y++ {
+		s.Rows.SetLevel(y, 1)
+	}
+	s.Rows.SetLevel(3, 2)
+	s.Rows.SetLevel(4, 2)
+	testCases := []struct {
+		y        int
+		ok       bool
+		from, to int
+	}{
+		{1, false, 0, 0},
+		{2, true, 2, 7},
+		{3, true, 3, 4},
+		{5, true, 3, 4},
+		{6, true, 2, 7},
+		{8, true, 2, 7},
+		{9, false, 0, 0},
+	}
+	for _, c := range testCases {
+		from, to, ok := s.Rows.Group(c.y)
+		assert.Equalf(t, c.ok, ok, "case %d", c.y)
+		if ok {
+			assert.Equalf(t, []int{c.from, c.to}, []int{from, to}, "case %d", c.y)
+		}
+	}
+
+	s.Cols.SetHidden(1, true)
+	s.Cols.SetHidden(2, true)
+	assert.Equal(t, 3, s.NextVisibleCol(0, 1))
+	assert.Equal(t, 0, s.NextVisibleCol(3, -1))
+	assert.Equal(t, 3, s.NextVisibleCol(1, 0))
+
+	s.InsertEmptyCol(0)
+	s.DeleteRow(3)
+	assert.Equal(t, []int{2, 3}, s.Cols.HiddenItems())
+	assert.Equal(t, []int{2, 3, 4, 5, 6}, s.Rows.GroupedItems())
+	assert.Equal(t, 2, s.Rows.Level(3))
+}
+
+func TestCellStyle(t *testing.T) {
+	s := New(0, "Sheet1")
+	bold := Style{Bold: true}
+	s.SetCellStyle(1, 1, bold)
+	s.SetRangeStyle(Rect{0, 0, 2, 2}, func(st Style) Style {
+		st.Align = AlignRight
+		return st
+	})
+	assert.Equal(t, Style{Bold: true, Align: AlignRight}, s.CellStyle(1, 1))
+	assert.Equal(t, Style{Align: AlignRight}, s.CellStyle(0, 1))
+	assert.True(t, s.Cell(0, 0).style == s.Cell(1, 0).style, "equal styles are shared")
+	s.SetCellStyle(0, 0, Style{})
+	assert.Nil(t, s.Cell(0, 0).style)
+	assert.Equal(t, Style{}, s.CellStyle(5, 5))
+}
+
+func TestMerge(t *testing.T) {
+	s := New(0, "Sheet1")
+	assert.True(t, s.Merge(Rect{1, 1, 2, 3}))
+	assert.False(t, s.Merge(Rect{2, 3, 2, 2}), "overlapping")
+	assert.False(t, s.Merge(Rect{5, 5, 1, 1}), "single cell")
+	r, ok := s.MergedRect(2, 3)
+	assert.True(t, ok)
+	assert.Equal(t, Rect{1, 1, 2, 3}, r)
+
+	s.InsertEmptyRow(2)
+	s.InsertEmptyCol(0)
+	assert.Equal(t, []Rect{{2, 1, 2, 4}}, s.Merges())
+	s.DeleteCol(3)
+	s.DeleteRow(1)
+	s.DeleteRow(1)
+	s.DeleteRow(1)
+	assert.Empty(t, s.Merges(), "merged cells shrunk to one cell")
+
+	s.Merge(Rect{0, 0, 3, 1})
+	assert.False(t, s.Unmerge(Rect{0, 1, 1, 1}))
+	assert.True(t, s.Unmerge(Rect{2, 0, 1, 1}))
+	assert.Empty(t, s.Merges())
+}
+
+func TestNotes(t *testing.T) {
+	s := New(0, "Sheet1")
+	s.SetNote(1, 1, "first")
+	s.SetNote(0, 3, "second")
+	s.SetNote(2, 2, "third")
+	s.SetNote(2, 2, "")
+	assert.Equal(t, []Note{{1, 1, "first"}, {0, 3, "second"}}, s.Notes())
+
+	s.InsertEmptyRow(0)
+	s.DeleteCol(0)
+	assert.Equal(t, []Note{{0, 2, "first"}}, s.Notes())
+	s.PermuteRows(Rect{0, 1, 1, 2}, []int{1, 0})
+	assert.Equal(t, "first", s.Note(0, 1))
+	assert.Equal(t, "", s.Note(0, 2))
+}
@@ -0,0 +1,81 @@
+package sheet
+
+const (
+	// AlignGeneral puts numbers to the right and other values to the left.
+	AlignGeneral = iota
+	AlignLeft
+	AlignCenter
+	AlignRight
+)
+
+// Style describes how the cell value is displayed. Colors are like "#ff8000", empty for default ones.
+// Styles are immutable and shared by cells, so each cell keeps just a pointer.
+type Style struct {
+	Align     int
+	Bold      bool
+	Italic    bool
+	Underline bool
+	Fg        string
+	Bg        string
+}
+
+// IsDefault checks if the style does not change anything.
+func (st Style) IsDefault() bool {
+	return st == Style{}
+}
+
+// Overlay returns the style with settings of o applied over it.
+func (st Style) Overlay(o Style) Style {
+	if o.Align != AlignGeneral {
+		st.Align = o.Align
+	}
+	st.Bold = st.Bold || o.Bold
+	st.Italic = st.Italic || o.Italic
+	st.Underline = st.Underline || o.Underline
+	if o.Fg != "" {
+		st.Fg = o.Fg
+	}
+	if o.Bg != "" {
+		st.Bg = o.Bg
+	}
+	return st
+}
+
+// CellStyle returns the style of the cell.
+func (s *Sheet) CellStyle(x, y int) Style {
+	if c := s.Cell(x, y); c != nil && c.style != nil {
+		return *c.style
+	}
+	return Style{}
+}
+
+// SetCellStyle sets the style of the cell, the same styles of different cells share memory.
+func (s *Sheet) SetCellStyle(x, y int, st Style) {
+	c := s.Cell(x, y)
+	if c == nil {
+		if st.IsDefault() {
+			return
+		}
+		s.SetCell(x, y, NewCellEmpty())
+		c = s.Cell(x, y)
+	}
+	if st.IsDefault() {
+		c.style = nil
+		return
+	}
+	shared, ok := s.styles[st]
+	if !ok {
+		shared = &st
+		s.styles[st] = shared
+	}
+	c.style = shared
+}
+
+// SetRangeStyle changes styles of all the cells in the rect with given function.
+func (s *Sheet) SetRangeStyle(r Rect, change func(Style) Style) {
+	for x := r.X; x <= r.MaxX(); x++ {
+		for y := r.Y; y <= r.MaxY(); y++ {
+			s.SetCellStyle(x, y, change(s.CellStyle(x, y)))
+		}
+	}
+}
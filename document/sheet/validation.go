@@ -0,0 +1,77 @@
+package sheet
+
+const (
+	// ValidateList allows one of listed values.
+	ValidateList = iota
+	// ValidateDecimal and ValidateWhole allow numbers meeting the conditions, whole numbers only for ValidateWhole.
+	ValidateDecimal
+	ValidateWhole
+	// ValidateDate allows dates meeting the conditions.
+	ValidateDate
+	// ValidateFormula allows values for which the formula is true.
+	ValidateFormula
+)
+
+// Validation restricts values entered into cells of the range. Empty cells are always valid.
+type Validation struct {
+	Range Rect
+	Kind  int
+	// Values of ValidateList, or Source referencing cells with them like "=$A$1:$A$5".
+	Values []string
+	Source string
+	// Conditions like ">=1" and "<=10" of ValidateDecimal, ValidateWhole and ValidateDate, operands of dates
+	// are dates like "2024-01-31" or their serial numbers.
+	Conditions []FilterCondition
+	// Formula of ValidateFormula is written for the top left cell of the range like in ConditionalRule.
+	Formula string
+	// Warning lets invalid values be kept after a warning instead of rejecting them.
+	Warning bool
+	// Message is shown for invalid values instead of the default description.
+	Message string
+}
+
+// AddValidation adds the validation for the range, it replaces validations of cells inside the range.
+func (s *Sheet) AddValidation(v *Validation) {
+	validations := s.Validations[:0]
+	for _, o := range s.Validations {
+		if !v.Range.Contains(o.Range.X, o.Range.Y) || !v.Range.Contains(o.Range.MaxX(), o.Range.MaxY()) {
+			validations = append(validations, o)
+		}
+	}
+	s.Validations = append(validations, v)
+}
+
+// RemoveValidations removes validations intersecting the rect. Returns false if there are no such validations.
+func (s *Sheet) RemoveValidations(r Rect) bool {
+	validations := s.Validations[:0]
+	for _, v := range s.Validations {
+		if !v.Range.Intersects(r) {
+			validations = append(validations, v)
+		}
+	}
+	found := len(validations) < len(s.Validations)
+	s.Validations = validations
+	return found
+}
+
+// Validation returns the validation of the cell or nil, the latest added one wins if ranges overlap.
+func (s *Sheet) Validation(x, y int) *Validation {
+	for i := len(s.Validations) - 1; i >= 0; i-- {
+		if s.Validations[i].Range.Contains(x, y) {
+			return s.Validations[i]
+		}
+	}
+	return nil
+}
+
+// shiftValidations moves ranges of validations when a row (a column if rows is not set) is inserted or deleted.
+func (s *Sheet) shiftValidations(rows bool, n, delta int) {
+	validations := s.Validations[:0]
+	for _, v := range s.Validations {
+		shiftRect(&v.Range, rows, n, delta)
+		if v.Range.Width > 0 && v.Range.Height > 0 {
+			validations = append(validations, v)
+		}
+	}
+	s.Validations = validations
+}
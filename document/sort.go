@@ -0,0 +1,161 @@
+package document
+
+import (
+	"xl/document/eval"
+	"xl/document/sheet"
+
+	"sort"
+	"time"
+)
+
+// SortKey is a column to sort rows by.
+type SortKey struct {
+	Col        int
+	Descending bool
+}
+
+// dateLayouts are formats of texts compared as dates when sorting.
+var dateLayouts = []string{
+	"2006-01-02",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"2006/01/02",
+	"02.01.2006",
+	"01/02/2006",
+	"2 Jan 2006",
+	"Jan 2, 2006",
+}
+
+// SortRows sorts rows within the area of the sheet (all used cells if the area is empty) by the key columns.
+// Rows having equal keys keep their order. If header is set, the first row of the area stays in place.
+// References to the moved cells follow them. Returns the new position of every row of the sorted area.
+func (d *Document) SortRows(s *sheet.Sheet, area sheet.Rect, keys []SortKey, header bool) (map[int]int, error) {
+	if area.Width == 0 {
+		area = s.UsedRect()
+	}
+	if header {
+		area.Y++
+		area.Height--
+	}
+	if area.Height < 2 {
+		return nil, nil
+	}
+	for _, k := range keys {
+		if k.Col < area.X || k.Col > area.MaxX() {
+			return nil, eval.NewError(eval.ErrorKindRef, "column %s is out of the sorted range", ColName(k.Col))
+		}
+	}
+	// formulas are parsed so that their references follow the cells
+	d.parseFormulas()
+
+	ec := eval.NewContext(d, s.Idx)
+	values := make([][]eval.Value, area.Height)
+	for i := range values {
+		values[i] = make([]eval.Value, len(keys))
+		for j, k := range keys {
+			if v, err := d.Value(ec, eval.Cell{SheetIdx: s.Idx, X: k.Col, Y: area.Y + i}); err == nil {
+				values[i][j] = v
+			}
+		}
+	}
+	order := make([]int, area.Height)
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		for j, k := range keys {
+			if c := compareSortValues(ec, values[order[a]][j], values[order[b]][j], k.Descending); c != 0 {
+				return c < 0
+			}
+		}
+		return false
+	})
+
+	s.PermuteRows(area, order)
+	// position of every row after sorting
+	newY := make(map[int]int, len(order))
+	for i, n := range order {
+		newY[area.Y+n] = area.Y + i
+	}
+	for _, r := range d.refRegistry {
+		if r.Cell.SheetIdx != s.Idx || r.RangeEnd || !area.Contains(r.Cell.X, r.Cell.Y) {
+			continue
+		}
+		r.Cell.Y = newY[r.Cell.Y]
+	}
+	d.UpdateSpills()
+	return newY, nil
+}
+
+// parseFormulas makes all formulas of the document resolve their references.
+func (d *Document) parseFormulas() {
+	for _, s := range d.Sheets {
+		ec := eval.NewContext(d, s.Idx)
+		for _, segment := range s.Segments {
+			size := segment.Size()
+			for x := size.X; x <= size.MaxX(); x++ {
+				for y := size.Y; y <= size.MaxY(); y++ {
+					segment.Cell(x, y).IsFormula(ec)
+				}
+			}
+		}
+	}
+}
+
+// compareSortValues compares values with eval.CompareValues, texts looking like dates are compared as dates.
+// Values which failed to evaluate (nil) and empty values go last in both orders.
+func compareSortValues(ec *eval.Context, a, b eval.Value, descending bool) int {
+	aLast, bLast := sortsLast(ec, a), sortsLast(ec, b)
+	switch {
+	case aLast && bLast:
+		return 0
+	case aLast:
+		return 1
+	case bLast:
+		return -1
+	}
+	c, err := eval.CompareValues(ec, a, b)
+	if err != nil {
+		return 0
+	}
+	if ta, ok := dateValue(ec, a); ok {
+		if tb, ok := dateValue(ec, b); ok {
+			c = 0
+			if ta.Before(tb) {
+				c = -1
+			} else if ta.After(tb) {
+				c = 1
+			}
+		}
+	}
+	if descending {
+		return -c
+	}
+	return c
+}
+
+// sortsLast checks if the value goes after all others regardless of the order.
+func sortsLast(ec *eval.Context, v eval.Value) bool {
+	if v == nil {
+		return true
+	}
+	t, err := v.Type(ec)
+	return err != nil || t == eval.TypeEmpty
+}
+
+// dateValue parses text values looking like dates.
+func dateValue(ec *eval.Context, v eval.Value) (time.Time, bool) {
+	if t, err := v.Type(ec); err != nil || t != eval.TypeString {
+		return time.Time{}, false
+	}
+	s, err := v.StringValue(ec)
+	if err != nil {
+		return time.Time{}, false
+	}
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
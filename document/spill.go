@@ -0,0 +1,183 @@
+package document
+
+import (
+	"xl/document/eval"
+	"xl/document/sheet"
+)
+
+// spillRange is a range of cells filled by array result of the formula in its top left (anchor) cell.
+type spillRange struct {
+	anchor *eval.CellRef
+	width  int
+	height int
+}
+
+// covers checks if the cell belongs to the spill range. Anchor cell itself is not covered.
+func (r *spillRange) covers(cell eval.Cell) bool {
+	a := r.anchor.Cell
+	if a.SheetIdx != cell.SheetIdx || a == cell {
+		return false
+	}
+	return cell.X >= a.X && cell.X < a.X+r.width && cell.Y >= a.Y && cell.Y < a.Y+r.height
+}
+
+// intersects checks if two spill ranges have common cells.
+func (r *spillRange) intersects(sheetIdx, x, y, width, height int) bool {
+	a := r.anchor.Cell
+	return a.SheetIdx == sheetIdx &&
+		a.X < x+width && x < a.X+r.width &&
+		a.Y < y+height && y < a.Y+r.height
+}
+
+// Spilled checks if the cell is filled by array result of some formula nearby.
+// Such cells are read-only.
+func (d *Document) Spilled(cell eval.Cell) bool {
+	return d.spillCovering(cell) != nil
+}
+
+// UpdateSpills evaluates all formulas to find ones spilling their results into neighbouring cells.
+func (d *Document) UpdateSpills() {
+	for _, s := range d.Sheets {
+		ec := eval.NewContext(d, s.Idx)
+		for _, segment := range s.Segments {
+			size := segment.Size()
+			for x := size.X; x <= size.MaxX(); x++ {
+				for y := size.Y; y <= size.MaxY(); y++ {
+					if segment.Cell(x, y).IsFormula(ec) {
+						d.UpdateSpill(eval.Cell{SheetIdx: s.Idx, X: x, Y: y})
+					}
+				}
+			}
+		}
+	}
+}
+
+// UpdateSpill evaluates the cell to refresh its spill range.
+func (d *Document) UpdateSpill(cell eval.Cell) {
+	_, _, _ = d.cellValue(eval.NewContext(d, cell.SheetIdx), cell)
+}
+
+// spill checks if the array fits into the blank cells next to the anchor cell and remembers the spill range.
+// Returns top left element of the array, or #SPILL! error if there is no room for the array.
+func (d *Document) spill(s *sheet.Sheet, anchor eval.Cell, a *eval.ArrayValue) (eval.Value, error) {
+	width, height := a.Width(), a.Height()
+	if width == 0 || height == 0 {
+		d.removeSpill(anchor)
+		return eval.NewEmptyValue(), nil
+	}
+	if !d.spillRangeIsBlank(s, anchor, width, height) {
+		d.removeSpill(anchor)
+		return eval.NewEmptyValue(), eval.NewError(eval.ErrorKindSpill, "#SPILL!")
+	}
+	r := d.findSpill(anchor)
+	if r == nil {
+		r = &spillRange{
+			anchor: d.cellRef(anchor, false),
+		}
+		d.spills = append(d.spills, r)
+	}
+	r.width, r.height = width, height
+	return a.At(0, 0), nil
+}
+
+// spilledValue returns value for the cell filled by array result of the formula nearby.
+// Returns empty value if the cell is not spilled.
+func (d *Document) spilledValue(ec *eval.Context, cell eval.Cell) (eval.Value, error) {
+	r := d.spillCovering(cell)
+	if r == nil {
+		return eval.NewEmptyValue(), nil
+	}
+	anchor := r.anchor.Cell
+	s := d.sheetByIdx(anchor.SheetIdx)
+	c := s.Cell(anchor.X, anchor.Y)
+	if c == nil || !c.IsFormula(ec) {
+		// the formula has gone
+		d.removeSpill(anchor)
+		return eval.NewEmptyValue(), nil
+	}
+	if ec.Visited(anchor) {
+		return eval.NewEmptyValue(), eval.NewError(eval.ErrorKindRef, "circular reference")
+	}
+	l := ec.AddVisited(anchor)
+	defer ec.ResetVisited(l)
+	v, err := c.Value(ec)
+	if err != nil {
+		// error is displayed in the anchor cell
+		return eval.NewEmptyValue(), nil
+	}
+	a, err := arrayResult(ec, v)
+	if err != nil {
+		return eval.NewEmptyValue(), nil
+	}
+	if a == nil {
+		d.removeSpill(anchor)
+		return eval.NewEmptyValue(), nil
+	}
+	if _, err = d.spill(s, anchor, a); err != nil {
+		return eval.NewEmptyValue(), nil
+	}
+	x, y := cell.X-anchor.X, cell.Y-anchor.Y
+	if x >= a.Width() || y >= a.Height() {
+		return eval.NewEmptyValue(), nil
+	}
+	return a.At(x, y), nil
+}
+
+// spillRangeIsBlank checks if there are no values and other spill ranges in the range the array to be spilled to.
+func (d *Document) spillRangeIsBlank(s *sheet.Sheet, anchor eval.Cell, width, height int) bool {
+	for _, r := range d.spills {
+		if r.anchor.Cell != anchor && r.intersects(anchor.SheetIdx, anchor.X, anchor.Y, width, height) {
+			return false
+		}
+	}
+	for x := anchor.X; x < anchor.X+width; x++ {
+		for y := anchor.Y; y < anchor.Y+height; y++ {
+			if x == anchor.X && y == anchor.Y {
+				continue
+			}
+			if c := s.Cell(x, y); c != nil && c.RawValue() != "" {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (d *Document) findSpill(anchor eval.Cell) *spillRange {
+	for _, r := range d.spills {
+		if r.anchor.Cell == anchor {
+			return r
+		}
+	}
+	return nil
+}
+
+func (d *Document) spillCovering(cell eval.Cell) *spillRange {
+	for _, r := range d.spills {
+		if r.covers(cell) {
+			return r
+		}
+	}
+	return nil
+}
+
+func (d *Document) removeSpill(anchor eval.Cell) {
+	for i, r := range d.spills {
+		if r.anchor.Cell == anchor {
+			r.anchor.UsageCount--
+			d.spills = append(d.spills[:i], d.spills[i+1:]...)
+			return
+		}
+	}
+}
+
+// arrayResult returns formula result as array if it is an array or a range. Otherwise returns nil.
+func arrayResult(ec *eval.Context, v eval.Value) (*eval.ArrayValue, error) {
+	switch v := v.(type) {
+	case *eval.ArrayValue:
+		return v, nil
+	case *eval.RangeRef:
+		return v.Array(ec)
+	}
+	return nil, nil
+}
@@ -0,0 +1,191 @@
+package document
+
+import (
+	"xl/document/eval"
+	"xl/document/sheet"
+
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// ValidateCell checks the value of the cell against the validation of its range, returns the error describing
+// allowed values if the value is invalid.
+func (d *Document) ValidateCell(s *sheet.Sheet, x, y int) error {
+	v := s.Validation(x, y)
+	if v == nil {
+		return nil
+	}
+	ec := eval.NewContext(d, s.Idx)
+	cell := eval.Cell{SheetIdx: s.Idx, X: x, Y: y}
+	value, err := d.Value(ec, cell)
+	if err != nil {
+		return err
+	}
+	if t, err := value.Type(ec); err != nil || t == eval.TypeEmpty {
+		return err
+	}
+	ok, err := d.validValue(ec, s, v, value, x, y)
+	if err != nil || ok {
+		return err
+	}
+	if v.Message != "" {
+		return eval.NewError(eval.ErrorKindCasting, v.Message)
+	}
+	return eval.NewError(eval.ErrorKindCasting, "value must be %s", ValidationDescription(v))
+}
+
+// validValue checks if the value of the cell meets the validation.
+func (d *Document) validValue(ec *eval.Context, s *sheet.Sheet, v *sheet.Validation, value eval.Value, x, y int) (bool, error) {
+	switch v.Kind {
+	case sheet.ValidateList:
+		text, err := value.StringValue(ec)
+		if err != nil {
+			return false, err
+		}
+		values, err := d.ValidationValues(s, v)
+		if err != nil {
+			return false, err
+		}
+		for _, allowed := range values {
+			if strings.EqualFold(allowed, text) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case sheet.ValidateDecimal, sheet.ValidateWhole:
+		if t, err := value.Type(ec); err != nil || t != eval.TypeDecimal {
+			return false, err
+		}
+		n, err := value.DecimalValue(ec)
+		if err != nil {
+			return false, err
+		}
+		if v.Kind == sheet.ValidateWhole && !n.Equal(n.Truncate(0)) {
+			return false, nil
+		}
+		return conditionsMatch(v.Conditions, n, nil), nil
+	case sheet.ValidateDate:
+		n, ok := dateSerialValue(ec, value)
+		return ok && conditionsMatch(v.Conditions, n, ParseDateSerial), nil
+	case sheet.ValidateFormula:
+		c := sheet.NewCellUntyped(relativeFormula(v.Formula, s.Title, x-v.Range.X, y-v.Range.Y))
+		defer c.Free()
+		res, err := c.BoolValue(ec)
+		return err == nil && res, nil
+	}
+	return true, nil
+}
+
+// ValidationValues returns values allowed by the list validation of the sheet.
+func (d *Document) ValidationValues(s *sheet.Sheet, v *sheet.Validation) ([]string, error) {
+	if v.Source == "" {
+		return v.Values, nil
+	}
+	ec := eval.NewContext(d, s.Idx)
+	c := sheet.NewCellUntyped(relativeFormula(v.Source, s.Title, 0, 0))
+	defer c.Free()
+	value, err := c.Value(ec)
+	if err != nil {
+		return nil, err
+	}
+	a, err := arrayResult(ec, value)
+	if err != nil {
+		return nil, err
+	}
+	if a == nil {
+		a = eval.NewArrayValue([][]eval.Value{{value}})
+	}
+	var values []string
+	for y := 0; y < a.Height(); y++ {
+		for _, item := range a.Row(y) {
+			text, err := item.StringValue(ec)
+			if err != nil {
+				return nil, err
+			}
+			if text != "" {
+				values = append(values, text)
+			}
+		}
+	}
+	return values, nil
+}
+
+// ValidationDescription describes values allowed by the validation.
+func ValidationDescription(v *sheet.Validation) string {
+	var conditions []string
+	for _, c := range v.Conditions {
+		conditions = append(conditions, c.String())
+	}
+	cond := ""
+	if len(conditions) > 0 {
+		cond = " " + strings.Join(conditions, " and ")
+	}
+	switch v.Kind {
+	case sheet.ValidateList:
+		if v.Source != "" {
+			return "one of values of " + strings.TrimPrefix(v.Source, "=")
+		}
+		return "one of: " + strings.Join(v.Values, ", ")
+	case sheet.ValidateDecimal:
+		return "a number" + cond
+	case sheet.ValidateWhole:
+		return "a whole number" + cond
+	case sheet.ValidateDate:
+		return "a date" + cond
+	}
+	return "meeting the condition " + v.Formula
+}
+
+// conditionsMatch checks if the number meets all the conditions. Operands are converted to numbers with
+// the operand function if it is set.
+func conditionsMatch(conditions []sheet.FilterCondition, n decimal.Decimal, operand func(string) (decimal.Decimal, bool)) bool {
+	for _, c := range conditions {
+		if operand != nil {
+			o, ok := operand(c.Operand)
+			if !ok {
+				return false
+			}
+			c.Operand = o.String()
+		}
+		if !c.Matches(n.String()) {
+			return false
+		}
+	}
+	return true
+}
+
+// dateSerialValue returns the serial number of the date given as a number or as a text like "2024-01-31".
+func dateSerialValue(ec *eval.Context, v eval.Value) (decimal.Decimal, bool) {
+	if t, err := v.Type(ec); err == nil && t == eval.TypeDecimal {
+		n, err := v.DecimalValue(ec)
+		return n, err == nil
+	}
+	t, ok := dateValue(ec, v)
+	if !ok {
+		return decimal.Zero, false
+	}
+	return DateSerial(t), true
+}
+
+// ParseDateSerial returns the serial number of the date given as a number or as a text like "2024-01-31".
+func ParseDateSerial(text string) (decimal.Decimal, bool) {
+	if n, err := decimal.NewFromString(text); err == nil {
+		return n, true
+	}
+	return dateSerialValue(nil, eval.NewStringValue(text))
+}
+
+// DateSerial returns the number of days since 1900-01-00 counting the nonexistent 1900-02-29 like Excel does.
+// The fraction is the time of the day.
+func DateSerial(t time.Time) decimal.Decimal {
+	epoch := time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.UTC)
+	days := decimal.NewFromInt(int64(t.Sub(epoch).Hours()/24 + 1e-9))
+	if t.Before(time.Date(1900, 3, 1, 0, 0, 0, 0, time.UTC)) {
+		days = days.Sub(decimal.NewFromInt(1))
+	}
+	secs := t.Hour()*3600 + t.Minute()*60 + t.Second()
+	return days.Add(decimal.NewFromInt(int64(secs)).Div(decimal.NewFromInt(86400)))
+}
@@ -0,0 +1,90 @@
+package formula
+
+import (
+	"xl/document/eval"
+
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/alecthomas/participle"
+)
+
+// ParseError is a formula syntax error.
+type ParseError struct {
+	msg string
+	// Offset is a byte offset of the error in the formula source.
+	Offset int
+	// Unexpected is a token found at the error position, empty at the end of the formula.
+	Unexpected string
+	// Expected lists tokens which could be there instead.
+	Expected []string
+}
+
+var (
+	reUnexpected = regexp.MustCompile(`unexpected (?:token )?("(?:[^"\\]|\\.)*")(?: \(expected (.+)\))?$`)
+	reErrorPos   = regexp.MustCompile(`^(?:<source>:)?\d+:\d+: `)
+)
+
+// newParseError turns parser error into ParseError.
+func newParseError(source string, err error) *ParseError {
+	pe := &ParseError{
+		Offset: len(source),
+	}
+	if perr, ok := err.(participle.Error); ok {
+		pe.Offset = perr.Position().Offset
+	}
+	if pe.Offset > len(source) {
+		pe.Offset = len(source)
+	}
+	msg := reErrorPos.ReplaceAllString(err.Error(), "")
+	if m := reUnexpected.FindStringSubmatch(msg); m != nil {
+		if token, err := strconv.Unquote(m[1]); err == nil && token != "<EOF>" {
+			pe.Unexpected = token
+		}
+		if m[2] != "" {
+			for _, e := range strings.Split(m[2], " | ") {
+				pe.Expected = append(pe.Expected, expectedTokenName(e))
+			}
+		}
+		msg = "unexpected " + pe.token()
+		if len(pe.Expected) > 0 {
+			msg += ", expected " + strings.Join(pe.Expected, " or ")
+		}
+	} else if pe.Offset < len(source) {
+		pe.Unexpected = source[pe.Offset : pe.Offset+1]
+	}
+	pe.msg = msg
+	return pe
+}
+
+func (e *ParseError) Error() string {
+	return e.msg
+}
+
+func (e *ParseError) Kind() int {
+	return eval.ErrorKindFormula
+}
+
+// Description returns the message for the status line.
+func (e *ParseError) Description() string {
+	return fmt.Sprintf("syntax error at %d: %s", e.Offset+1, e.msg)
+}
+
+func (e *ParseError) token() string {
+	if e.Unexpected == "" {
+		return "end of formula"
+	}
+	return strconv.Quote(e.Unexpected)
+}
+
+// expectedTokenName makes grammar token names readable.
+func expectedTokenName(s string) string {
+	switch s {
+	case "<cell>":
+		// the last alternative of an operand, so anything is possible
+		return "value"
+	}
+	return strings.Trim(s, "<>")
+}
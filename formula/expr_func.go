@@ -110,17 +110,55 @@ func (e *Primary) BuildFunc() (Function, int) {
 			return eval.NewStringValue(string(*e.String)), nil
 		}
 		return f, 0
+	} else if e.Array != nil {
+		return e.Array.BuildFunc()
 	} else {
 		f := func(ec *eval.Context, args []eval.Value) (eval.Value, error) {
 			if len(args) == 0 {
 				panic("too few arguments")
 			}
+			// names are resolved here, so functions get the referenced cell, range or constant
+			if r, ok := args[0].(*eval.NameRef); ok {
+				return r.Resolve(ec)
+			}
 			return args[0], nil
 		}
 		return f, 1
 	}
 }
 
+func (e *Array) BuildFunc() (Function, int) {
+	f := func(*eval.Context, []eval.Value) (eval.Value, error) {
+		rows := make([][]eval.Value, len(e.Rows))
+		for y, r := range e.Rows {
+			if len(r.Items) != len(e.Rows[0].Items) {
+				return eval.NewEmptyValue(), eval.NewError(eval.ErrorKindFormula, "array rows must be of the same length")
+			}
+			rows[y] = make([]eval.Value, len(r.Items))
+			for x, item := range r.Items {
+				rows[y][x] = item.value()
+			}
+		}
+		return eval.NewArrayValue(rows), nil
+	}
+	return f, 0
+}
+
+func (e *ArrayItem) value() eval.Value {
+	if e.Number != nil {
+		d := decimal.NewFromFloat(*e.Number)
+		if e.Minus {
+			d = d.Neg()
+		}
+		return eval.NewDecimalValue(d)
+	} else if e.String != nil {
+		return eval.NewStringValue(string(*e.String))
+	} else if e.Boolean != nil {
+		return eval.NewBoolValue(bool(*e.Boolean))
+	}
+	return eval.NewEmptyValue()
+}
+
 func (e *Func) BuildFunc() (Function, int) {
 	totalConsumedArgs := 0
 	subFunc := make([]Function, len(e.Arguments))
@@ -16,6 +16,7 @@ const (
 	OutputTypeFunction
 	OutputTypeSheet
 	OutputTypeCell
+	OutputTypeName
 )
 
 func (e *Expression) Output(of OutputFunc) {
@@ -90,6 +91,8 @@ func (e *Primary) Output(of OutputFunc) {
 		} else {
 			of("FALSE", OutputTypeBoolean)
 		}
+	} else if e.Array != nil {
+		e.Array.Output(of)
 	} else if e.Func != nil {
 		e.Func.Output(of)
 	} else if e.Variable != nil {
@@ -97,6 +100,41 @@ func (e *Primary) Output(of OutputFunc) {
 	}
 }
 
+func (e *Array) Output(of OutputFunc) {
+	of("{", OutputTypeSymbol)
+	for i, r := range e.Rows {
+		if i > 0 {
+			of(";", OutputTypeSymbol)
+		}
+		for j, x := range r.Items {
+			if j > 0 {
+				of(",", OutputTypeSymbol)
+			}
+			x.Output(of)
+		}
+	}
+	of("}", OutputTypeSymbol)
+}
+
+func (e *ArrayItem) Output(of OutputFunc) {
+	if e.Number != nil {
+		if e.Minus {
+			of("-", OutputTypeOperator)
+		}
+		of(strconv.FormatFloat(*e.Number, 'f', -1, 64), OutputTypeNumber)
+	} else if e.String != nil {
+		of("\"", OutputTypeSymbol)
+		of(string(*e.String), OutputTypeString)
+		of("\"", OutputTypeSymbol)
+	} else if e.Boolean != nil {
+		if *e.Boolean {
+			of("TRUE", OutputTypeBoolean)
+		} else {
+			of("FALSE", OutputTypeBoolean)
+		}
+	}
+}
+
 func (e *Func) Output(of OutputFunc) {
 	of(string(e.Name), OutputTypeFunction)
 	of("(", OutputTypeSymbol)
@@ -111,6 +149,10 @@ func (e *Func) Output(of OutputFunc) {
 }
 
 func (e *Variable) Output(of OutputFunc) {
+	if e.Name != nil {
+		of(string(*e.Name), OutputTypeName)
+		return
+	}
 	e.Cell.Output(of)
 	if e.CellTo != nil {
 		of(":", OutputTypeSymbol)
@@ -13,15 +13,22 @@ const maxArguments = 1000
 type Function func(*eval.Context, []eval.Value) (eval.Value, error)
 
 type functionDef struct {
-	F       Function
-	MinArgs int
-	MaxArgs int
+	F           Function
+	MinArgs     int
+	MaxArgs     int
+	Description string
 }
 
 var functions = map[string]functionDef{
-	"TRIM": {trim, 1, 1},
-	"SUM":  {sum, 1, maxArguments},
-	"IF":   {if_, 3, 3},
+	"TRIM": {trim, 1, 1, "Removes spaces from text"},
+	"SUM":  {sum, 1, maxArguments, "Adds its arguments"},
+	"IF":   {if_, 3, 3, "Specifies a logical test to perform"},
+
+	"SEQUENCE":  {sequence, 1, 4, "Generates a list of sequential numbers in an array, such as 1, 2, 3, 4"},
+	"TRANSPOSE": {transpose, 1, 1, "Returns the transpose of an array"},
+	"SORT":      {sort_, 1, 4, "Sorts the contents of a range or array"},
+	"FILTER":    {filter, 2, 3, "Filters a range of data based on criteria you define"},
+	"UNIQUE":    {unique, 1, 3, "Returns a list of unique values in a list or range"},
 	// ABS [Math and trigonometry] Returns the absolute value of a number
 	// ACCRINT [Financial] Returns the accrued interest for a security that pays periodic interest
 	// ACCRINTM [Financial] Returns the accrued interest for a security that pays interest at maturity
@@ -519,6 +526,16 @@ func sum(ec *eval.Context, args []eval.Value) (eval.Value, error) {
 			if err != nil {
 				return eval.NewEmptyValue(), err
 			}
+		} else if a, ok := args[i].(*eval.ArrayValue); ok {
+			for y := 0; y < a.Height(); y++ {
+				for _, v := range a.Row(y) {
+					d, err := v.DecimalValue(ec)
+					if err != nil {
+						return eval.NewEmptyValue(), err
+					}
+					s = s.Add(d)
+				}
+			}
 		} else {
 			d, err := args[i].DecimalValue(ec)
 			if err != nil {
@@ -0,0 +1,268 @@
+package formula
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"xl/document/eval"
+
+	"github.com/shopspring/decimal"
+)
+
+// maxArraySize limits number of elements in arrays created by functions.
+const maxArraySize = 1000000
+
+// SEQUENCE [Math and trigonometry] Generates a list of sequential numbers in an array, such as 1, 2, 3, 4
+func sequence(ec *eval.Context, args []eval.Value) (eval.Value, error) {
+	rows, err := intArg(ec, args, 0, 1)
+	if err != nil {
+		return eval.NewEmptyValue(), err
+	}
+	cols, err := intArg(ec, args, 1, 1)
+	if err != nil {
+		return eval.NewEmptyValue(), err
+	}
+	start, err := decimalArg(ec, args, 2, decimal.New(1, 0))
+	if err != nil {
+		return eval.NewEmptyValue(), err
+	}
+	step, err := decimalArg(ec, args, 3, decimal.New(1, 0))
+	if err != nil {
+		return eval.NewEmptyValue(), err
+	}
+	if rows < 1 || cols < 1 || rows*cols > maxArraySize {
+		return eval.NewEmptyValue(), eval.NewError(eval.ErrorKindFormula, "invalid array size %dx%d", rows, cols)
+	}
+	a := eval.NewArrayValueSized(cols, rows)
+	v := start
+	for y := 0; y < rows; y++ {
+		for x := 0; x < cols; x++ {
+			a.Set(x, y, eval.NewDecimalValue(v))
+			v = v.Add(step)
+		}
+	}
+	return a, nil
+}
+
+// TRANSPOSE [Lookup and reference] Returns the transpose of an array
+func transpose(ec *eval.Context, args []eval.Value) (eval.Value, error) {
+	a, err := arrayValue(ec, args[0])
+	if err != nil {
+		return eval.NewEmptyValue(), err
+	}
+	return transposeArray(a), nil
+}
+
+// SORT [Lookup and reference] Sorts the contents of a range or array
+func sort_(ec *eval.Context, args []eval.Value) (eval.Value, error) {
+	a, err := arrayValue(ec, args[0])
+	if err != nil {
+		return eval.NewEmptyValue(), err
+	}
+	idx, err := intArg(ec, args, 1, 1)
+	if err != nil {
+		return eval.NewEmptyValue(), err
+	}
+	order, err := intArg(ec, args, 2, 1)
+	if err != nil {
+		return eval.NewEmptyValue(), err
+	}
+	byCol, err := boolArg(ec, args, 3, false)
+	if err != nil {
+		return eval.NewEmptyValue(), err
+	}
+	if order != 1 && order != -1 {
+		return eval.NewEmptyValue(), eval.NewError(eval.ErrorKindFormula, "sort order must be 1 or -1")
+	}
+	if byCol {
+		a = transposeArray(a)
+	}
+	if idx < 1 || idx > a.Width() {
+		return eval.NewEmptyValue(), eval.NewError(eval.ErrorKindFormula, "sort index is out of range")
+	}
+	rows := make([][]eval.Value, a.Height())
+	for y := range rows {
+		rows[y] = a.Row(y)
+	}
+	var sortErr error
+	sort.SliceStable(rows, func(i, j int) bool {
+		res, err := eval.CompareValues(ec, rows[i][idx-1], rows[j][idx-1])
+		if err != nil {
+			sortErr = err
+		}
+		return res*order < 0
+	})
+	if sortErr != nil {
+		return eval.NewEmptyValue(), sortErr
+	}
+	res := eval.NewArrayValue(rows)
+	if byCol {
+		res = transposeArray(res)
+	}
+	return res, nil
+}
+
+// FILTER [Lookup and reference] Filters a range of data based on criteria you define
+func filter(ec *eval.Context, args []eval.Value) (eval.Value, error) {
+	a, err := arrayValue(ec, args[0])
+	if err != nil {
+		return eval.NewEmptyValue(), err
+	}
+	include, err := arrayValue(ec, args[1])
+	if err != nil {
+		return eval.NewEmptyValue(), err
+	}
+	byCol := false
+	if include.Width() == 1 && include.Height() == a.Height() {
+		include = transposeArray(include)
+	} else if include.Height() == 1 && include.Width() == a.Width() {
+		a = transposeArray(a)
+		byCol = true
+	} else {
+		return eval.NewEmptyValue(), eval.NewError(eval.ErrorKindFormula, "include array size does not match")
+	}
+	var rows [][]eval.Value
+	for y := 0; y < a.Height(); y++ {
+		ok, err := include.At(y, 0).BoolValue(ec)
+		if err != nil {
+			return eval.NewEmptyValue(), err
+		}
+		if ok {
+			rows = append(rows, a.Row(y))
+		}
+	}
+	if len(rows) == 0 {
+		if len(args) > 2 {
+			return args[2], nil
+		}
+		return eval.NewEmptyValue(), eval.NewError(eval.ErrorKindFormula, "no values satisfy the filter")
+	}
+	res := eval.NewArrayValue(rows)
+	if byCol {
+		res = transposeArray(res)
+	}
+	return res, nil
+}
+
+// UNIQUE [Lookup and reference] Returns a list of unique values in a list or range
+func unique(ec *eval.Context, args []eval.Value) (eval.Value, error) {
+	a, err := arrayValue(ec, args[0])
+	if err != nil {
+		return eval.NewEmptyValue(), err
+	}
+	byCol, err := boolArg(ec, args, 1, false)
+	if err != nil {
+		return eval.NewEmptyValue(), err
+	}
+	exactlyOnce, err := boolArg(ec, args, 2, false)
+	if err != nil {
+		return eval.NewEmptyValue(), err
+	}
+	if byCol {
+		a = transposeArray(a)
+	}
+	keys := make([]string, a.Height())
+	counts := make(map[string]int)
+	for y := range keys {
+		var key strings.Builder
+		for _, v := range a.Row(y) {
+			t, err := v.Type(ec)
+			if err != nil {
+				return eval.NewEmptyValue(), err
+			}
+			s, err := v.StringValue(ec)
+			if err != nil {
+				return eval.NewEmptyValue(), err
+			}
+			// comparison is case insensitive
+			key.WriteString(strconv.Itoa(t) + ":" + strconv.Quote(strings.ToLower(s)) + ";")
+		}
+		keys[y] = key.String()
+		counts[keys[y]]++
+	}
+	var rows [][]eval.Value
+	for y, key := range keys {
+		if exactlyOnce && counts[key] > 1 {
+			continue
+		}
+		if !exactlyOnce {
+			if counts[key] == 0 {
+				// already added
+				continue
+			}
+			counts[key] = 0
+		}
+		rows = append(rows, a.Row(y))
+	}
+	if len(rows) == 0 {
+		return eval.NewEmptyValue(), eval.NewError(eval.ErrorKindFormula, "no unique values")
+	}
+	res := eval.NewArrayValue(rows)
+	if byCol {
+		res = transposeArray(res)
+	}
+	return res, nil
+}
+
+// arrayValue turns ranges and single values into arrays.
+func arrayValue(ec *eval.Context, v eval.Value) (*eval.ArrayValue, error) {
+	switch v := v.(type) {
+	case *eval.ArrayValue:
+		return v, nil
+	case *eval.RangeRef:
+		return v.Array(ec)
+	}
+	sv, err := eval.NewStaticValue(ec, v)
+	if err != nil {
+		return nil, err
+	}
+	return eval.NewArrayValue([][]eval.Value{{sv}}), nil
+}
+
+// isArray checks if the value consists of multiple values.
+func isArray(v eval.Value) bool {
+	switch v.(type) {
+	case *eval.ArrayValue, *eval.RangeRef:
+		return true
+	}
+	return false
+}
+
+func transposeArray(a *eval.ArrayValue) *eval.ArrayValue {
+	res := eval.NewArrayValueSized(a.Height(), a.Width())
+	for y := 0; y < a.Height(); y++ {
+		for x := 0; x < a.Width(); x++ {
+			res.Set(y, x, a.At(x, y))
+		}
+	}
+	return res
+}
+
+// intArg returns Nth optional argument as integer.
+func intArg(ec *eval.Context, args []eval.Value, n int, def int) (int, error) {
+	if len(args) <= n {
+		return def, nil
+	}
+	d, err := args[n].DecimalValue(ec)
+	if err != nil {
+		return 0, err
+	}
+	return int(d.IntPart()), nil
+}
+
+// decimalArg returns Nth optional argument as decimal.
+func decimalArg(ec *eval.Context, args []eval.Value, n int, def decimal.Decimal) (decimal.Decimal, error) {
+	if len(args) <= n {
+		return def, nil
+	}
+	return args[n].DecimalValue(ec)
+}
+
+// boolArg returns Nth optional argument as bool.
+func boolArg(ec *eval.Context, args []eval.Value, n int, def bool) (bool, error) {
+	if len(args) <= n {
+		return def, nil
+	}
+	return args[n].BoolValue(ec)
+}
@@ -0,0 +1,56 @@
+package formula
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FunctionInfo describes a function available in formulas.
+type FunctionInfo struct {
+	Name        string
+	MinArgs     int
+	MaxArgs     int
+	Description string
+}
+
+// Functions returns functions which names start with the prefix (case-insensitive), sorted by name.
+func Functions(prefix string) []FunctionInfo {
+	prefix = strings.ToUpper(prefix)
+	var res []FunctionInfo
+	for name, f := range functions {
+		if strings.HasPrefix(name, prefix) {
+			res = append(res, FunctionInfo{name, f.MinArgs, f.MaxArgs, f.Description})
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Name < res[j].Name
+	})
+	return res
+}
+
+// FunctionByName returns description of the function.
+func FunctionByName(name string) (FunctionInfo, bool) {
+	name = strings.ToUpper(name)
+	f, ok := functions[name]
+	if !ok {
+		return FunctionInfo{}, false
+	}
+	return FunctionInfo{name, f.MinArgs, f.MaxArgs, f.Description}, true
+}
+
+// Signature returns function name with its arguments like SORT(arg1; [arg2]; [arg3]; [arg4]).
+func (f FunctionInfo) Signature() string {
+	args := make([]string, 0, f.MinArgs+1)
+	for i := 1; i <= f.MinArgs; i++ {
+		args = append(args, "arg"+strconv.Itoa(i))
+	}
+	if f.MaxArgs == maxArguments {
+		args = append(args, "...")
+	} else {
+		for i := f.MinArgs + 1; i <= f.MaxArgs; i++ {
+			args = append(args, "[arg"+strconv.Itoa(i)+"]")
+		}
+	}
+	return f.Name + "(" + strings.Join(args, "; ") + ")"
+}
@@ -9,6 +9,11 @@ import (
 )
 
 func evalOperator(ec *eval.Context, op string, args ...eval.Value) (eval.Value, error) {
+	for i := range args {
+		if isArray(args[i]) {
+			return evalArrayOperator(ec, op, args)
+		}
+	}
 	v := eval.NewEmptyValue()
 	t, err := args[0].Type(ec)
 	if err != nil {
@@ -52,6 +57,53 @@ func evalOperator(ec *eval.Context, op string, args ...eval.Value) (eval.Value,
 	return v, nil
 }
 
+// evalArrayOperator applies operator to arrays element by element.
+// Arrays consisting of single row or column are expanded to the size of others.
+func evalArrayOperator(ec *eval.Context, op string, args []eval.Value) (eval.Value, error) {
+	arrays := make([]*eval.ArrayValue, len(args))
+	width, height := 1, 1
+	for i := range args {
+		a, err := arrayValue(ec, args[i])
+		if err != nil {
+			return eval.NewEmptyValue(), err
+		}
+		if a.Width() > width {
+			width = a.Width()
+		}
+		if a.Height() > height {
+			height = a.Height()
+		}
+		arrays[i] = a
+	}
+	for _, a := range arrays {
+		if (a.Width() != 1 && a.Width() != width) || (a.Height() != 1 && a.Height() != height) {
+			return eval.NewEmptyValue(), eval.NewError(eval.ErrorKindFormula, "array sizes do not match")
+		}
+	}
+	res := eval.NewArrayValueSized(width, height)
+	elements := make([]eval.Value, len(arrays))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			for i, a := range arrays {
+				ax, ay := x, y
+				if a.Width() == 1 {
+					ax = 0
+				}
+				if a.Height() == 1 {
+					ay = 0
+				}
+				elements[i] = a.At(ax, ay)
+			}
+			v, err := evalOperator(ec, op, elements...)
+			if err != nil {
+				return eval.NewEmptyValue(), err
+			}
+			res.Set(x, y, v)
+		}
+	}
+	return res, nil
+}
+
 func evalBoolOperator(op string, args []bool) (eval.Value, error) {
 	switch op {
 	case "=":
@@ -5,6 +5,7 @@ import (
 
 	"bytes"
 	"strings"
+	"sync"
 
 	"github.com/alecthomas/participle"
 	"github.com/alecthomas/participle/lexer"
@@ -14,6 +15,7 @@ type Boolean bool
 type Sheet string
 type FuncName string
 type String string
+type Name string
 
 func (b *Boolean) Capture(values []string) error {
 	*b = Boolean(strings.EqualFold(values[0], "TRUE"))
@@ -38,6 +40,11 @@ func (f *FuncName) Capture(values []string) error {
 	return nil
 }
 
+func (n *Name) Capture(values []string) error {
+	*n = Name(values[0])
+	return nil
+}
+
 func (s *String) Capture(values []string) error {
 	// remove first and last char
 	values[0] = values[0][1 : len(values[0])-1]
@@ -99,18 +106,36 @@ type Primary struct {
 	Number        *float64  `| @Number`
 	String        *String   `| @String`
 	Boolean       *Boolean  `| @("TRUE" | "FALSE")`
+	Array         *Array    `| @@`
 	Func          *Func     `| @@`
 	Variable      *Variable `| @@`
 }
 
+// Array is an array constant like {1,2,3} (a row) or {1;2;3} (a column).
+type Array struct {
+	Rows []*ArrayRow `"{" @@ { ";" @@ } "}"`
+}
+
+type ArrayRow struct {
+	Items []*ArrayItem `@@ { "," @@ }`
+}
+
+type ArrayItem struct {
+	Minus   bool     `( [ @"-" ]`
+	Number  *float64 `  @Number )`
+	String  *String  `| @String`
+	Boolean *Boolean `| @("TRUE" | "FALSE")`
+}
+
 type Func struct {
 	Name      FuncName    `@FuncName`
 	Arguments []*Equality `[ @@ { ";" @@ } ] ")"`
 }
 
 type Variable struct {
-	Cell   *Cell `@@`
-	CellTo *Cell `[ ":" @@ ]`
+	Cell   *Cell `( @@`
+	CellTo *Cell `  [ ":" @@ ] )`
+	Name   *Name `| @Name`
 }
 
 type Cell struct {
@@ -121,31 +146,57 @@ type Cell struct {
 var lex = lexer.Must(lexer.Regexp(
 	`(\s+)` +
 		`|^=` +
-		`|(?P<Operators><>|<=|>=|[-+*/()=<>;:\^])` +
+		`|(?P<Operators><>|<=|>=|[-+*/()=<>;:\^{},])` +
 		`|(?P<Number>\d*\.?\d+([eE][-+]?\d+)?)` +
 		`|(?P<String>"([^"]|"")*")` +
-		`|(?P<Boolean>(?i)TRUE|FALSE)` +
+		`|(?P<Boolean>(?i:TRUE|FALSE)\b)` +
 		`|(?P<FuncName>[A-Za-z][A-Za-z0-9\.]+)\(` +
 		`|(?P<Sheet>[A-Za-z0-9_]+|'([^']|'')*')!` +
-		`|(?P<cell>\$?[A-Za-z]+\$?[1-9][0-9]*)`,
+		`|(?P<cell>\$?[A-Za-z]+\$?[1-9][0-9]*)` +
+		`|(?P<Name>[A-Za-z_][A-Za-z0-9_\.]*)`,
 ))
 
+var (
+	parser     *participle.Parser
+	parserErr  error
+	parserOnce sync.Once
+)
+
+// buildParser builds the grammar once. The parser keeps no state between
+// parsing calls, so it is safe to share between goroutines.
+func buildParser() (*participle.Parser, error) {
+	parserOnce.Do(func() {
+		parser, parserErr = participle.Build(
+			&Expression{},
+			participle.Lexer(lex),
+			participle.CaseInsensitive("Boolean"),
+			participle.Upper("cell"),
+		)
+	})
+	return parser, parserErr
+}
+
 // Parse parses the formula, extracts variables from it and builds
 // functions chain that perform the expression representing by the formula..
 func Parse(source string) (*Expression, error) {
-	// TODO: do that once
-	p, err := participle.Build(
-		&Expression{},
-		participle.Lexer(lex),
-		participle.CaseInsensitive("Boolean"),
-		participle.Upper("cell"),
-	)
+	p, err := buildParser()
 	if err != nil {
-		panic(err)
+		return nil, eval.NewError(eval.ErrorKindFormula, "formula grammar: %s", err.Error())
 	}
 	expression := &Expression{}
 	if err = p.ParseString(source, expression); err != nil {
-		return nil, eval.NewError(eval.ErrorKindFormula, err.Error())
+		return nil, newParseError(source, err)
 	}
 	return expression, nil
 }
+
+// IsName checks if given string can be used as a name of named range or constant.
+// The name must not look like a cell, a boolean or anything else but a single name.
+func IsName(s string) bool {
+	expr, err := Parse("=" + s)
+	if err != nil {
+		return false
+	}
+	vars := expr.Variables()
+	return len(vars) == 1 && vars[0].Name != nil && string(*vars[0].Name) == s && expr.String() == "="+s
+}
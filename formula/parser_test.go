@@ -3,6 +3,7 @@ package formula
 import (
 	"xl/document/eval"
 
+	"sync"
 	"testing"
 
 	"github.com/shopspring/decimal"
@@ -56,6 +57,20 @@ func TestParse(t *testing.T) {
 		{`=A1:B200+A1:C300`, "10", 2},
 		{`=$A$1:B$200+A$1:$C$300`, "10", 2},
 		{`='Sheet With Spaces'!A1:'Sheet With Spaces'!B200+Sheet2!A1:Sheet2!C300`, "10", 2},
+		{`=TaxRate*2`, "8", 1},
+		{`=SUM(Revenue; A1)`, "10", 2},
+		{`={1;2;3}`, "1", 0},
+		{`=SUM({1,2;3,4})`, "10", 0},
+		{`=SUM({1,-2,"";TRUE,FALSE,3})`, "3", 0},
+		{`=SUM({1;2}*{10,100})`, "330", 0},
+		{`=SUM(SEQUENCE(3))`, "6", 0},
+		{`=SUM(SEQUENCE(2; 3; 10; -1))`, "45", 0},
+		{`=TRANSPOSE({1;2})`, "1", 0},
+		{`=SUM(SORT({3;1;2})*{1;10;100})`, "321", 0},
+		{`=SUM(SORT({3;1;2}; 1; -1)*{1;10;100})`, "123", 0},
+		{`=SUM(FILTER({1;2;3}; {1;2;3}>1))`, "5", 0},
+		{`=SUM(UNIQUE({1;1;2}))`, "3", 0},
+		{`=SUM(UNIQUE({1;1;2}; FALSE; TRUE))`, "2", 0},
 	}
 	for _, c := range testCases {
 		expr, err := Parse(c.f)
@@ -76,19 +91,51 @@ func TestParse(t *testing.T) {
 	}
 }
 
+func TestIsName(t *testing.T) {
+	testCases := []struct {
+		name string
+		ok   bool
+	}{
+		{`TaxRate`, true},
+		{`Tax_Rate.2019`, true},
+		{`_total`, true},
+		{`A1`, false},
+		{`Q1Sales`, false},
+		{`TRUE`, false},
+		{`false`, false},
+		{`TrueCount`, true},
+		{`Falsey`, true},
+		{`Tax Rate`, false},
+		{`1st`, false},
+	}
+	for _, c := range testCases {
+		assert.Equalf(t, c.ok, IsName(c.name), "case %s", c.name)
+	}
+}
+
 func TestParseErrors(t *testing.T) {
 	testCases := []struct {
-		f   string
-		err string
+		f          string
+		err        string
+		offset     int
+		unexpected string
 	}{
-		{`=`, `<source>:1:1: unexpected "<EOF>" (expected <cell>)`},
-		{`=()`, `<source>:1:3: unexpected ")" (expected <cell>)`},
-		{`=1+`, `<source>:1:3: unexpected token "+"`},
+		{`=`, `unexpected end of formula, expected value`, 1, ""},
+		{`=()`, `unexpected ")", expected value`, 2, ")"},
+		{`=1+`, `unexpected end of formula, expected value`, 3, ""},
+		{`={1,2`, `unexpected end of formula, expected "}"`, 5, ""},
+		{`=1 2`, `unexpected "2"`, 3, "2"},
 	}
 	for _, c := range testCases {
 		_, err := Parse(c.f)
-		assert.Errorf(t, err, "case %s: must fail", c.f)
+		if !assert.Errorf(t, err, "case %s: must fail", c.f) {
+			continue
+		}
 		assert.Equalf(t, c.err, err.Error(), "case %s: must fail with reason '%s', actual '%s'", c.f, c.err, err.Error())
+		if pe, ok := err.(*ParseError); assert.Truef(t, ok, "case %s: must be a syntax error", c.f) {
+			assert.Equalf(t, c.offset, pe.Offset, "case %s: error offset", c.f)
+			assert.Equalf(t, c.unexpected, pe.Unexpected, "case %s: unexpected token", c.f)
+		}
 	}
 }
 
@@ -114,3 +161,98 @@ func TestExecuteErrors(t *testing.T) {
 		assert.Equalf(t, c.err, err.Error(), "case %s: must fail with reason '%s', actual '%s'", c.f, c.err, err.Error())
 	}
 }
+
+func TestParseConcurrent(t *testing.T) {
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				expr, err := Parse(`=SUM(A1:B2; 'Sheet 1'!C3)*2^-1+IF(TRUE; "a"; "b")`)
+				if assert.NoError(t, err) {
+					assert.Len(t, expr.Variables(), 2)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+var benchFormulas = []string{
+	`=1+2*3`,
+	`=A1*2`,
+	`=SUM(A1:Z100)`,
+	`=IF(AND(A1>0; B1<10); "yes"; "no")`,
+	`='Sheet 1'!A1+ROUND(B2/C3; 2)^2-{1,2;3,4}`,
+}
+
+func BenchmarkParse(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := Parse(benchFormulas[i%len(benchFormulas)]); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParseParallel(b *testing.B) {
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			if _, err := Parse(benchFormulas[i%len(benchFormulas)]); err != nil {
+				b.Fatal(err)
+			}
+			i++
+		}
+	})
+}
+
+func TestReferences(t *testing.T) {
+	testCases := []struct {
+		f    string
+		refs []Reference
+	}{
+		{`=1+2`, nil},
+		{`=a1+SUM(B2:c3`, []Reference{
+			{Offset: 1, Length: 2, CellFrom: "A1"},
+			{Offset: 8, Length: 5, CellFrom: "B2", CellTo: "C3"},
+		}},
+		{`='My sheet'!A1:B2*Sheet2!C3:`, []Reference{
+			{Offset: 1, Length: 16, Sheet: "My sheet", CellFrom: "A1", CellTo: "B2"},
+			{Offset: 18, Length: 9, Sheet: "Sheet2", CellFrom: "C3"},
+		}},
+		{`=A1&"B2`, []Reference{
+			{Offset: 1, Length: 2, CellFrom: "A1"},
+		}},
+	}
+	for _, c := range testCases {
+		assert.Equalf(t, c.refs, References(c.f), "case %s", c.f)
+	}
+}
+
+func TestFunctionSignature(t *testing.T) {
+	testCases := []struct {
+		name      string
+		signature string
+	}{
+		{"if", "IF(arg1; arg2; arg3)"},
+		{"SUM", "SUM(arg1; ...)"},
+		{"Sort", "SORT(arg1; [arg2]; [arg3]; [arg4])"},
+	}
+	for _, c := range testCases {
+		f, ok := FunctionByName(c.name)
+		if assert.Truef(t, ok, "case %s: function must exist", c.name) {
+			assert.Equalf(t, c.signature, f.Signature(), "case %s", c.name)
+		}
+	}
+	_, ok := FunctionByName("NOSUCHFUNC")
+	assert.False(t, ok)
+
+	var names []string
+	for _, f := range Functions("s") {
+		names = append(names, f.Name)
+	}
+	assert.Equal(t, []string{"SEQUENCE", "SORT", "SUM"}, names)
+}
@@ -0,0 +1,123 @@
+package formula
+
+import (
+	"strings"
+
+	"github.com/alecthomas/participle/lexer"
+)
+
+// Token is a piece of the formula source classified with one of OutputType* constants.
+type Token struct {
+	Offset int
+	Text   string
+	Type   int
+}
+
+// Reference is a cell or a range reference found in the formula source.
+type Reference struct {
+	// Offset and Length locate the reference in the source.
+	Offset int
+	Length int
+	// Sheet is empty for references to the current sheet.
+	Sheet    string
+	CellFrom string
+	// CellTo is empty for a single cell reference.
+	CellTo string
+}
+
+var operators = map[string]bool{
+	"+": true, "-": true, "*": true, "/": true, "^": true,
+	"=": true, "<>": true, "<": true, "<=": true, ">": true, ">=": true,
+}
+
+// Tokenize splits the formula source into tokens. Unlike Parse it accepts incomplete formulas,
+// the rest of the source which can not be recognized is returned as a single symbol.
+// Whitespaces are not returned.
+func Tokenize(source string) []Token {
+	var tokens []Token
+	l, err := lex.Lex(strings.NewReader(source))
+	if err != nil {
+		return nil
+	}
+	names := lexer.SymbolsByRune(lex)
+	for {
+		t, err := l.Next()
+		if err != nil {
+			if pe, ok := err.(*lexer.Error); ok && pe.Pos.Offset < len(source) {
+				tokens = append(tokens, Token{pe.Pos.Offset, source[pe.Pos.Offset:], OutputTypeSymbol})
+			}
+			return tokens
+		}
+		if t.EOF() {
+			return tokens
+		}
+		offset := t.Pos.Offset
+		switch names[t.Type] {
+		case "Number":
+			tokens = append(tokens, Token{offset, t.Value, OutputTypeNumber})
+		case "String":
+			tokens = append(tokens, Token{offset, t.Value, OutputTypeString})
+		case "Boolean":
+			tokens = append(tokens, Token{offset, t.Value, OutputTypeBoolean})
+		case "FuncName":
+			// the token includes opening parenthesis
+			n := len(t.Value) - 1
+			tokens = append(tokens, Token{offset, t.Value[:n], OutputTypeFunction}, Token{offset + n, "(", OutputTypeSymbol})
+		case "Sheet":
+			tokens = append(tokens, Token{offset, t.Value, OutputTypeSheet})
+		case "cell":
+			tokens = append(tokens, Token{offset, t.Value, OutputTypeCell})
+		case "Name":
+			tokens = append(tokens, Token{offset, t.Value, OutputTypeName})
+		default:
+			if operators[t.Value] && offset > 0 {
+				tokens = append(tokens, Token{offset, t.Value, OutputTypeOperator})
+			} else {
+				tokens = append(tokens, Token{offset, t.Value, OutputTypeSymbol})
+			}
+		}
+	}
+}
+
+// References finds cell and range references in the formula source, including incomplete formulas.
+func References(source string) []Reference {
+	var refs []Reference
+	tokens := Tokenize(source)
+	// cellAt reads optional sheet and a cell starting from i-th token
+	cellAt := func(i int) (sheet, cell string, next int) {
+		if i < len(tokens) && tokens[i].Type == OutputTypeSheet {
+			var s Sheet
+			_ = s.Capture([]string{tokens[i].Text})
+			sheet = string(s)
+			i++
+		}
+		if i < len(tokens) && tokens[i].Type == OutputTypeCell {
+			return sheet, strings.ToUpper(tokens[i].Text), i + 1
+		}
+		return "", "", -1
+	}
+	for i := 0; i < len(tokens); {
+		sheet, cell, next := cellAt(i)
+		if next < 0 {
+			i++
+			continue
+		}
+		ref := Reference{
+			Offset:   tokens[i].Offset,
+			Sheet:    sheet,
+			CellFrom: cell,
+		}
+		end := tokens[next-1].Offset + len(tokens[next-1].Text)
+		if next < len(tokens) && tokens[next].Text == ":" {
+			if _, cellTo, n := cellAt(next + 1); n >= 0 {
+				ref.CellTo = cellTo
+				end = tokens[n-1].Offset + len(tokens[n-1].Text)
+				next = n
+			}
+		}
+		ref.Length = end - ref.Offset
+		refs = append(refs, ref)
+		i = next
+	}
+	return refs
+}
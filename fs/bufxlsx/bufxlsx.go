@@ -2,12 +2,16 @@ package bufxlsx
 
 import (
 	"xl/document"
+	"xl/document/eval"
 	"xl/document/sheet"
+	"xl/formula"
 	"xl/fs"
 
-	"os"
+	"bytes"
+	"strings"
 
 	"github.com/360EntSecGroup-Skylar/excelize"
+	"github.com/shopspring/decimal"
 )
 
 type BufXLSX struct {
@@ -34,19 +38,39 @@ func (b *BufXLSX) Open() (*document.Document, error) {
 		if err != nil {
 			return nil, err
 		}
+		// excelize rounds displayed numbers to 15 digits, raw values keep them as written
+		raw, err := xlsx.GetRows(name, excelize.Options{RawCellValue: true})
+		if err != nil {
+			return nil, err
+		}
 
-		if len(data) == 0 || len(data[0]) == 0 {
+		// rows may have different lengths, like a merged title above a table
+		width, height := 0, len(data)
+		for _, row := range data {
+			if len(row) > width {
+				width = len(row)
+			}
+		}
+		if width == 0 {
 			continue
 		}
 
-		width, height := len(data[0]), len(data)
-
 		// make cells & transpose
-		cells := make([][]sheet.Cell, len(data[0]))
+		cells := make([][]sheet.Cell, width)
 		for x := 0; x < width; x++ {
 			cells[x] = make([]sheet.Cell, height)
 			for y := 0; y < height; y++ {
-				cells[x][y] = *sheet.NewCellUntyped(data[y][x])
+				v := ""
+				if x < len(data[y]) {
+					v = data[y][x]
+				}
+				if y < len(raw) && x < len(raw[y]) && raw[y][x] != v {
+					// raw booleans are 1 and 0
+					if t, err := xlsx.GetCellType(name, document.CellName(x, y)); err == nil && t != excelize.CellTypeBool {
+						v = raw[y][x]
+					}
+				}
+				cells[x][y] = *sheet.NewCellUntyped(v)
 			}
 		}
 
@@ -56,22 +80,668 @@ func (b *BufXLSX) Open() (*document.Document, error) {
 		}
 
 		s.AddStaticSegment(0, 0, width, height, cells)
+		if err := readOutline(xlsx, name, s); err != nil {
+			return nil, err
+		}
+		if err := readFormats(xlsx, name, s, width, height); err != nil {
+			return nil, err
+		}
+		if err := readFormulas(xlsx, name, s, width, height); err != nil {
+			return nil, err
+		}
+		if err := readMerges(xlsx, name, s); err != nil {
+			return nil, err
+		}
+		if err := readValidations(xlsx, name, s); err != nil {
+			return nil, err
+		}
+		if err := readNotes(xlsx, name, s); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, dn := range xlsx.GetDefinedName() {
+		// skip built-in names like print areas
+		if strings.HasPrefix(dn.Name, "_xlnm.") {
+			continue
+		}
+		// names which definitions are not supported (formulas, external references) are skipped
+		_ = d.SetName(dn.Name, dn.RefersTo)
 	}
 
 	return d, nil
 }
 
-// Write writes display values for the current sheet into CSV file.
+// Write writes all sheets of the document into XLSX file.
 func (b *BufXLSX) Write(doc *document.Document) error {
-	file, err := os.Create(b.filename)
+	xlsx := excelize.NewFile()
+	defaultSheet := xlsx.GetSheetName(0)
+
+	for i, s := range doc.Sheets {
+		var err error
+		if i == 0 {
+			err = xlsx.SetSheetName(defaultSheet, s.Title)
+		} else {
+			_, err = xlsx.NewSheet(s.Title)
+		}
+		if err != nil {
+			return err
+		}
+		ec := eval.NewContext(doc, s.Idx)
+		for _, segment := range s.Segments {
+			size := segment.Size()
+			for x := size.X; x <= size.MaxX(); x++ {
+				for y := size.Y; y <= size.MaxY(); y++ {
+					if err := writeCell(xlsx, s.Title, x, y, segment.Cell(x, y), ec); err != nil {
+						return err
+					}
+				}
+			}
+		}
+		if err := writeOutline(xlsx, s); err != nil {
+			return err
+		}
+		if err := writeFormats(xlsx, s); err != nil {
+			return err
+		}
+		for _, r := range s.Merges() {
+			err := xlsx.MergeCell(s.Title, document.CellName(r.X, r.Y), document.CellName(r.MaxX(), r.MaxY()))
+			if err != nil {
+				return err
+			}
+		}
+		if err := writeValidations(xlsx, s); err != nil {
+			return err
+		}
+		for _, n := range s.Notes() {
+			if err := xlsx.AddComment(s.Title, excelize.Comment{Cell: document.CellName(n.X, n.Y), Text: n.Text}); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, name := range doc.Names() {
+		def, err := doc.NameDefinition(name)
+		if err != nil {
+			return err
+		}
+		err = xlsx.SetDefinedName(&excelize.DefinedName{
+			Name:     name,
+			RefersTo: def,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return xlsx.SaveAs(b.filename)
+}
+
+// readOutline reads hidden state and group levels of rows and columns, including the ones without data.
+func readOutline(xlsx *excelize.File, sheetTitle string, s *sheet.Sheet) error {
+	// the number of rows having definitions, they may have no cells
+	rows, err := xlsx.Rows(sheetTitle)
 	if err != nil {
 		return err
 	}
-	defer func() {
-		_ = file.Close()
-	}()
+	height := 0
+	for rows.Next() {
+		height++
+	}
+	if err := rows.Close(); err != nil {
+		return err
+	}
+	for y := 0; y < height; y++ {
+		visible, err := xlsx.GetRowVisible(sheetTitle, y+1)
+		if err != nil {
+			return err
+		}
+		level, err := xlsx.GetRowOutlineLevel(sheetTitle, y+1)
+		if err != nil {
+			return err
+		}
+		s.Rows.SetHidden(y, !visible)
+		s.Rows.SetLevel(y, int(level))
+	}
+	// excelize does not list column definitions, so all the columns are checked
+	for x := 0; x < excelize.MaxColumns; x++ {
+		col := document.ColName(x)
+		visible, err := xlsx.GetColVisible(sheetTitle, col)
+		if err != nil {
+			return err
+		}
+		level, err := xlsx.GetColOutlineLevel(sheetTitle, col)
+		if err != nil {
+			return err
+		}
+		s.Cols.SetHidden(x, !visible)
+		s.Cols.SetLevel(x, int(level))
+	}
+	return nil
+}
 
-	// TODO
+// writeOutline writes hidden rows and columns and their group levels.
+func writeOutline(xlsx *excelize.File, s *sheet.Sheet) error {
+	for _, y := range s.Rows.HiddenItems() {
+		if err := xlsx.SetRowVisible(s.Title, y+1, false); err != nil {
+			return err
+		}
+	}
+	for _, y := range s.Rows.GroupedItems() {
+		if err := xlsx.SetRowOutlineLevel(s.Title, y+1, uint8(s.Rows.Level(y))); err != nil {
+			return err
+		}
+	}
+	for _, x := range s.Cols.HiddenItems() {
+		if err := xlsx.SetColVisible(s.Title, document.ColName(x), false); err != nil {
+			return err
+		}
+	}
+	for _, x := range s.Cols.GroupedItems() {
+		if err := xlsx.SetColOutlineLevel(s.Title, document.ColName(x), uint8(s.Cols.Level(x))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// builtInNumFmts are format codes of built-in number formats supported by the sheet.
+var builtInNumFmts = map[int]string{
+	1:  "0",
+	2:  "0.00",
+	3:  "#,##0",
+	4:  "#,##0.00",
+	9:  "0%",
+	10: "0.00%",
+	11: "0.00E+00",
+	14: "mm-dd-yy",
+	15: "d-mmm-yy",
+	16: "d-mmm",
+	17: "mmm-yy",
+	18: "h:mm AM/PM",
+	19: "h:mm:ss AM/PM",
+	20: "h:mm",
+	21: "h:mm:ss",
+	22: "m/d/yy h:mm",
+	37: "#,##0 ;(#,##0)",
+	38: "#,##0 ;[Red](#,##0)",
+	39: "#,##0.00;(#,##0.00)",
+	40: "#,##0.00;[Red](#,##0.00)",
+	45: "mm:ss",
+	46: "[h]:mm:ss",
+	48: "##0.0E+0",
+	49: "@",
+}
+
+// readMerges reads merged cells.
+func readMerges(xlsx *excelize.File, sheetTitle string, s *sheet.Sheet) error {
+	merges, err := xlsx.GetMergeCells(sheetTitle)
+	if err != nil {
+		return err
+	}
+	for _, m := range merges {
+		x1, y1, err := document.CellAxis(m.GetStartAxis())
+		if err != nil {
+			return err
+		}
+		x2, y2, err := document.CellAxis(m.GetEndAxis())
+		if err != nil {
+			return err
+		}
+		s.Merge(sheet.RectFromCorners(x1, y1, x2, y2))
+	}
+	return nil
+}
 
+// readNotes reads notes of cells. Notes made by Excel start with the author name, it is dropped.
+func readNotes(xlsx *excelize.File, sheetTitle string, s *sheet.Sheet) error {
+	comments, err := xlsx.GetComments(sheetTitle)
+	if err != nil {
+		return err
+	}
+	for _, c := range comments {
+		x, y, err := document.CellAxis(c.Cell)
+		if err != nil {
+			return err
+		}
+		text := c.Text
+		for _, run := range c.Paragraph {
+			text += run.Text
+		}
+		if c.Author != "" {
+			text = strings.TrimPrefix(text, c.Author+":")
+		}
+		s.SetNote(x, y, strings.TrimSpace(text))
+	}
 	return nil
 }
+
+// xlsxValidationKinds maps types of XLSX data validations to sheet.Validate* constants.
+var xlsxValidationKinds = map[string]int{
+	"list":    sheet.ValidateList,
+	"decimal": sheet.ValidateDecimal,
+	"whole":   sheet.ValidateWhole,
+	"date":    sheet.ValidateDate,
+	"custom":  sheet.ValidateFormula,
+}
+
+// xlsxValidationOps maps single operand operators of XLSX data validations to operators of conditions.
+var xlsxValidationOps = map[string]string{
+	"equal":              "=",
+	"notEqual":           "<>",
+	"greaterThan":        ">",
+	"greaterThanOrEqual": ">=",
+	"lessThan":           "<",
+	"lessThanOrEqual":    "<=",
+}
+
+// readValidations reads data validations. Validations of unsupported types or with operands referencing cells
+// are skipped.
+func readValidations(xlsx *excelize.File, sheetTitle string, s *sheet.Sheet) error {
+	dvs, err := xlsx.GetDataValidations(sheetTitle)
+	if err != nil {
+		return err
+	}
+	for _, dv := range dvs {
+		kind, ok := xlsxValidationKinds[dv.Type]
+		if !ok {
+			continue
+		}
+		v := sheet.Validation{Kind: kind}
+		// without the error alert invalid values are kept
+		if !dv.ShowErrorMessage || (dv.ErrorStyle != nil && *dv.ErrorStyle != "stop") {
+			v.Warning = true
+		}
+		if dv.Error != nil {
+			v.Message = *dv.Error
+		}
+		switch kind {
+		case sheet.ValidateList:
+			if l := len(dv.Formula1); l >= 2 && dv.Formula1[0] == '"' && dv.Formula1[l-1] == '"' {
+				v.Values = strings.Split(strings.ReplaceAll(dv.Formula1[1:l-1], `""`, `"`), ",")
+			} else {
+				v.Source = "=" + dv.Formula1
+			}
+		case sheet.ValidateFormula:
+			v.Formula = formulaFromExcel(dv.Formula1)
+		default:
+			if v.Conditions, ok = validationConditions(dv); !ok {
+				continue
+			}
+		}
+		for _, ref := range strings.Fields(dv.Sqref) {
+			parts := strings.SplitN(ref, ":", 2)
+			x1, y1, err := document.CellAxis(parts[0])
+			if err != nil {
+				return err
+			}
+			x2, y2 := x1, y1
+			if len(parts) == 2 {
+				if x2, y2, err = document.CellAxis(parts[1]); err != nil {
+					return err
+				}
+			}
+			rv := v
+			rv.Range = sheet.RectFromCorners(x1, y1, x2, y2)
+			s.AddValidation(&rv)
+		}
+	}
+	return nil
+}
+
+// validationConditions converts the operator and numeric operands of the data validation to conditions.
+func validationConditions(dv *excelize.DataValidation) ([]sheet.FilterCondition, bool) {
+	for _, f := range []string{dv.Formula1, dv.Formula2} {
+		if _, err := decimal.NewFromString(f); f != "" && err != nil {
+			return nil, false
+		}
+	}
+	switch dv.Operator {
+	case "", "between":
+		return []sheet.FilterCondition{{Op: ">=", Operand: dv.Formula1}, {Op: "<=", Operand: dv.Formula2}}, true
+	case "notBetween":
+		return nil, false
+	}
+	return []sheet.FilterCondition{{Op: xlsxValidationOps[dv.Operator], Operand: dv.Formula1}}, true
+}
+
+// formulaEscaper escapes formulas of data validations, excelize writes them into XML as is.
+var formulaEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// writeValidations writes data validations. Validations with several conditions other than a range of values
+// can not be expressed in XLSX and are left out.
+func writeValidations(xlsx *excelize.File, s *sheet.Sheet) error {
+	for _, v := range s.Validations {
+		dv := excelize.NewDataValidation(true)
+		dv.Sqref = document.CellName(v.Range.X, v.Range.Y)
+		if v.Range.Width > 1 || v.Range.Height > 1 {
+			dv.Sqref += ":" + document.CellName(v.Range.MaxX(), v.Range.MaxY())
+		}
+		switch v.Kind {
+		case sheet.ValidateList:
+			if v.Source != "" {
+				dv.SetSqrefDropList(formulaEscaper.Replace(strings.TrimPrefix(v.Source, "=")))
+			} else if err := dv.SetDropList(v.Values); err != nil {
+				return err
+			}
+		case sheet.ValidateFormula:
+			expr, err := formula.Parse(v.Formula)
+			if err != nil {
+				continue
+			}
+			dv.Type = "custom"
+			dv.Formula1 = formulaEscaper.Replace(excelFormula(expr))
+		default:
+			if !setValidationConditions(dv, v) {
+				continue
+			}
+		}
+		style := excelize.DataValidationErrorStyleStop
+		if v.Warning {
+			style = excelize.DataValidationErrorStyleWarning
+		}
+		dv.SetError(style, "", v.Message)
+		if err := xlsx.AddDataValidation(s.Title, dv); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setValidationConditions sets the type, the operator and operands of the data validation from the validation
+// of numbers or dates. Returns false if the conditions can not be expressed.
+func setValidationConditions(dv *excelize.DataValidation, v *sheet.Validation) bool {
+	for kind, k := range xlsxValidationKinds {
+		if k == v.Kind {
+			dv.Type = kind
+		}
+	}
+	operands := make([]string, len(v.Conditions))
+	for i, c := range v.Conditions {
+		n, err := decimal.NewFromString(c.Operand)
+		if v.Kind == sheet.ValidateDate {
+			var ok bool
+			if n, ok = document.ParseDateSerial(c.Operand); ok {
+				err = nil
+			}
+		}
+		if err != nil {
+			return false
+		}
+		operands[i] = n.String()
+	}
+	switch {
+	case len(v.Conditions) == 1:
+		for op, c := range xlsxValidationOps {
+			if c == v.Conditions[0].Op {
+				dv.Operator = op
+			}
+		}
+		dv.Formula1 = operands[0]
+		return true
+	case len(v.Conditions) == 2 && v.Conditions[0].Op == ">=" && v.Conditions[1].Op == "<=":
+		dv.Operator, dv.Formula1, dv.Formula2 = "between", operands[0], operands[1]
+		return true
+	case len(v.Conditions) == 2 && v.Conditions[0].Op == "<=" && v.Conditions[1].Op == ">=":
+		dv.Operator, dv.Formula1, dv.Formula2 = "between", operands[1], operands[0]
+		return true
+	}
+	return false
+}
+
+// readFormulas reads formulas of cells having data, they replace values calculated by Excel.
+func readFormulas(xlsx *excelize.File, sheetTitle string, s *sheet.Sheet, width, height int) error {
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			f, err := xlsx.GetCellFormula(sheetTitle, document.CellName(x, y))
+			if err != nil {
+				return err
+			}
+			if f != "" {
+				s.Cell(x, y).SetValueUntyped(formulaFromExcel(f))
+			}
+		}
+	}
+	return nil
+}
+
+// formulaFromExcel converts the formula in Excel notation to the one used in sheets: with leading '=' and
+// with ';' as arguments separator.
+func formulaFromExcel(f string) string {
+	var b strings.Builder
+	b.WriteByte('=')
+	quoted, arrayDepth := false, 0
+	for _, r := range f {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case quoted:
+		case r == '{':
+			arrayDepth++
+		case r == '}':
+			arrayDepth--
+		case r == ',' && arrayDepth == 0:
+			r = ';'
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// cellFormat is the number format and the style of a cell, XLSX keeps them together.
+type cellFormat struct {
+	code  string
+	style sheet.Style
+}
+
+// xlsxAligns maps horizontal alignments of XLSX to sheet.Align* constants.
+var xlsxAligns = map[string]int{
+	"left":   sheet.AlignLeft,
+	"center": sheet.AlignCenter,
+	"right":  sheet.AlignRight,
+}
+
+// formatReader returns a function giving number formats and styles of XLSX styles.
+func formatReader(xlsx *excelize.File) func(styleID int) (cellFormat, error) {
+	formats := make(map[int]cellFormat)
+	return func(styleID int) (cellFormat, error) {
+		if f, ok := formats[styleID]; ok {
+			return f, nil
+		}
+		style, err := xlsx.GetStyle(styleID)
+		if err != nil {
+			return cellFormat{}, err
+		}
+		f := cellFormat{code: builtInNumFmts[style.NumFmt]}
+		if style.CustomNumFmt != nil {
+			f.code = *style.CustomNumFmt
+		}
+		if style.Alignment != nil {
+			f.style.Align = xlsxAligns[style.Alignment.Horizontal]
+		}
+		if font := style.Font; font != nil {
+			f.style.Bold = font.Bold
+			f.style.Italic = font.Italic
+			f.style.Underline = font.Underline != "" && font.Underline != "none"
+			f.style.Fg = colorFromXLSX(font.Color)
+		}
+		if style.Fill.Type == "pattern" && style.Fill.Pattern == 1 && len(style.Fill.Color) > 0 {
+			f.style.Bg = colorFromXLSX(style.Fill.Color[0])
+		}
+		formats[styleID] = f
+		return f, nil
+	}
+}
+
+// readFormats reads number formats of columns, number formats and styles of cells having data.
+// Cells having number formats get raw values instead of the ones already formatted by excelize.
+func readFormats(xlsx *excelize.File, sheetTitle string, s *sheet.Sheet, width, height int) error {
+	format := formatReader(xlsx)
+	for x := 0; x < width; x++ {
+		styleID, err := xlsx.GetColStyle(sheetTitle, document.ColName(x))
+		if err != nil {
+			return err
+		}
+		colFormat, err := format(styleID)
+		if err != nil {
+			return err
+		}
+		s.SetColFormat(x, colFormat.code)
+		for y := 0; y < height; y++ {
+			axis := document.CellName(x, y)
+			styleID, err := xlsx.GetCellStyle(sheetTitle, axis)
+			if err != nil {
+				return err
+			}
+			f, err := format(styleID)
+			if err != nil {
+				return err
+			}
+			s.SetCellStyle(x, y, f.style)
+			c := s.Cell(x, y)
+			if f.code != colFormat.code {
+				c.SetFormat(f.code)
+			}
+			if f.code == "" || c.RawValue() == "" {
+				continue
+			}
+			if t, err := xlsx.GetCellType(sheetTitle, axis); err != nil || t == excelize.CellTypeBool {
+				continue
+			}
+			raw, err := xlsx.GetCellValue(sheetTitle, axis, excelize.Options{RawCellValue: true})
+			if err != nil {
+				return err
+			}
+			c.SetValueUntyped(raw)
+		}
+	}
+	return nil
+}
+
+// writeFormats writes number formats of columns, number formats and styles of cells.
+func writeFormats(xlsx *excelize.File, s *sheet.Sheet) error {
+	styles := make(map[cellFormat]int)
+	style := func(f cellFormat) (int, error) {
+		if id, ok := styles[f]; ok {
+			return id, nil
+		}
+		xs := &excelize.Style{}
+		if f.code != "" {
+			xs.CustomNumFmt = &f.code
+		}
+		for name, align := range xlsxAligns {
+			if f.style.Align == align {
+				xs.Alignment = &excelize.Alignment{Horizontal: name}
+			}
+		}
+		if f.style.Bold || f.style.Italic || f.style.Underline || f.style.Fg != "" {
+			xs.Font = &excelize.Font{Bold: f.style.Bold, Italic: f.style.Italic, Color: colorToXLSX(f.style.Fg)}
+			if f.style.Underline {
+				xs.Font.Underline = "single"
+			}
+		}
+		if f.style.Bg != "" {
+			xs.Fill = excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{colorToXLSX(f.style.Bg)}}
+		}
+		id, err := xlsx.NewStyle(xs)
+		styles[f] = id
+		return id, err
+	}
+	for _, x := range s.FormattedCols() {
+		id, err := style(cellFormat{code: s.ColFormat(x)})
+		if err != nil {
+			return err
+		}
+		if err := xlsx.SetColStyle(s.Title, document.ColName(x), id); err != nil {
+			return err
+		}
+	}
+	for _, segment := range s.Segments {
+		size := segment.Size()
+		for x := size.X; x <= size.MaxX(); x++ {
+			for y := size.Y; y <= size.MaxY(); y++ {
+				// cells in formatted columns need the column format too
+				f := cellFormat{code: segment.Cell(x, y).Format(), style: s.CellStyle(x, y)}
+				if f.code == "" {
+					f.code = s.ColFormat(x)
+				}
+				if f == (cellFormat{}) {
+					continue
+				}
+				id, err := style(f)
+				if err != nil {
+					return err
+				}
+				axis := document.CellName(x, y)
+				if err := xlsx.SetCellStyle(s.Title, axis, axis, id); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// colorFromXLSX converts the color like "FF8000" to "#ff8000".
+func colorFromXLSX(color string) string {
+	if len(color) != 6 {
+		return ""
+	}
+	return "#" + strings.ToLower(color)
+}
+
+// colorToXLSX converts the color like "#ff8000" to "FF8000".
+func colorToXLSX(color string) string {
+	return strings.ToUpper(strings.TrimPrefix(color, "#"))
+}
+
+// writeCell writes typed cell value or formula.
+func writeCell(xlsx *excelize.File, sheetTitle string, x, y int, c *sheet.Cell, ec *eval.Context) error {
+	if c.RawValue() == "" {
+		return nil
+	}
+	axis := document.CellName(x, y)
+	if expr := c.Expression(ec); expr != nil {
+		return xlsx.SetCellFormula(sheetTitle, axis, excelFormula(expr))
+	}
+	v, err := c.Value(ec)
+	if err != nil {
+		return xlsx.SetCellValue(sheetTitle, axis, c.RawValue())
+	}
+	t, _ := v.Type(ec)
+	switch t {
+	case eval.TypeDecimal:
+		// the number text keeps all the digits, float64 would round it
+		d, _ := v.DecimalValue(ec)
+		return xlsx.SetCellDefault(sheetTitle, axis, d.String())
+	case eval.TypeBool:
+		bv, _ := v.BoolValue(ec)
+		return xlsx.SetCellValue(sheetTitle, axis, bv)
+	}
+	return xlsx.SetCellValue(sheetTitle, axis, c.RawValue())
+}
+
+// excelFormula returns formula text in Excel notation: without leading '=' and with ',' as arguments separator.
+// Array constants are written as is.
+func excelFormula(expr *formula.Expression) string {
+	var buf bytes.Buffer
+	arrayDepth := 0
+	expr.Output(func(s string, t int) {
+		if t == formula.OutputTypeSymbol {
+			switch s {
+			case "=":
+				return
+			case "{":
+				arrayDepth++
+			case "}":
+				arrayDepth--
+			case ";":
+				if arrayDepth == 0 {
+					s = ","
+				}
+			}
+		}
+		buf.WriteString(s)
+	})
+	return buf.String()
+}
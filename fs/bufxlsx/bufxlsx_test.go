@@ -0,0 +1,119 @@
+package bufxlsx
+
+import (
+	"xl/document"
+	"xl/document/eval"
+	"xl/document/sheet"
+
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// roundTrip writes the document to a temporary file and reads it back.
+func roundTrip(t *testing.T, d *document.Document) *document.Document {
+	b := NewWithFilename(filepath.Join(t.TempDir(), "book.xlsx"))
+	require.NoError(t, b.Write(d))
+	read, err := b.Open()
+	require.NoError(t, err)
+	require.NotEmpty(t, read.Sheets)
+	// like the app does after opening a file
+	read.CurrentSheet = read.Sheets[0]
+	return read
+}
+
+func TestRoundTrip(t *testing.T) {
+	d := document.NewWithEmptySheet()
+	s := d.CurrentSheet
+	for name, v := range map[string]string{
+		"A1": "5",
+		"A2": "=A1*3",
+		"A3": "0.1234567890123456789",
+		"B1": "=SUM(A1:A2;TaxRate)",
+		"B2": `=IF(A1>1;"a, b";"c")`,
+		"B3": "text",
+		"C1": "TRUE",
+		"D4": "=Revenue",
+	} {
+		x, y, err := document.CellAxis(name)
+		require.NoError(t, err)
+		s.SetCell(x, y, sheet.NewCellUntyped(v))
+	}
+	require.NoError(t, d.SetName("TaxRate", "0.5"))
+	require.NoError(t, d.SetName("Revenue", "A1"))
+
+	read := roundTrip(t, d)
+	rs := read.Sheets[0]
+	ec := eval.NewContext(read, rs.Idx)
+	// formulas are written the way they are printed
+	for name, expected := range map[string]string{
+		"A1": "5",
+		"A2": "=A1*3",
+		"A3": "0.1234567890123456789",
+		"B1": "=SUM(A1:A2; TaxRate)",
+		"B2": `=IF(A1>1; "a, b"; "c")`,
+		"B3": "text",
+		"C1": "TRUE",
+		"D4": "=Revenue",
+	} {
+		x, y, err := document.CellAxis(name)
+		require.NoError(t, err)
+		if assert.NotNilf(t, rs.Cell(x, y), "cell %s", name) {
+			assert.Equalf(t, expected, rs.Cell(x, y).RawValue(), "cell %s", name)
+		}
+	}
+	assert.Equal(t, []string{"Revenue", "TaxRate"}, read.Names())
+	for _, name := range d.Names() {
+		expected, _ := d.NameDefinition(name)
+		def, err := read.NameDefinition(name)
+		assert.NoError(t, err)
+		assert.Equalf(t, expected, def, "name %s", name)
+	}
+	v, err := read.StringValue(ec, eval.Cell{SheetIdx: rs.Idx, X: 1, Y: 0})
+	assert.NoError(t, err)
+	assert.Equal(t, "20.5", v)
+}
+
+func TestRoundTripValidations(t *testing.T) {
+	d := document.NewWithEmptySheet()
+	s := d.CurrentSheet
+	validations := []*sheet.Validation{
+		{Range: sheet.Rect{X: 0, Y: 0, Width: 1, Height: 5}, Kind: sheet.ValidateFormula, Formula: "=D1<5"},
+		{Range: sheet.Rect{X: 1, Y: 0, Width: 1, Height: 5}, Kind: sheet.ValidateFormula, Formula: `=B1<>"a&b"`,
+			Warning: true, Message: "not a&b"},
+		{Range: sheet.Rect{X: 2, Y: 0, Width: 1, Height: 1}, Kind: sheet.ValidateList, Values: []string{"<none>", "R&D"}},
+		{Range: sheet.Rect{X: 3, Y: 0, Width: 2, Height: 2}, Kind: sheet.ValidateWhole,
+			Conditions: []sheet.FilterCondition{sheet.ParseFilterCondition(">=1"), sheet.ParseFilterCondition("<=10")}},
+	}
+	for _, v := range validations {
+		s.AddValidation(v)
+	}
+	s.SetCell(4, 1, sheet.NewCellUntyped("1"))
+
+	read := roundTrip(t, d)
+	assert.Equal(t, validations, read.Sheets[0].Validations)
+}
+
+func TestRoundTripOutline(t *testing.T) {
+	d := document.NewWithEmptySheet()
+	s := d.CurrentSheet
+	s.SetCell(1, 1, sheet.NewCellUntyped("1"))
+	// rows and columns outside of data are kept too
+	s.Rows.SetHidden(1, true)
+	s.Rows.SetHidden(6, true)
+	s.Rows.SetLevel(5, 1)
+	s.Rows.SetLevel(6, 2)
+	s.Cols.SetHidden(0, true)
+	s.Cols.SetLevel(5, 2)
+	s.Cols.SetHidden(7, true)
+
+	rs := roundTrip(t, d).Sheets[0]
+	assert.Equal(t, []int{1, 6}, rs.Rows.HiddenItems())
+	assert.Equal(t, []int{5, 6}, rs.Rows.GroupedItems())
+	assert.Equal(t, 2, rs.Rows.Level(6))
+	assert.Equal(t, []int{0, 7}, rs.Cols.HiddenItems())
+	assert.Equal(t, []int{5}, rs.Cols.GroupedItems())
+	assert.Equal(t, 2, rs.Cols.Level(5))
+}
@@ -18,3 +18,12 @@ type KeyEvent struct {
 	Key tcell.Key
 	Ch  rune
 }
+
+type MouseEvent struct {
+	InputEventInterface
+
+	X       int
+	Y       int
+	Buttons tcell.ButtonMask
+	Mod     tcell.ModMask
+}
@@ -19,6 +19,16 @@ const (
 	StatusFlagError = 1 << iota
 )
 
+const (
+	AreaNone = iota
+	AreaFormulaLine
+	AreaHRuler
+	AreaVRuler
+	AreaGrid
+	AreaSheetTab
+	AreaStatusLine
+)
+
 type DirtyFlag int
 
 type OutputInterface interface {
@@ -27,18 +37,38 @@ type OutputInterface interface {
 	ViewportHeight() int
 	ViewportWidth() int
 	SetDirty(DirtyFlag)
-	InputCommand() (string, error)
-	EditCellValue(string) (string, error)
+	// InputCommand reads a line in the status line after the prompt: ":" for commands, "/" or "?" for search.
+	InputCommand(prompt string) (string, error)
+	// EditCellValue edits the value placing cursor at given byte offset, negative offset means the end of the value.
+	// The flag tells whether editing has been cancelled.
+	EditCellValue(value string, cursorOffset int) (string, bool, error)
+	// PickValue lets user choose one of the values starting from the selected one, returns its index
+	// or -1 if the choice has been cancelled.
+	PickValue(values []string, selected int) (int, error)
 	SetStatus(string, int)
+	SetVimMode(enabled bool)
+	SetCommandHistory(history []string)
+	// ScreenArea tells what is drawn at the screen position.
+	ScreenArea(x, y int) ScreenArea
+	// TextWidth returns width in pixels of a column showing the text without truncation.
+	TextWidth(text string) int
 	Screen() tcell.Screen
 }
 
 type DataDelegateInterface interface {
 	DocView() *DocView
+	// SheetView describes the active window.
 	SheetView() *SheetView
-	CellView(x, y int) *CellView
-	RowView(n int) *RowView
-	ColView(n int) *ColView
+	WindowsView() *WindowsView
+	// CellView, RowView and ColView describe parts of the sheet with given index in DocView.Sheets.
+	CellView(sheet, x, y int) *CellView
+	RowView(sheet, n int) *RowView
+	ColView(sheet, n int) *ColView
+	FormulaRefs(source string) []FormulaRef
+	// CompleteCommand returns candidates to replace the command line from given byte offset.
+	CompleteCommand(line string) (int, []string)
+	// Interrupted is called with data of an interrupt event read while the editor or the list of values is open.
+	Interrupted(data interface{})
 }
 
 type CellView struct {
@@ -46,30 +76,88 @@ type CellView struct {
 	DisplayText string
 	Error       *string
 	Expression  *formula.Expression
+	// Cell is filled by array formula nearby and can not be edited.
+	ReadOnly bool
+	// Cell matches the search pattern.
+	Match bool
+	// Style has alignment resolved to left, center or right.
+	Style sheet.Style
+	// Merge is the area of merged cells the cell belongs to, it is empty if the cell is not merged.
+	Merge sheet.Rect
+	// Note is set if the cell has a note.
+	Note bool
 }
 
 type RowView struct {
 	Name   string
 	Height int
+	Hidden bool
 }
 
 type ColView struct {
-	Name  string
-	Width int
+	Name   string
+	Width  int
+	Hidden bool
+	// Column belongs to autofilter range, Filtered is set if it has filter criteria.
+	Filter   bool
+	Filtered bool
 }
 
 type SheetView struct {
+	// Sheet is an index in DocView.Sheets.
+	Sheet           int
 	Name            string
 	Cursor          sheet.Cursor
 	Viewport        sheet.Viewport
+	Selection       sheet.Rect
 	FormulaLineView FormulaLineView
 }
 
+// WindowsView lists windows showing sheets in parts of the grid area, the active one has the cursor.
+type WindowsView struct {
+	Windows []*SheetView
+	Active  int
+	Layout  *WindowLayout
+}
+
+// WindowLayout divides the area between windows. Leaves refer to Windows by index, nodes place their children
+// side by side if Vertical is set, otherwise one above another.
+type WindowLayout struct {
+	Window   int
+	Vertical bool
+	Children []*WindowLayout
+}
+
 type FormulaLineView struct {
 	DisplayText string
 	Expression  *formula.Expression
 }
 
+// FormulaRef is a cell or a range referenced by the formula being edited.
+type FormulaRef struct {
+	// Offset and Length locate the reference in the formula source (in bytes).
+	Offset int
+	Length int
+	// Cells range, valid only if the reference points to the current sheet.
+	CurrentSheet bool
+	X, Y         int
+	X2, Y2       int
+}
+
+// ScreenArea is a part of the screen found by position, one of Area* kinds.
+type ScreenArea struct {
+	Kind int
+	// Column and row of a cell in the grid, column in the horizontal ruler, row in the vertical ruler.
+	X, Y int
+	// Width of the column in chars and whether the position is at its right border, for the horizontal ruler.
+	Width     int
+	ColBorder bool
+	// Index of the sheet for sheet tabs.
+	Sheet int
+	// Index of the window for rulers and the grid.
+	Window int
+}
+
 type DocView struct {
 	Sheets          []string
 	CurrentSheetIdx int
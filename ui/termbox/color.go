@@ -12,4 +12,26 @@ var (
 	colorBlack   = tcell.ColorBlack
 	colorGrey236 = tcell.Color236
 	colorGrey239 = tcell.Color239
+	colorSpilled = tcell.ColorLightSteelBlue
+
+	colorSelection = tcell.ColorNavy
+	colorMatch     = tcell.ColorDarkOliveGreen
+
+	// formula syntax highlighting
+	colorNumber   = tcell.ColorLightGreen
+	colorString   = tcell.ColorKhaki
+	colorFunction = tcell.ColorDeepSkyBlue
+	colorCellRef  = tcell.ColorOrange
+	colorName     = tcell.ColorPlum
+	colorHint     = tcell.ColorLightYellow
+
+	// references highlighted while editing formula
+	colorRefs = []tcell.Color{
+		tcell.ColorDodgerBlue,
+		tcell.ColorOrangeRed,
+		tcell.ColorMediumOrchid,
+		tcell.ColorLimeGreen,
+		tcell.ColorGold,
+		tcell.ColorHotPink,
+	}
 )
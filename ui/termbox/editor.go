@@ -4,31 +4,48 @@ import (
 	"xl/ui"
 
 	"errors"
+	"strings"
+	"unicode"
 	"unicode/utf8"
 
 	"github.com/gdamore/tcell"
 )
 
-func (t *Termbox) enterEditorMode(config *editorConfig) (string, error) {
+// killRingSize is a number of killed texts remembered for yanking.
+const killRingSize = 20
+
+// enterEditorMode edits the text until Enter or Esc, the flag tells whether editing has been cancelled.
+func (t *Termbox) enterEditorMode(config *editorConfig) (string, bool, error) {
 	defer func() {
+		t.setFormulaRefs(nil)
+		t.SetDirty(ui.DirtyHRuler | ui.DirtyVRuler | ui.DirtyGrid)
 		t.screen.ShowCursor(t.lastCursorX, t.lastCursorY)
 	}()
 	e := newEditor(config)
 	for {
 		event, err := t.ReadKey()
 		if err != nil {
-			return "", err
+			return "", false, err
 		}
-		if keyEvent, ok := event.(ui.KeyEvent); ok {
-			stop := e.OnKey(keyEvent)
-			if stop {
-				break
+		switch ev := event.(type) {
+		case ui.KeyEvent:
+			if e.OnKey(ev) {
+				return e.result(), e.cancelled, nil
 			}
-		} else {
-			return "", errors.New("unknown event")
+		case ui.MouseEvent, nil:
+			// mouse is not used by editor, other events have been handled by ReadKey
+		default:
+			return "", false, errors.New("unknown event")
 		}
 	}
-	return e.Text(), nil
+}
+
+// result returns the edited text, or the original value if editing has been cancelled.
+func (e *editor) result() string {
+	if e.cancelled {
+		return e.config.Value
+	}
+	return e.Text()
 }
 
 type ResizeEventDelegateInterface interface {
@@ -36,17 +53,29 @@ type ResizeEventDelegateInterface interface {
 }
 
 type editorConfig struct {
-	Tbox                *Termbox
-	X                   int
-	Y                   int
-	Width               int
-	Height              int
+	Tbox   *Termbox
+	X      int
+	Y      int
+	Width  int
+	Height int
+	// MaxHeight is a number of lines the editor may grow to showing multi-line values.
+	MaxHeight           int
 	MaxRunes            int
 	MaxLines            int
 	ResizeEventDelegate ResizeEventDelegateInterface
 	FgColor             tcell.Color
 	BgColor             tcell.Color
 	Value               string
+	// Formula enables syntax highlighting and highlighting of referenced cells.
+	Formula bool
+	// Cursor is a byte offset of the cursor in Value, negative means the end of Value.
+	Cursor int
+	// Vim enables vim-like modal editing.
+	Vim bool
+	// History is navigated with Up and Down keys.
+	History []string
+	// Complete returns candidates to replace the text from given byte offset, called on Tab.
+	Complete func(text string) (int, []string)
 }
 
 type line struct {
@@ -62,8 +91,29 @@ type cursor struct {
 }
 
 type window struct {
-	topLine   *line
+	// index of the first visible line
+	topLine   int
 	firstRune int
+	height    int
+}
+
+// Kinds of editing actions, used to merge consecutive actions for undo and kill ring.
+const (
+	actionNone = iota
+	actionMove
+	actionInsert
+	actionDelete
+	actionKill
+	actionYank
+	actionUndo
+	// insertion of a reference or a function name while editing formula
+	actionComplete
+)
+
+// snapshot is a state of the editor saved for undo.
+type snapshot struct {
+	text   string
+	offset int
 }
 
 type editor struct {
@@ -71,94 +121,172 @@ type editor struct {
 	cursor     cursor
 	firstLine  *line
 	lastLine   *line
-	topLine    *line
 	linesCount int
 	window     window
+	cancelled  bool
+
+	lastAction int
+	undo       []snapshot
+	// location of the last yanked text to be replaced by yank-pop
+	yankStart   int
+	yankEnd     int
+	yankRingIdx int
+
+	// formula editing state
+	point       pointMode
+	completions []string
+	lastHint    string
+
+	vim vimState
+
+	// position in history and the text typed before navigating the history
+	historyIdx   int
+	historyDraft string
 }
 
 func newEditor(config *editorConfig) *editor {
-	l := line{
-		// FIXME: assuming single line
-		data: []byte(config.Value),
-	}
 	e := &editor{
 		config: config,
-		cursor: cursor{
-			line:        &l,
-			offsetRunes: utf8.RuneCountInString(config.Value),
-			offsetBytes: len(config.Value),
-		},
 		window: window{
-			topLine:   &l,
-			firstRune: 0,
+			height: config.Height,
 		},
-		firstLine:  &l,
-		lastLine:   &l,
-		linesCount: 1,
+		historyIdx: len(config.History),
+	}
+	offset := config.Cursor
+	if offset < 0 || offset > len(config.Value) {
+		offset = len(config.Value)
 	}
+	e.setText(config.Value, offset)
 	e.redraw()
 	return e
 }
 
 func (e *editor) OnKey(ev ui.KeyEvent) bool {
+	e.completions = nil
+	if e.config.Vim {
+		handled, stop := e.onVimKey(ev)
+		if stop {
+			return true
+		}
+		if handled {
+			e.redraw()
+			return false
+		}
+	}
+	if e.config.History != nil && (ev.Key == tcell.KeyUp || ev.Key == tcell.KeyDown) {
+		e.navigateHistory(ev.Key == tcell.KeyUp)
+		e.lastAction = actionNone
+		e.redraw()
+		return false
+	}
+	if e.config.Complete != nil && ev.Key == tcell.KeyTab {
+		start, candidates := e.config.Complete(e.Text()[:e.offset()])
+		e.complete(start, candidates)
+		e.lastAction = actionComplete
+		e.redraw()
+		return false
+	}
+	if e.onFormulaKey(ev) {
+		e.lastAction = actionComplete
+		e.redraw()
+		return false
+	}
+	action := actionMove
 	switch ev.Key {
 	case tcell.KeyCtrlF, tcell.KeyRight:
-		e.moveCursorForward()
+		if ev.Mod&tcell.ModCtrl != 0 {
+			e.moveCursorWordForward()
+		} else {
+			e.moveCursorForward()
+		}
 	case tcell.KeyCtrlB, tcell.KeyLeft:
-		e.moveCursorBackward()
+		if ev.Mod&tcell.ModCtrl != 0 {
+			e.moveCursorWordBackward()
+		} else {
+			e.moveCursorBackward()
+		}
 	case tcell.KeyCtrlN, tcell.KeyDown:
 		e.moveCursorNextLine()
 	case tcell.KeyCtrlP, tcell.KeyUp:
 		e.moveCursorPrevLine()
 	case tcell.KeyCtrlE, tcell.KeyEnd:
-		//e.moveCursorEOL()
-		//v.on_vcommand(vcommand_move_cursor_end_of_line, 0)
+		e.moveCursorEOL()
 	case tcell.KeyCtrlA, tcell.KeyHome:
-		//e.moveCursorBOL()
-		//v.on_vcommand(vcommand_move_cursor_beginning_of_line, 0)
+		e.moveCursorBOL()
 	case tcell.KeyCtrlV, tcell.KeyPgDn:
-		//v.on_vcommand(vcommand_move_view_half_forward, 0)
-	case tcell.KeyCtrlL:
-		//v.on_vcommand(vcommand_recenter, 0)
-	//case termbox.KeyCtrlSlash:
-	//v.on_vcommand(vcommand_undo, 0)
+		for i := 0; i < e.window.height; i++ {
+			e.moveCursorNextLine()
+		}
+	case tcell.KeyPgUp:
+		for i := 0; i < e.window.height; i++ {
+			e.moveCursorPrevLine()
+		}
+	case tcell.KeyCtrlZ, tcell.KeyCtrlUnderscore:
+		e.undoLast()
+		action = actionUndo
 	case tcell.KeyEnter, tcell.KeyCtrlJ:
-		if e.config.MaxLines <= 1 {
-			// exit editor when in single-line mode
+		if ev.Key == tcell.KeyEnter && ev.Mod&tcell.ModAlt == 0 {
 			return true
-		} else if e.linesCount < e.config.MaxLines {
+		}
+		// Alt-Enter or Ctrl-J inserts a line break
+		if e.linesCount < e.config.MaxLines {
+			e.saveUndo(actionInsert)
 			e.insertRune('\n')
+			action = actionInsert
 		}
 	case tcell.KeyBackspace, tcell.KeyBackspace2:
 		if ev.Mod&tcell.ModAlt != 0 {
-			//e.deleteWordBackward()
+			e.killWordBackward()
+			action = actionKill
 		} else {
+			e.saveUndo(actionDelete)
 			e.deleteRuneBackward()
+			action = actionDelete
 		}
 	case tcell.KeyDelete, tcell.KeyCtrlD:
+		e.saveUndo(actionDelete)
 		e.deleteRune()
+		action = actionDelete
 	case tcell.KeyCtrlK:
-		//v.on_vcommand(vcommand_kill_line, 0)
-	case tcell.KeyPgUp:
-		//v.on_vcommand(vcommand_move_view_half_backward, 0)
-	case tcell.KeyTab:
-		e.insertRune('\t')
-	// case tcell.KeyCtrlSpace:
-	// 	if ev.Ch == 0 {
-	// 		v.set_mark()
-	// 	}
+		e.killLine()
+		action = actionKill
+	case tcell.KeyCtrlU:
+		e.killToBOL()
+		action = actionKill
 	case tcell.KeyCtrlW:
-		//v.on_vcommand(vcommand_kill_region, 0)
+		e.killWordBackward()
+		action = actionKill
 	case tcell.KeyCtrlY:
-		//v.on_vcommand(vcommand_yank, 0)
+		e.yank()
+		action = actionYank
+	case tcell.KeyTab:
+		// Tab only completes, a tab character in a cell value would break the grid
+		action = e.lastAction
 	case tcell.KeyEsc:
-		// edit editor, discard changes
+		// exit editor, discard changes
+		e.cancelled = true
 		return true
 	default:
-		if ev.Ch != 0 {
+		if ev.Mod&tcell.ModAlt != 0 {
+			switch ev.Ch {
+			case 'f':
+				e.moveCursorWordForward()
+			case 'b':
+				e.moveCursorWordBackward()
+			case 'd':
+				e.killWordForward()
+				action = actionKill
+			case 'y':
+				e.yankPop()
+				action = actionYank
+			}
+		} else if ev.Ch != 0 {
+			e.saveUndo(actionInsert)
 			e.insertRune(ev.Ch)
+			action = actionInsert
 		}
 	}
+	e.lastAction = action
 
 	e.redraw()
 
@@ -166,8 +294,67 @@ func (e *editor) OnKey(ev ui.KeyEvent) bool {
 }
 
 func (e *editor) Text() string {
-	// FIXME: assuming single line
-	return string(e.firstLine.data)
+	var buf strings.Builder
+	for l := e.firstLine; l != nil; l = l.next {
+		if l != e.firstLine {
+			buf.WriteByte('\n')
+		}
+		buf.Write(l.data)
+	}
+	return buf.String()
+}
+
+// offset returns cursor position as byte offset in the text.
+func (e *editor) offset() int {
+	offset := 0
+	for l := e.firstLine; l != e.cursor.line; l = l.next {
+		offset += len(l.data) + 1
+	}
+	return offset + e.cursor.offsetBytes
+}
+
+// setText replaces the whole text of the editor and puts the cursor at given byte offset.
+func (e *editor) setText(text string, offset int) {
+	e.firstLine, e.lastLine = nil, nil
+	e.linesCount = 0
+	for _, s := range strings.Split(text, "\n") {
+		l := &line{
+			data: []byte(s),
+			prev: e.lastLine,
+		}
+		if e.lastLine != nil {
+			e.lastLine.next = l
+		} else {
+			e.firstLine = l
+		}
+		e.lastLine = l
+		e.linesCount++
+	}
+	e.setOffset(offset)
+}
+
+// setOffset moves cursor to given byte offset in the text.
+func (e *editor) setOffset(offset int) {
+	l := e.firstLine
+	for l.next != nil && offset > len(l.data) {
+		offset -= len(l.data) + 1
+		l = l.next
+	}
+	if offset > len(l.data) {
+		offset = len(l.data)
+	}
+	e.cursor = cursor{
+		line:        l,
+		offsetBytes: offset,
+		offsetRunes: utf8.RuneCount(l.data[:offset]),
+	}
+	e.adjustWindow()
+}
+
+// replaceText replaces text between start and end byte offsets with s and puts the cursor after it.
+func (e *editor) replaceText(start, end int, s string) {
+	text := e.Text()
+	e.setText(text[:start]+s+text[end:], start+len(s))
 }
 
 // insertRune inserts a rune 'r' at the current cursor position,
@@ -195,10 +382,13 @@ func (e *editor) deleteRune() {
 			return
 		}
 		// If cursor at end of line, connect next line to the end of current line.
-		line.data = append(line.data, line.next.data...)
+		next := line.next
+		line.data = append(line.data, next.data...)
+		line.next = next.next
 		if line.next != nil {
 			line.next.prev = line
-			line.next = line.next.next
+		} else {
+			e.lastLine = line
 		}
 		e.linesCount--
 		e.adjustWindow()
@@ -218,11 +408,13 @@ func (e *editor) deleteRuneBackward() {
 		}
 		// If cursor at beginning of line, connects current line to the end of previous.
 		e.cursor.offsetBytes = len(line.prev.data)
-		e.cursor.offsetRunes = utf8.RuneCountInString(string(line.prev.data))
+		e.cursor.offsetRunes = utf8.RuneCount(line.prev.data)
 		line.prev.data = append(line.prev.data, line.data...)
 		line.prev.next = line.next
 		if line.next != nil {
 			line.next.prev = line.prev
+		} else {
+			e.lastLine = line.prev
 		}
 		e.cursor.line = line.prev
 		e.linesCount--
@@ -262,7 +454,7 @@ func (e *editor) moveCursorBackward() {
 		}
 		e.cursor.line = line.prev
 		e.cursor.offsetBytes = len(line.prev.data)
-		e.cursor.offsetRunes = utf8.RuneCountInString(string(line.prev.data))
+		e.cursor.offsetRunes = utf8.RuneCount(line.prev.data)
 		e.adjustWindow()
 		return
 	}
@@ -277,7 +469,7 @@ func (e *editor) moveCursorNextLine() {
 	if line.next == nil {
 		return
 	}
-	runesLen := utf8.RuneCountInString(string(line.next.data))
+	runesLen := utf8.RuneCount(line.next.data)
 	if runesLen < e.cursor.offsetRunes {
 		e.cursor.offsetRunes = runesLen
 	}
@@ -292,7 +484,7 @@ func (e *editor) moveCursorPrevLine() {
 	if line.prev == nil {
 		return
 	}
-	runesLen := utf8.RuneCountInString(string(line.prev.data))
+	runesLen := utf8.RuneCount(line.prev.data)
 	if runesLen < e.cursor.offsetRunes {
 		e.cursor.offsetRunes = runesLen
 	}
@@ -302,6 +494,187 @@ func (e *editor) moveCursorPrevLine() {
 	e.adjustWindow()
 }
 
+// moveCursorBOL moves cursor to the beginning of line.
+func (e *editor) moveCursorBOL() {
+	e.cursor.offsetBytes = 0
+	e.cursor.offsetRunes = 0
+	e.adjustWindow()
+}
+
+// moveCursorEOL moves cursor to the end of line.
+func (e *editor) moveCursorEOL() {
+	e.cursor.offsetBytes = len(e.cursor.line.data)
+	e.cursor.offsetRunes = utf8.RuneCount(e.cursor.line.data)
+	e.adjustWindow()
+}
+
+// moveCursorWordForward moves cursor to the end of the current or the next word.
+func (e *editor) moveCursorWordForward() {
+	e.setOffset(wordEnd(e.Text(), e.offset()))
+}
+
+// moveCursorWordBackward moves cursor to the beginning of the current or the previous word.
+func (e *editor) moveCursorWordBackward() {
+	e.setOffset(wordStart(e.Text(), e.offset()))
+}
+
+// killLine kills text up to the end of line. At the end of line kills the line break.
+func (e *editor) killLine() {
+	offset := e.offset()
+	end := offset + len(e.cursor.line.data) - e.cursor.offsetBytes
+	if e.eol() && !e.eof() {
+		end++
+	}
+	e.kill(offset, end, false)
+}
+
+// killToBOL kills text from the beginning of line up to the cursor.
+func (e *editor) killToBOL() {
+	offset := e.offset()
+	e.kill(offset-e.cursor.offsetBytes, offset, true)
+}
+
+// killWordForward kills text up to the end of the word.
+func (e *editor) killWordForward() {
+	offset := e.offset()
+	e.kill(offset, wordEnd(e.Text(), offset), false)
+}
+
+// killWordBackward kills text from the beginning of the word up to the cursor.
+func (e *editor) killWordBackward() {
+	offset := e.offset()
+	e.kill(wordStart(e.Text(), offset), offset, true)
+}
+
+// kill removes text between start and end offsets and puts it into the kill ring.
+// Consecutive kills are joined, prepend is set for kills made backward.
+func (e *editor) kill(start, end int, prepend bool) {
+	if start == end {
+		return
+	}
+	e.saveUndo(actionKill)
+	t := e.config.Tbox
+	killed := e.Text()[start:end]
+	if e.lastAction == actionKill && len(t.killRing) > 0 {
+		top := len(t.killRing) - 1
+		if prepend {
+			t.killRing[top] = killed + t.killRing[top]
+		} else {
+			t.killRing[top] += killed
+		}
+	} else {
+		t.killRing = append(t.killRing, killed)
+		if len(t.killRing) > killRingSize {
+			t.killRing = t.killRing[1:]
+		}
+	}
+	e.replaceText(start, end, "")
+}
+
+// yank inserts the last killed text.
+func (e *editor) yank() {
+	t := e.config.Tbox
+	if len(t.killRing) == 0 {
+		return
+	}
+	e.saveUndo(actionYank)
+	e.yankRingIdx = len(t.killRing) - 1
+	e.yankStart = e.offset()
+	s := t.killRing[e.yankRingIdx]
+	e.replaceText(e.yankStart, e.yankStart, s)
+	e.yankEnd = e.yankStart + len(s)
+}
+
+// yankPop replaces just yanked text with the previous one from the kill ring.
+func (e *editor) yankPop() {
+	t := e.config.Tbox
+	if e.lastAction != actionYank || len(t.killRing) == 0 {
+		return
+	}
+	e.yankRingIdx--
+	if e.yankRingIdx < 0 {
+		e.yankRingIdx = len(t.killRing) - 1
+	}
+	s := t.killRing[e.yankRingIdx]
+	e.replaceText(e.yankStart, e.yankEnd, s)
+	e.yankEnd = e.yankStart + len(s)
+}
+
+// navigateHistory replaces the text with previous (or next) history entry starting with the text
+// typed before navigation started.
+func (e *editor) navigateHistory(back bool) {
+	if e.historyIdx == len(e.config.History) {
+		e.historyDraft = e.Text()
+	}
+	for i := e.historyIdx; ; {
+		if back {
+			i--
+		} else {
+			i++
+		}
+		if i < 0 {
+			return
+		}
+		if i >= len(e.config.History) {
+			e.historyIdx = len(e.config.History)
+			e.setText(e.historyDraft, len(e.historyDraft))
+			return
+		}
+		if strings.HasPrefix(e.config.History[i], e.historyDraft) {
+			e.historyIdx = i
+			e.setText(e.config.History[i], len(e.config.History[i]))
+			return
+		}
+	}
+}
+
+// complete replaces the text from start up to the cursor with the only candidate
+// or with common prefix of candidates showing them in the hint.
+func (e *editor) complete(start int, candidates []string) {
+	offset := e.offset()
+	switch len(candidates) {
+	case 0:
+		return
+	case 1:
+		c := candidates[0]
+		if !strings.HasSuffix(c, "/") && !strings.HasSuffix(c, `/"`) {
+			c += " "
+		}
+		e.saveUndo(actionComplete)
+		e.replaceText(start, offset, c)
+		return
+	}
+	e.completions = candidates
+	if prefix := commonPrefix(candidates); len(prefix) > offset-start {
+		e.saveUndo(actionComplete)
+		e.replaceText(start, offset, prefix)
+	}
+}
+
+// saveUndo remembers the current state before the change. Consecutive changes of the same kind
+// (like typing a word) are undone at once.
+func (e *editor) saveUndo(action int) {
+	if action == e.lastAction && (action == actionInsert || action == actionDelete || action == actionKill) {
+		return
+	}
+	s := snapshot{e.Text(), e.offset()}
+	if n := len(e.undo); n > 0 && e.undo[n-1].text == s.text {
+		return
+	}
+	e.undo = append(e.undo, s)
+}
+
+// undoLast restores the state before the last change.
+func (e *editor) undoLast() {
+	n := len(e.undo)
+	if n == 0 {
+		return
+	}
+	s := e.undo[n-1]
+	e.undo = e.undo[:n-1]
+	e.setText(s.text, s.offset)
+}
+
 // bol is true if cursor at beginning of line
 func (e *editor) bol() bool {
 	return e.cursor.offsetBytes == 0
@@ -342,6 +715,8 @@ func (e *editor) insertLine() {
 	current.next = &newLine
 	if newLine.next != nil {
 		newLine.next.prev = &newLine
+	} else {
+		e.lastLine = &newLine
 	}
 
 	// move cursor
@@ -353,34 +728,177 @@ func (e *editor) insertLine() {
 }
 
 func (e *editor) redraw() {
-	y := e.config.Y
-	line := e.window.topLine
-	for y-e.config.Y < e.config.Height {
-		text := ""
-		if line != nil && e.window.firstRune < len(string(line.data)) {
-			text = string(line.data)[e.window.firstRune:]
-		}
-		e.config.Tbox.drawCell(e.config.X, y, e.config.Width, 1, text, e.config.FgColor, e.config.BgColor)
+	t := e.config.Tbox
+	height := e.height()
+	if height < e.window.height {
+		// restore the grid under lines the editor does not occupy any more
+		t.SetDirty(ui.DirtyHRuler | ui.DirtyVRuler | ui.DirtyGrid)
+	}
+	e.window.height = height
+	e.adjustWindow()
+
+	highlight := e.isFormula()
+	var colors []tcell.Color
+	if e.config.Formula {
+		var refs []ui.FormulaRef
+		text := e.Text()
+		if highlight {
+			refs = t.dataDelegate.FormulaRefs(text)
+		}
+		t.setFormulaRefs(refs)
+		if highlight {
+			colors = formulaColors(text, t.formulaRefs)
+		}
+	}
+	hint := e.hint()
+	if hint != "" || e.lastHint != "" {
+		// the hint is drawn over the grid
+		t.SetDirty(ui.DirtyHRuler | ui.DirtyVRuler | ui.DirtyGrid)
+	}
+	e.lastHint = hint
+	if t.dirty != 0 {
+		t.RefreshView()
+	}
+	if hint != "" {
+		// below the editor if there is room, above otherwise
+		hintY := e.config.Y + height
+		if hintY >= t.screenHeight-statusLineHeight {
+			hintY = e.config.Y - 1
+		}
+		t.drawCell(e.config.X, hintY, utf8.RuneCountInString(hint)+1, 1, " "+hint, colorBlack, colorHint)
+	}
+
+	// skip lines above the window
+	line := e.firstLine
+	lineRunes := 0
+	for i := 0; i < e.window.topLine && line != nil; i++ {
+		// colors cover line breaks too
+		lineRunes += utf8.RuneCount(line.data) + 1
+		line = line.next
+	}
+	for y := e.config.Y; y < e.config.Y+height; y++ {
+		var text []rune
+		if line != nil {
+			text = []rune(string(line.data))
+		}
+		visible := ""
+		if e.window.firstRune < len(text) {
+			visible = string(text[e.window.firstRune:])
+		}
+		if colors != nil && line != nil {
+			lineColors := colors[lineRunes : lineRunes+len(text)]
+			t.drawColoredCell(e.config.X, y, e.config.Width, visible, lineColors[minInt(e.window.firstRune, len(text)):], e.config.BgColor)
+		} else {
+			t.drawCell(e.config.X, y, e.config.Width, 1, visible, e.config.FgColor, e.config.BgColor)
+		}
 		if line != nil {
 			if line == e.cursor.line {
-				e.config.Tbox.screen.ShowCursor(e.config.X+e.cursor.offsetRunes-e.window.firstRune, y)
+				cursorX := e.config.X + e.cursor.offsetRunes - e.window.firstRune
+				t.screen.ShowCursor(cursorX, y)
+				if e.config.Vim && e.vim.normal {
+					// block cursor shows normal mode
+					char, _, style, _ := t.screen.GetContent(cursorX, y)
+					t.screen.SetContent(cursorX, y, char, nil, style.Reverse(true))
+				}
 			}
 			// advance to next line
+			lineRunes += len(text) + 1
 			line = line.next
 		}
-		y++
 	}
-	e.config.Tbox.screen.Show()
+	t.screen.Show()
+}
+
+// height returns number of lines to display.
+func (e *editor) height() int {
+	h := e.linesCount
+	if h > e.config.MaxHeight {
+		h = e.config.MaxHeight
+	}
+	if h < e.config.Height {
+		h = e.config.Height
+	}
+	return h
 }
 
+// adjustWindow scrolls the text so the cursor is visible.
 func (e *editor) adjustWindow() {
 	if e.window.firstRune < e.cursor.offsetRunes-(e.config.Width-1) {
 		e.window.firstRune = e.cursor.offsetRunes - (e.config.Width - 1)
 	} else if e.window.firstRune > e.cursor.offsetRunes {
 		e.window.firstRune = e.cursor.offsetRunes
 	}
-	// TODO: adjust vertical position
-	//if e.cursor.lineNum
+	n := 0
+	for l := e.firstLine; l != e.cursor.line; l = l.next {
+		n++
+	}
+	if n < e.window.topLine {
+		e.window.topLine = n
+	} else if e.window.height > 0 && n >= e.window.topLine+e.window.height {
+		e.window.topLine = n - e.window.height + 1
+	}
+}
+
+// isWordRune checks if the rune is a part of a word for word motions.
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// wordEnd returns offset of the end of the word at or after the offset.
+func wordEnd(text string, offset int) int {
+	inWord := false
+	for i, r := range text[offset:] {
+		if isWordRune(r) {
+			inWord = true
+		} else if inWord {
+			return offset + i
+		}
+	}
+	return len(text)
+}
+
+// wordStart returns offset of the beginning of the word at or before the offset.
+func wordStart(text string, offset int) int {
+	inWord := false
+	for offset > 0 {
+		r, l := utf8.DecodeLastRuneInString(text[:offset])
+		if isWordRune(r) {
+			inWord = true
+		} else if inWord {
+			return offset
+		}
+		offset -= l
+	}
+	return 0
+}
+
+// commonPrefix returns the longest prefix of all the strings.
+func commonPrefix(s []string) string {
+	if len(s) == 0 {
+		return ""
+	}
+	prefix := s[0]
+	for _, v := range s[1:] {
+		for !strings.HasPrefix(v, prefix) {
+			_, l := utf8.DecodeLastRuneInString(prefix)
+			prefix = prefix[:len(prefix)-l]
+		}
+	}
+	return prefix
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
 }
 
 func cloneBytes(s []byte) []byte {
@@ -0,0 +1,188 @@
+package termbox
+
+import (
+	"xl/formula"
+	"xl/ui"
+
+	"strings"
+
+	"github.com/gdamore/tcell"
+)
+
+// refInsertAfter lists chars after which a reference may be inserted in point mode.
+const refInsertAfter = "=(;:+-*/^<>,{"
+
+// pointMode is a state of inserting reference by navigating the grid with arrow keys.
+type pointMode struct {
+	active bool
+	// location of inserted reference in the line
+	start int
+	end   int
+	// range is being selected from anchor to the point
+	anchorX int
+	anchorY int
+	x       int
+	y       int
+}
+
+// onFormulaKey handles keys specific for formula editing. Returns true if the key has been handled.
+func (e *editor) onFormulaKey(ev ui.KeyEvent) bool {
+	if !e.isFormula() {
+		e.point.active = false
+		return false
+	}
+	switch ev.Key {
+	case tcell.KeyUp, tcell.KeyDown, tcell.KeyLeft, tcell.KeyRight:
+		if !e.point.active && !e.canInsertRef() {
+			return false
+		}
+		e.movePoint(ev)
+		return true
+	case tcell.KeyTab:
+		e.point.active = false
+		return e.completeFunction()
+	}
+	e.point.active = false
+	return false
+}
+
+// isFormula checks if the text being edited is a formula.
+func (e *editor) isFormula() bool {
+	return e.config.Formula && len(e.firstLine.data) > 0 && e.firstLine.data[0] == '='
+}
+
+// canInsertRef checks if a reference can be inserted at the cursor position.
+func (e *editor) canInsertRef() bool {
+	before := strings.TrimRight(e.Text()[:e.offset()], " ")
+	return len(before) > 0 && strings.IndexByte(refInsertAfter, before[len(before)-1]) >= 0
+}
+
+// movePoint moves the point in the grid and replaces the reference inserted before with the new one.
+// With Shift pressed the range is selected.
+func (e *editor) movePoint(ev ui.KeyEvent) {
+	t := e.config.Tbox
+	sv := t.dataDelegate.SheetView()
+	if !e.point.active {
+		e.saveUndo(actionComplete)
+		offset := e.offset()
+		e.point = pointMode{
+			active:  true,
+			start:   offset,
+			end:     offset,
+			anchorX: sv.Cursor.X,
+			anchorY: sv.Cursor.Y,
+			x:       sv.Cursor.X,
+			y:       sv.Cursor.Y,
+		}
+	}
+	// the point moves over visible rows and columns only and must stay in the viewport
+	switch ev.Key {
+	case tcell.KeyUp:
+		e.point.y = stepVisible(t.activePane().rowNums, e.point.y, -1)
+	case tcell.KeyDown:
+		e.point.y = stepVisible(t.activePane().rowNums, e.point.y, 1)
+	case tcell.KeyLeft:
+		e.point.x = stepVisible(t.activePane().colNums, e.point.x, -1)
+	case tcell.KeyRight:
+		e.point.x = stepVisible(t.activePane().colNums, e.point.x, 1)
+	}
+	if ev.Mod&tcell.ModShift == 0 {
+		e.point.anchorX, e.point.anchorY = e.point.x, e.point.y
+	}
+
+	ref := t.dataDelegate.CellView(sv.Sheet, e.point.x, e.point.y).Name
+	if e.point.anchorX != e.point.x || e.point.anchorY != e.point.y {
+		x1, x2 := e.point.anchorX, e.point.x
+		if x1 > x2 {
+			x1, x2 = x2, x1
+		}
+		y1, y2 := e.point.anchorY, e.point.y
+		if y1 > y2 {
+			y1, y2 = y2, y1
+		}
+		ref = t.dataDelegate.CellView(sv.Sheet, x1, y1).Name + ":" + t.dataDelegate.CellView(sv.Sheet, x2, y2).Name
+	}
+	e.replaceText(e.point.start, e.point.end, ref)
+	e.point.end = e.point.start + len(ref)
+}
+
+// completeFunction completes function name before the cursor.
+// If there are several functions matching, completes their common prefix and shows them in the hint.
+func (e *editor) completeFunction() bool {
+	text := e.Text()[:e.offset()]
+	tokens := formula.Tokenize(text)
+	if len(tokens) == 0 {
+		return false
+	}
+	last := tokens[len(tokens)-1]
+	if (last.Type != formula.OutputTypeName && last.Type != formula.OutputTypeCell) || last.Offset+len(last.Text) != len(text) {
+		return false
+	}
+	candidates := formula.Functions(last.Text)
+	if len(candidates) == 0 {
+		return true
+	}
+	e.saveUndo(actionComplete)
+	if len(candidates) == 1 {
+		e.replaceText(last.Offset, len(text), candidates[0].Name+"(")
+		return true
+	}
+	for _, c := range candidates {
+		e.completions = append(e.completions, c.Name)
+	}
+	e.replaceText(last.Offset, len(text), commonPrefix(e.completions))
+	return true
+}
+
+// hint returns the text of the popup shown under the editor: completion candidates
+// or signature of the function which arguments are being edited.
+func (e *editor) hint() string {
+	if len(e.completions) > 0 {
+		return strings.Join(e.completions, " ")
+	}
+	if !e.isFormula() {
+		return ""
+	}
+	// names of functions which parentheses are not closed, empty for subexpressions
+	var stack []string
+	tokens := formula.Tokenize(e.Text()[:e.offset()])
+	for i, token := range tokens {
+		switch {
+		case token.Type == formula.OutputTypeFunction:
+			stack = append(stack, token.Text)
+		case token.Text == "(" && token.Type == formula.OutputTypeSymbol:
+			// function name token is followed by its opening parenthesis
+			if i == 0 || tokens[i-1].Type != formula.OutputTypeFunction {
+				stack = append(stack, "")
+			}
+		case token.Text == ")" && len(stack) > 0:
+			stack = stack[:len(stack)-1]
+		}
+	}
+	for i := len(stack) - 1; i >= 0; i-- {
+		if f, ok := formula.FunctionByName(stack[i]); ok {
+			return f.Signature() + " " + f.Description
+		}
+	}
+	return ""
+}
+
+// stepVisible returns the number drawn delta positions away from n, staying within the drawn numbers.
+func stepVisible(nums []int, n, delta int) int {
+	for i, num := range nums {
+		if num == n {
+			return nums[clamp(i+delta, 0, len(nums)-1)]
+		}
+	}
+	return n
+}
+
+func clamp(v, min, max int) int {
+	if v > max {
+		v = max
+	}
+	if v < min {
+		v = min
+	}
+	return v
+}
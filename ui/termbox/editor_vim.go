@@ -0,0 +1,225 @@
+package termbox
+
+import (
+	"xl/ui"
+
+	"github.com/gdamore/tcell"
+)
+
+// vimState is a state of vim-like modal editing.
+type vimState struct {
+	normal bool
+	// operator waiting for a motion: 'd' or 'c'
+	pending rune
+	// keys of the change being recorded and of the last complete change, used by '.'
+	recording  []ui.KeyEvent
+	lastChange []ui.KeyEvent
+	replaying  bool
+}
+
+// onVimKey handles keys of normal mode and switching between modes.
+// Returns true if the key has been handled, and true in the second value if editor must exit.
+func (e *editor) onVimKey(ev ui.KeyEvent) (bool, bool) {
+	v := &e.vim
+	if !v.normal {
+		if v.recording != nil && !v.replaying {
+			v.recording = append(v.recording, ev)
+		}
+		if ev.Key == tcell.KeyEsc {
+			v.normal = true
+			e.finishChange()
+			if !e.bol() {
+				e.moveCursorBackward()
+			}
+			return true, false
+		}
+		return false, false
+	}
+
+	if ev.Key == tcell.KeyEsc {
+		if v.pending != 0 {
+			v.pending = 0
+			return true, false
+		}
+		// exit editor, discard changes
+		e.cancelled = true
+		return true, true
+	}
+	if ev.Key != tcell.KeyRune || ev.Mod&(tcell.ModAlt|tcell.ModCtrl) != 0 {
+		// arrows, Enter and control keys work the same way in both modes
+		v.pending = 0
+		return false, false
+	}
+	e.lastAction = actionNone
+	if v.pending != 0 {
+		op := v.pending
+		v.pending = 0
+		e.recordChange(ev)
+		e.vimOperator(op, ev.Ch)
+		return true, false
+	}
+
+	switch ev.Ch {
+	case 'h':
+		if !e.bol() {
+			e.moveCursorBackward()
+		}
+	case 'l':
+		if !e.eol() {
+			e.moveCursorForward()
+		}
+	case 'j':
+		e.moveCursorNextLine()
+	case 'k':
+		e.moveCursorPrevLine()
+	case '0':
+		e.moveCursorBOL()
+	case '$':
+		e.moveCursorEOL()
+	case 'w':
+		e.setOffset(nextWordStart(e.Text(), e.offset()))
+	case 'b':
+		e.moveCursorWordBackward()
+	case 'e':
+		e.moveCursorWordForward()
+	case 'u':
+		e.undoLast()
+	case '.':
+		e.repeatChange()
+	case 'i', 'a', 'I', 'A':
+		e.recordChange(ev)
+		switch ev.Ch {
+		case 'a':
+			if !e.eol() {
+				e.moveCursorForward()
+			}
+		case 'I':
+			e.moveCursorBOL()
+		case 'A':
+			e.moveCursorEOL()
+		}
+		e.saveUndo(actionInsert)
+		v.normal = false
+	case 'x':
+		e.recordChange(ev)
+		if !e.eol() {
+			offset := e.offset()
+			e.kill(offset, offset+len(string(e.runeAtCursor())), false)
+		}
+		e.finishChange()
+	case 'D', 'C':
+		e.recordChange(ev)
+		e.vimOperator(ev.Ch+'a'-'A', '$')
+	case 'd', 'c':
+		e.recordChange(ev)
+		v.pending = ev.Ch
+	case 'p', 'P':
+		e.recordChange(ev)
+		if ev.Ch == 'p' && !e.eol() {
+			e.moveCursorForward()
+		}
+		e.yank()
+		e.finishChange()
+	}
+	return true, false
+}
+
+// vimOperator deletes (op 'd') or changes (op 'c') text from the cursor to the motion.
+func (e *editor) vimOperator(op rune, motion rune) {
+	text, offset := e.Text(), e.offset()
+	start, end := offset, offset
+	switch motion {
+	case 'w':
+		if op == 'c' {
+			// cw changes up to the end of word like ce
+			end = wordEnd(text, offset)
+		} else {
+			end = nextWordStart(text, offset)
+		}
+	case 'e':
+		end = wordEnd(text, offset)
+	case 'b':
+		start = wordStart(text, offset)
+	case '$':
+		end = offset + len(e.cursor.line.data) - e.cursor.offsetBytes
+	case '0':
+		start = offset - e.cursor.offsetBytes
+	case op:
+		// dd and cc work on the whole line
+		start = offset - e.cursor.offsetBytes
+		end = start + len(e.cursor.line.data)
+		if op == 'd' {
+			if e.cursor.line.next != nil {
+				end++
+			} else if e.cursor.line.prev != nil {
+				start--
+			}
+		}
+	default:
+		e.vim.recording = nil
+		return
+	}
+	e.kill(start, end, false)
+	if op == 'c' {
+		e.vim.normal = false
+		return
+	}
+	e.finishChange()
+}
+
+// recordChange starts recording keys of the change to be repeated by '.'.
+func (e *editor) recordChange(ev ui.KeyEvent) {
+	if e.vim.replaying {
+		return
+	}
+	if e.vim.recording == nil {
+		e.vim.recording = []ui.KeyEvent{}
+	}
+	e.vim.recording = append(e.vim.recording, ev)
+}
+
+// finishChange remembers the recorded change for '.'.
+func (e *editor) finishChange() {
+	if e.vim.replaying || e.vim.recording == nil {
+		return
+	}
+	e.vim.lastChange = e.vim.recording
+	e.vim.recording = nil
+}
+
+// repeatChange repeats the last change.
+func (e *editor) repeatChange() {
+	if len(e.vim.lastChange) == 0 {
+		return
+	}
+	e.vim.replaying = true
+	for _, ev := range e.vim.lastChange {
+		e.OnKey(ev)
+	}
+	e.vim.replaying = false
+	e.vim.normal = true
+}
+
+// runeAtCursor returns the rune under the cursor or zero at the end of line.
+func (e *editor) runeAtCursor() rune {
+	if e.eol() {
+		return 0
+	}
+	for _, r := range string(e.cursor.line.data[e.cursor.offsetBytes:]) {
+		return r
+	}
+	return 0
+}
+
+// nextWordStart returns offset of the beginning of the next word after the offset.
+func nextWordStart(text string, offset int) int {
+	inWord := true
+	for i, r := range text[offset:] {
+		if !isWordRune(r) {
+			inWord = false
+		} else if !inWord {
+			return offset + i
+		}
+	}
+	return len(text)
+}
@@ -0,0 +1,114 @@
+package termbox
+
+import (
+	"xl/formula"
+	"xl/ui"
+
+	"unicode/utf8"
+
+	"github.com/gdamore/tcell"
+)
+
+// tokenColors maps formula token types to colors.
+var tokenColors = map[int]tcell.Color{
+	formula.OutputTypeNumber:   colorNumber,
+	formula.OutputTypeBoolean:  colorNumber,
+	formula.OutputTypeString:   colorString,
+	formula.OutputTypeFunction: colorFunction,
+	formula.OutputTypeSheet:    colorCellRef,
+	formula.OutputTypeCell:     colorCellRef,
+	formula.OutputTypeName:     colorName,
+}
+
+// expressionText returns formula text and colors for every rune of it.
+func expressionText(expr *formula.Expression) (string, []tcell.Color) {
+	var text []rune
+	var colors []tcell.Color
+	expr.Output(func(s string, t int) {
+		c := tokenColor(t)
+		for _, r := range s {
+			text = append(text, r)
+			colors = append(colors, c)
+		}
+	})
+	return string(text), colors
+}
+
+// formulaColors returns colors for every rune of the formula source which is being edited.
+// Referenced cells and ranges get the same colors as they are highlighted in the grid.
+func formulaColors(source string, refs []ui.FormulaRef) []tcell.Color {
+	byteColors := make([]tcell.Color, len(source))
+	for i := range byteColors {
+		byteColors[i] = colorWhite
+	}
+	for _, token := range formula.Tokenize(source) {
+		for i := token.Offset; i < token.Offset+len(token.Text) && i < len(source); i++ {
+			byteColors[i] = tokenColor(token.Type)
+		}
+	}
+	for i, ref := range refs {
+		for j := ref.Offset; j < ref.Offset+ref.Length && j < len(source); j++ {
+			byteColors[j] = refColor(i)
+		}
+	}
+	colors := make([]tcell.Color, 0, utf8.RuneCountInString(source))
+	for i := range source {
+		colors = append(colors, byteColors[i])
+	}
+	return colors
+}
+
+func tokenColor(t int) tcell.Color {
+	if c, ok := tokenColors[t]; ok {
+		return c
+	}
+	return colorWhite
+}
+
+// refColor returns color of n-th reference in the formula.
+func refColor(n int) tcell.Color {
+	return colorRefs[n%len(colorRefs)]
+}
+
+// formulaRefColor returns color of the first reference covering the cell. Returns false if the cell is not referenced.
+func (t *Termbox) formulaRefColor(x, y int) (tcell.Color, bool) {
+	for i, ref := range t.formulaRefs {
+		if ref.CurrentSheet && x >= ref.X && x <= ref.X2 && y >= ref.Y && y <= ref.Y2 {
+			return refColor(i), true
+		}
+	}
+	return 0, false
+}
+
+// setFormulaRefs highlights cells referenced by the formula being edited.
+func (t *Termbox) setFormulaRefs(refs []ui.FormulaRef) {
+	if len(refs) == 0 && len(t.formulaRefs) == 0 {
+		return
+	}
+	t.formulaRefs = refs
+	t.SetDirty(ui.DirtyGrid)
+}
+
+// drawColoredCell acts like drawCell, but every rune has its own color.
+func (t *Termbox) drawColoredCell(x int, y int, width int, text string, colors []tcell.Color, bg tcell.Color) {
+	var st tcell.Style
+	st = st.Background(bg)
+	textAsRunes := []rune(text)
+	textLen := len(textAsRunes)
+	for i := 0; i < width; i++ {
+		char := ' '
+		st = st.Foreground(colorWhite)
+		if i < textLen {
+			if textLen > width && i == width-1 {
+				char = '>'
+				st = st.Foreground(colorYellow)
+			} else {
+				char = textAsRunes[i]
+				if i < len(colors) {
+					st = st.Foreground(colors[i])
+				}
+			}
+		}
+		t.screen.SetContent(x+i, y, char, nil, st)
+	}
+}
@@ -22,48 +22,76 @@ func (t *Termbox) ReadKey() (ui.InputEventInterface, error) {
 
 	case *tcell.EventResize:
 	//handling resize
+	case *tcell.EventInterrupt:
+		t.dataDelegate.Interrupted(ev.Data())
 	case *tcell.EventMouse:
-	//handling mouse
+		x, y := ev.Position()
+		e := ui.MouseEvent{
+			X:       x,
+			Y:       y,
+			Buttons: ev.Buttons(),
+			Mod:     ev.Modifiers(),
+		}
+		return e, nil
 	case *tcell.EventError:
 		return nil, errors.New("unknown event")
 	}
 	return nil, nil
 }
 
-func (t *Termbox) EditCellValue(oldValue string) (string, error) {
+func (t *Termbox) EditCellValue(oldValue string, cursorOffset int) (string, bool, error) {
 	w, _ := t.screen.Size()
-	v, err := t.enterEditorMode(&editorConfig{
-		Tbox:     t,
-		X:        0,
-		Y:        0,
-		Width:    w,
-		Height:   formulaLineHeight,
-		MaxLines: 1,
-		FgColor:  colorWhite,
-		BgColor:  colorBlack,
-		Value:    oldValue,
+	v, cancelled, err := t.enterEditorMode(&editorConfig{
+		Tbox:      t,
+		X:         0,
+		Y:         0,
+		Width:     w,
+		Height:    formulaLineHeight,
+		MaxHeight: editorMaxHeight,
+		MaxLines:  editorMaxLines,
+		FgColor:   colorWhite,
+		BgColor:   colorBlack,
+		Value:     oldValue,
+		Formula:   true,
+		Cursor:    cursorOffset,
+		Vim:       t.vimMode,
 	})
 	if err != nil {
-		return "", err
+		return "", false, err
 	}
-	return v, nil
+	return v, cancelled, nil
 }
 
-func (t *Termbox) InputCommand() (string, error) {
+func (t *Termbox) InputCommand(prompt string) (string, error) {
 	w, h := t.screen.Size()
-	t.drawCell(0, h-statusLineHeight, 1, statusLineHeight, ":", colorWhite, colorBlack)
-	v, err := t.enterEditorMode(&editorConfig{
+	x := len(prompt)
+	t.drawCell(0, h-statusLineHeight, x, statusLineHeight, prompt, colorWhite, colorBlack)
+	// commands and search patterns have separate histories
+	history := &t.searchHistory
+	var complete func(string) (int, []string)
+	if prompt == ":" {
+		history = &t.commandHistory
+		complete = t.dataDelegate.CompleteCommand
+	}
+	v, _, err := t.enterEditorMode(&editorConfig{
 		Tbox:     t,
-		X:        1,
+		X:        x,
 		Y:        h - statusLineHeight,
-		Width:    w - 1,
+		Width:    w - x,
 		Height:   statusLineHeight,
 		MaxLines: 1,
 		FgColor:  colorWhite,
 		BgColor:  colorBlack,
+		Cursor:   -1,
+		Vim:      t.vimMode,
+		History:  *history,
+		Complete: complete,
 	})
 	if err != nil {
 		return "", err
 	}
+	if v != "" && (len(*history) == 0 || (*history)[len(*history)-1] != v) {
+		*history = append(*history, v)
+	}
 	return v, nil
 }
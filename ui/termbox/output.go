@@ -1,10 +1,14 @@
 package termbox
 
 import (
-	"bytes"
+	"xl/document/sheet"
 	"xl/ui"
 
+	"strings"
+	"unicode/utf8"
+
 	"github.com/gdamore/tcell"
+	"github.com/mattn/go-runewidth"
 )
 
 const (
@@ -15,6 +19,10 @@ const (
 	statusLineHeight  = 1
 	hRulerHeight      = 1
 	formulaLineHeight = 1
+
+	// cell editor limits for multi-line values
+	editorMaxHeight = 5
+	editorMaxLines  = 1000
 )
 
 func (t *Termbox) SetDataDelegate(delegate ui.DataDelegateInterface) {
@@ -33,100 +41,29 @@ func (t *Termbox) SetStatus(msg string, flags int) {
 
 func (t *Termbox) RefreshView() {
 	docView := t.dataDelegate.DocView()
-	sheetView := t.dataDelegate.SheetView()
+	windowsView := t.dataDelegate.WindowsView()
+	sheetView := windowsView.Windows[windowsView.Active]
+	t.activeWindow = windowsView.Active
 
 	// formula line
 	if t.dirty&ui.DirtyFormulaLine > 0 {
 		formulaLineView := sheetView.FormulaLineView
-		currentCellName := t.dataDelegate.CellView(sheetView.Cursor.X, sheetView.Cursor.Y).Name
+		currentCellName := t.dataDelegate.CellView(sheetView.Sheet, sheetView.Cursor.X, sheetView.Cursor.Y).Name
 		t.drawCell(0, 0, t.screenWidth, formulaLineHeight, currentCellName, colorYellow, colorBlack)
-		text := formulaLineView.DisplayText
+		x := len(currentCellName) + 1
 		if formulaLineView.Expression != nil {
-			var buf bytes.Buffer
-			formulaLineView.Expression.Output(func(s string, t int) {
-				buf.WriteString(s)
-			})
-			text = buf.String()
-		}
-		t.drawCell(len(currentCellName)+1, 0, t.screenWidth, formulaLineHeight, text, colorWhite, colorBlack)
-	}
-
-	// vertical ruler
-	if t.dirty&ui.DirtyVRuler > 0 {
-		screenY := formulaLineHeight + hRulerHeight
-		cellY := sheetView.Viewport.Top
-		t.vRulerWidth = 0
-		for screenY < t.screenHeight-statusLineHeight {
-			rowView := t.dataDelegate.RowView(cellY)
-			heightChars := pixelsToCharsY(rowView.Height)
-			fg := colorWhite
-			if cellY == sheetView.Cursor.Y {
-				fg = colorYellow
-			}
-			t.drawCell(0, screenY, len(rowView.Name)+1+1, heightChars, rowView.Name, fg, colorBlack)
-			if len(rowView.Name)+1 > t.vRulerWidth {
-				t.vRulerWidth = len(rowView.Name) + 1
-			}
-			cellY++
-			screenY += heightChars
+			text, colors := expressionText(formulaLineView.Expression)
+			t.drawColoredCell(x, 0, t.screenWidth-x, text, colors, colorBlack)
+		} else {
+			t.drawCell(x, 0, t.screenWidth, formulaLineHeight, formulaLineView.DisplayText, colorWhite, colorBlack)
 		}
-		t.calculatedViewportHeight = cellY - sheetView.Viewport.Top
 	}
 
-	// horizontal ruler
-	if t.dirty&ui.DirtyHRuler > 0 {
-		screenX := t.vRulerWidth
-		screenY := formulaLineHeight
-		cellX := sheetView.Viewport.Left
-		for screenX < t.screenWidth {
-			colView := t.dataDelegate.ColView(cellX)
-			widthChars := pixelsToCharsX(colView.Width)
-			fg := colorWhite
-			if cellX == sheetView.Cursor.X {
-				fg = colorYellow
-			}
-			t.drawCell(screenX, screenY, widthChars, hRulerHeight, colView.Name, fg, colorBlack)
-			cellX++
-			screenX += widthChars
-		}
-		t.calculatedViewportWidth = cellX - sheetView.Viewport.Left
-	}
-
-	// grid
-	if t.dirty&ui.DirtyGrid > 0 {
-		cellY := sheetView.Viewport.Top
-		screenY := formulaLineHeight + hRulerHeight
-		for screenY < t.screenHeight-statusLineHeight {
-			cellX := sheetView.Viewport.Left
-			screenX := t.vRulerWidth
-			heightChars := pixelsToCharsY(t.dataDelegate.RowView(cellY).Height)
-			for screenX < t.screenWidth {
-				widthChars := pixelsToCharsX(t.dataDelegate.ColView(cellX).Width)
-				c := t.dataDelegate.CellView(cellX, cellY)
-				text := c.DisplayText
-
-				bgColor := colorBlack
-				if cellX%2 != 0 || cellY%2 == 0 {
-					bgColor = colorGrey236
-				}
-				if cellX%2 != 0 && cellY%2 == 0 {
-					bgColor = colorGrey239
-				}
-				if cellX == sheetView.Cursor.X && cellY == sheetView.Cursor.Y {
-					t.lastCursorX = screenX
-					t.lastCursorY = screenY
-					t.screen.ShowCursor(screenX, screenY)
-				}
-				if c.Error != nil {
-					text = *c.Error
-					bgColor = colorRed
-				}
-				t.drawCell(screenX, screenY, widthChars, heightChars, text, colorGrey, bgColor)
-				cellX++
-				screenX += widthChars
-			}
-			cellY++
-			screenY += heightChars
+	// rulers and grids of windows
+	if t.dirty&(ui.DirtyVRuler|ui.DirtyHRuler|ui.DirtyGrid) > 0 {
+		t.layoutPanes(windowsView.Layout)
+		for _, p := range t.panes {
+			t.drawPane(p, windowsView)
 		}
 	}
 
@@ -155,28 +92,151 @@ func (t *Termbox) RefreshView() {
 	t.screen.Show()
 }
 
+// ScreenArea tells what is drawn at the screen position according to the last drawing iteration.
+func (t *Termbox) ScreenArea(x, y int) ui.ScreenArea {
+	if y < formulaLineHeight {
+		return ui.ScreenArea{Kind: ui.AreaFormulaLine}
+	}
+	if y >= t.screenHeight-statusLineHeight {
+		if n := x / sheetNameMaxWidth; n < len(t.dataDelegate.DocView().Sheets) {
+			return ui.ScreenArea{Kind: ui.AreaSheetTab, Sheet: n}
+		}
+		return ui.ScreenArea{Kind: ui.AreaStatusLine}
+	}
+	for _, p := range t.panes {
+		if x >= p.x && x < p.x+p.width && y >= p.y && y < p.y+p.height {
+			return t.paneArea(p, x, y)
+		}
+	}
+	return ui.ScreenArea{}
+}
+
+// drawFilterIndicator draws the autofilter drop-down mark in the horizontal ruler.
+func (t *Termbox) drawFilterIndicator(x, y int, filtered bool) {
+	st := tcell.StyleDefault.Background(colorBlack).Foreground(colorWhite)
+	ch := '▽'
+	if filtered {
+		st = st.Foreground(colorYellow)
+		ch = '▼'
+	}
+	t.screen.SetContent(x, y, ch, nil, st)
+}
+
+// drawNoteIndicator draws the mark of a cell having a note in its top right corner.
+func (t *Termbox) drawNoteIndicator(x, y int, st tcell.Style) {
+	t.screen.SetContent(x, y, '◥', nil, st.Foreground(colorRed).Underline(false))
+}
+
+// findStart returns index of the last start not greater than the position, or -1.
+func findStart(starts []int, pos int) int {
+	for i := len(starts) - 1; i >= 0; i-- {
+		if starts[i] <= pos {
+			return i
+		}
+	}
+	return -1
+}
+
+// drawCell draws text in the rectangle. Multi-line text occupies several rows, long lines are wrapped
+// if the rectangle is higher than one row. Wide characters take two columns.
 func (t *Termbox) drawCell(x int, y int, width int, height int, text string, fg tcell.Color, bg tcell.Color) {
-	var st tcell.Style
-	st = st.Background(bg)
-	textAsRunes := []rune(text)
-	textLen := len(textAsRunes)
+	t.drawStyledCell(x, y, width, height, text, tcell.StyleDefault.Foreground(fg).Background(bg), sheet.AlignLeft)
+}
+
+// drawStyledCell draws text like drawCell does with given style and alignment. Truncated lines are always
+// aligned to the left.
+func (t *Termbox) drawStyledCell(x int, y int, width int, height int, text string, st tcell.Style, align int) {
+	// padding is not underlined
+	pad := st.Underline(false)
+	lines := strings.Split(text, "\n")
+	if height > 1 {
+		lines = wrapLines(lines, width)
+	}
 	for cursorY := y; cursorY < y+height; cursorY++ {
-		indexX := 0
-		for cursorX := x; cursorX < x+width; cursorX++ {
-			char := ' '
-			st = st.Foreground(fg)
-			if cursorY == y && indexX < textLen {
-				if textLen > width && cursorX == x+width-1 {
-					char = '>'
-					st = st.Foreground(colorYellow)
-				} else {
-					char = textAsRunes[indexX]
+		var line string
+		if cursorY-y < len(lines) {
+			line = lines[cursorY-y]
+		}
+		// lines which do not fit are marked as truncated text too
+		lineWidth := runewidth.StringWidth(line)
+		truncated := lineWidth > width || (cursorY == y+height-1 && len(lines) > height)
+		textWidth := width
+		if truncated {
+			textWidth--
+		}
+		cursorX := x
+		if !truncated {
+			switch align {
+			case sheet.AlignRight:
+				cursorX += width - lineWidth
+			case sheet.AlignCenter:
+				cursorX += (width - lineWidth) / 2
+			}
+		}
+		for i := x; i < cursorX; i++ {
+			t.screen.SetContent(i, cursorY, ' ', nil, pad)
+		}
+		for _, r := range line {
+			w := runewidth.RuneWidth(r)
+			if w == 0 {
+				continue
+			}
+			if cursorX+w > x+textWidth {
+				break
+			}
+			t.screen.SetContent(cursorX, cursorY, r, nil, st)
+			cursorX += w
+		}
+		for ; cursorX < x+textWidth; cursorX++ {
+			t.screen.SetContent(cursorX, cursorY, ' ', nil, pad)
+		}
+		if truncated && width > 0 {
+			t.screen.SetContent(x+width-1, cursorY, '>', nil, pad.Foreground(colorYellow))
+		}
+	}
+}
+
+// wrapLines splits lines wider than width, breaking them after spaces if possible.
+func wrapLines(lines []string, width int) []string {
+	var res []string
+	for _, line := range lines {
+		for runewidth.StringWidth(line) > width && width > 1 {
+			end, lineWidth, lastSpace := 0, 0, -1
+			for i, r := range line {
+				lineWidth += runewidth.RuneWidth(r)
+				if lineWidth > width {
+					break
+				}
+				end = i + utf8.RuneLen(r)
+				if r == ' ' {
+					lastSpace = end
 				}
 			}
-			t.screen.SetContent(cursorX, cursorY, char, nil, st)
-			indexX++
+			if lastSpace > 0 {
+				end = lastSpace
+			}
+			if end == 0 {
+				// a wide character does not fit at all
+				break
+			}
+			res = append(res, line[:end])
+			line = line[end:]
+		}
+		res = append(res, line)
+	}
+	return res
+}
+
+// TextWidth returns width in pixels of a column showing the text without truncation,
+// including a space separating it from the next column.
+func (t *Termbox) TextWidth(text string) int {
+	width := 0
+	for _, line := range strings.Split(text, "\n") {
+		if w := runewidth.StringWidth(line); w > width {
+			width = w
 		}
 	}
+	return (width + 1) * pixelsInCharX
 }
 
 func pixelsToCharsX(pixels int) int {
@@ -187,8 +247,9 @@ func pixelsToCharsX(pixels int) int {
 	return res
 }
 
+// pixelsToCharsY rounds the height to the nearest number of lines, so that default rows are one line high.
 func pixelsToCharsY(pixels int) int {
-	res := pixels / pixelsInCharY
+	res := (pixels + pixelsInCharY/2) / pixelsInCharY
 	if res < 1 {
 		res = 1
 	}
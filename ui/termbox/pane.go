@@ -0,0 +1,309 @@
+package termbox
+
+import (
+	"xl/document/sheet"
+	"xl/ui"
+
+	"github.com/gdamore/tcell"
+)
+
+// pane is a part of the screen where a window draws its rulers and grid.
+type pane struct {
+	window int
+	sheet  int
+	// Position and size of the pane in chars.
+	x, y, width, height int
+
+	// How many rows and columns are visible for last drawing iteration, not counting frozen ones.
+	viewportWidth  int
+	viewportHeight int
+
+	// Length in chars of vertical ruler for last drawing iteration.
+	vRulerWidth int
+
+	// Screen positions where visible columns and rows start and their numbers for last drawing iteration.
+	colStarts []int
+	colNums   []int
+	rowStarts []int
+	rowNums   []int
+}
+
+// layoutPanes places panes of windows between the formula line and the status line.
+// If placement changes, all the panes are redrawn.
+func (t *Termbox) layoutPanes(layout *ui.WindowLayout) {
+	var panes []*pane
+	var place func(l *ui.WindowLayout, x, y, width, height int)
+	place = func(l *ui.WindowLayout, x, y, width, height int) {
+		if len(l.Children) == 0 {
+			panes = append(panes, &pane{window: l.Window, x: x, y: y, width: width, height: height})
+			return
+		}
+		// children get equal parts of the area
+		n := len(l.Children)
+		for i, c := range l.Children {
+			if l.Vertical {
+				from, to := width*i/n, width*(i+1)/n
+				place(c, x+from, y, to-from, height)
+			} else {
+				from, to := height*i/n, height*(i+1)/n
+				place(c, x, y+from, width, to-from)
+			}
+		}
+	}
+	place(layout, 0, formulaLineHeight, t.screenWidth, t.screenHeight-formulaLineHeight-statusLineHeight)
+
+	changed := len(panes) != len(t.panes)
+	for i := 0; !changed && i < len(panes); i++ {
+		p, old := panes[i], t.panes[i]
+		changed = p.window != old.window || p.x != old.x || p.y != old.y || p.width != old.width || p.height != old.height
+	}
+	if changed {
+		t.panes = panes
+		t.dirty |= ui.DirtyHRuler | ui.DirtyVRuler | ui.DirtyGrid
+	}
+}
+
+// activePane returns the pane of the active window.
+func (t *Termbox) activePane() *pane {
+	for _, p := range t.panes {
+		if p.window == t.activeWindow {
+			return p
+		}
+	}
+	return &pane{}
+}
+
+// drawPane draws rulers and the grid of the window. Only the active window highlights the cursor.
+func (t *Termbox) drawPane(p *pane, windowsView *ui.WindowsView) {
+	dirty := t.dirty
+	sheetView := windowsView.Windows[p.window]
+	active := p.window == windowsView.Active
+	// references of the formula being edited are shown in all windows of the current sheet
+	refs := sheetView.Sheet == windowsView.Windows[windowsView.Active].Sheet
+	p.sheet = sheetView.Sheet
+	right, bottom := p.x+p.width, p.y+p.height
+
+	// vertical ruler
+	if dirty&ui.DirtyVRuler > 0 {
+		screenY := p.y + hRulerHeight
+		cellY := 0
+		prevRulerWidth := p.vRulerWidth
+		p.vRulerWidth = 0
+		p.rowStarts = p.rowStarts[:0]
+		p.rowNums = p.rowNums[:0]
+		p.viewportHeight = 0
+		for screenY < bottom {
+			// frozen rows go first, then rows of the scrolled area
+			if cellY == sheetView.Viewport.FrozenRows {
+				cellY = sheetView.Viewport.Top
+			}
+			rowView := t.dataDelegate.RowView(p.sheet, cellY)
+			if rowView.Hidden {
+				cellY++
+				continue
+			}
+			p.rowStarts = append(p.rowStarts, screenY)
+			p.rowNums = append(p.rowNums, cellY)
+			if cellY >= sheetView.Viewport.FrozenRows {
+				p.viewportHeight++
+			}
+			heightChars := minInt(pixelsToCharsY(rowView.Height), bottom-screenY)
+			fg := colorWhite
+			if active && cellY == sheetView.Cursor.Y {
+				fg = colorYellow
+			}
+			t.drawCell(p.x, screenY, len(rowView.Name)+1+1, heightChars, rowView.Name, fg, colorBlack)
+			if len(rowView.Name)+1 > p.vRulerWidth {
+				p.vRulerWidth = len(rowView.Name) + 1
+			}
+			cellY++
+			screenY += heightChars
+		}
+		if p.vRulerWidth != prevRulerWidth {
+			// row numbers got wider or narrower, everything right of them is shifted
+			dirty |= ui.DirtyHRuler | ui.DirtyGrid
+		}
+	}
+
+	// horizontal ruler
+	if dirty&ui.DirtyHRuler > 0 {
+		screenX := p.x + p.vRulerWidth
+		screenY := p.y
+		t.drawCell(p.x, screenY, p.vRulerWidth, hRulerHeight, "", colorWhite, colorBlack)
+		cellX := 0
+		p.colStarts = p.colStarts[:0]
+		p.colNums = p.colNums[:0]
+		p.viewportWidth = 0
+		for screenX < right {
+			if cellX == sheetView.Viewport.FrozenCols {
+				cellX = sheetView.Viewport.Left
+			}
+			colView := t.dataDelegate.ColView(p.sheet, cellX)
+			if colView.Hidden {
+				cellX++
+				continue
+			}
+			p.colStarts = append(p.colStarts, screenX)
+			p.colNums = append(p.colNums, cellX)
+			if cellX >= sheetView.Viewport.FrozenCols {
+				p.viewportWidth++
+			}
+			widthChars := minInt(pixelsToCharsX(colView.Width), right-screenX)
+			fg := colorWhite
+			if active && cellX == sheetView.Cursor.X {
+				fg = colorYellow
+			}
+			t.drawCell(screenX, screenY, widthChars, hRulerHeight, colView.Name, fg, colorBlack)
+			if colView.Filter {
+				t.drawFilterIndicator(screenX+widthChars-1, screenY, colView.Filtered)
+			}
+			cellX++
+			screenX += widthChars
+		}
+	}
+
+	// grid
+	if dirty&ui.DirtyGrid > 0 {
+		// merged cells are drawn over the area their visible parts take when the rest of the grid is done
+		merged := make(map[sheet.Rect]*mergedArea)
+		cellY := 0
+		screenY := p.y + hRulerHeight
+		for screenY < bottom {
+			if cellY == sheetView.Viewport.FrozenRows {
+				cellY = sheetView.Viewport.Top
+			}
+			rowView := t.dataDelegate.RowView(p.sheet, cellY)
+			if rowView.Hidden {
+				cellY++
+				continue
+			}
+			cellX := 0
+			screenX := p.x + p.vRulerWidth
+			heightChars := minInt(pixelsToCharsY(rowView.Height), bottom-screenY)
+			for screenX < right {
+				if cellX == sheetView.Viewport.FrozenCols {
+					cellX = sheetView.Viewport.Left
+				}
+				colView := t.dataDelegate.ColView(p.sheet, cellX)
+				if colView.Hidden {
+					cellX++
+					continue
+				}
+				widthChars := minInt(pixelsToCharsX(colView.Width), right-screenX)
+				c := t.dataDelegate.CellView(p.sheet, cellX, cellY)
+				if r := c.Merge; r.Width > 0 {
+					m := merged[r]
+					if m == nil {
+						m = &mergedArea{x: screenX, y: screenY}
+						merged[r] = m
+					}
+					m.right = maxInt(m.right, screenX+widthChars)
+					m.bottom = maxInt(m.bottom, screenY+heightChars)
+					_, st := t.gridCellStyle(sheetView, refs, t.dataDelegate.CellView(p.sheet, r.X, r.Y), r.X, r.Y)
+					t.drawStyledCell(screenX, screenY, widthChars, heightChars, "", st, 0)
+				} else {
+					if active && cellX == sheetView.Cursor.X && cellY == sheetView.Cursor.Y {
+						t.showCursor(screenX, screenY, screenY+heightChars)
+					}
+					text, st := t.gridCellStyle(sheetView, refs, c, cellX, cellY)
+					t.drawStyledCell(screenX, screenY, widthChars, heightChars, text, st, c.Style.Align)
+					if c.Note && widthChars > 0 {
+						t.drawNoteIndicator(screenX+widthChars-1, screenY, st)
+					}
+				}
+				cellX++
+				screenX += widthChars
+			}
+			cellY++
+			screenY += heightChars
+		}
+		for r, m := range merged {
+			if active && r.X == sheetView.Cursor.X && r.Y == sheetView.Cursor.Y {
+				t.showCursor(m.x, m.y, m.bottom)
+			}
+			c := t.dataDelegate.CellView(p.sheet, r.X, r.Y)
+			text, st := t.gridCellStyle(sheetView, refs, c, r.X, r.Y)
+			t.drawStyledCell(m.x, m.y, m.right-m.x, m.bottom-m.y, text, st, c.Style.Align)
+			if c.Note {
+				t.drawNoteIndicator(m.right-1, m.y, st)
+			}
+		}
+	}
+}
+
+// mergedArea is the screen area of merged cells.
+type mergedArea struct {
+	x, y, right, bottom int
+}
+
+// showCursor places the cursor at the screen position of the cell ending above the bottom row.
+func (t *Termbox) showCursor(x, y, bottom int) {
+	t.lastCursorX = x
+	t.lastCursorY = y
+	t.lastCursorBottom = bottom
+	t.screen.ShowCursor(x, y)
+}
+
+// gridCellStyle returns the text and the style to draw the cell of the window with. Formula references
+// are highlighted if refs is set.
+func (t *Termbox) gridCellStyle(sheetView *ui.SheetView, refs bool, c *ui.CellView, x, y int) (string, tcell.Style) {
+	text := c.DisplayText
+	bgColor := colorBlack
+	if x%2 != 0 || y%2 == 0 {
+		bgColor = colorGrey236
+	}
+	if x%2 != 0 && y%2 == 0 {
+		bgColor = colorGrey239
+	}
+	if c.Style.Bg != "" {
+		bgColor = tcell.GetColor(c.Style.Bg)
+	}
+	if c.Match {
+		bgColor = colorMatch
+	}
+	if sheetView.Selection.Contains(x, y) {
+		bgColor = colorSelection
+	}
+	fgColor := colorGrey
+	if c.ReadOnly {
+		fgColor = colorSpilled
+	}
+	if c.Style.Fg != "" {
+		fgColor = tcell.GetColor(c.Style.Fg)
+	}
+	if c.Error != nil {
+		text = *c.Error
+		bgColor = colorRed
+	}
+	if refColor, ok := t.formulaRefColor(x, y); ok && refs {
+		fgColor = colorBlack
+		bgColor = refColor
+	}
+	st := tcell.StyleDefault.Foreground(fgColor).Background(bgColor).
+		Bold(c.Style.Bold).Italic(c.Style.Italic).Underline(c.Style.Underline)
+	return text, st
+}
+
+// paneArea tells what is drawn at the screen position within the pane.
+func (t *Termbox) paneArea(p *pane, x, y int) ui.ScreenArea {
+	col := findStart(p.colStarts, x)
+	row := findStart(p.rowStarts, y)
+	switch {
+	case y < p.y+hRulerHeight:
+		if col < 0 {
+			return ui.ScreenArea{}
+		}
+		area := ui.ScreenArea{Kind: ui.AreaHRuler, X: p.colNums[col], Window: p.window}
+		area.Width = pixelsToCharsX(t.dataDelegate.ColView(p.sheet, area.X).Width)
+		area.ColBorder = x == p.colStarts[col]+area.Width-1
+		return area
+	case x < p.x+p.vRulerWidth:
+		if row < 0 {
+			return ui.ScreenArea{}
+		}
+		return ui.ScreenArea{Kind: ui.AreaVRuler, Y: p.rowNums[row], Window: p.window}
+	case col >= 0 && row >= 0:
+		return ui.ScreenArea{Kind: ui.AreaGrid, X: p.colNums[col], Y: p.rowNums[row], Window: p.window}
+	}
+	return ui.ScreenArea{}
+}
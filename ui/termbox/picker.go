@@ -0,0 +1,102 @@
+package termbox
+
+import (
+	"xl/ui"
+
+	"errors"
+	"strings"
+	"unicode"
+
+	"github.com/gdamore/tcell"
+	"github.com/mattn/go-runewidth"
+)
+
+// pickerMaxHeight is a number of values shown in the picker at once.
+const pickerMaxHeight = 10
+
+// PickValue shows the list of values under the cursor cell and lets user choose one with arrow keys.
+// Typing a char jumps to the next value starting with it.
+func (t *Termbox) PickValue(values []string, selected int) (int, error) {
+	defer t.SetDirty(ui.DirtyGrid)
+	if len(values) == 0 {
+		return -1, nil
+	}
+	width := 0
+	for _, v := range values {
+		width = maxInt(width, runewidth.StringWidth(v))
+	}
+	width = minInt(width+2, t.screenWidth)
+	x := minInt(t.lastCursorX, t.screenWidth-width)
+	// the list goes below the cursor cell, or above it if there is more space there
+	y := t.lastCursorBottom
+	below := t.screenHeight - statusLineHeight - y
+	height := minInt(minInt(len(values), pickerMaxHeight), below)
+	if height < len(values) && t.lastCursorY-formulaLineHeight > below {
+		height = minInt(minInt(len(values), pickerMaxHeight), t.lastCursorY-formulaLineHeight)
+		y = t.lastCursorY - height
+	}
+	if height <= 0 {
+		return -1, errors.New("no room for the list of values")
+	}
+	if selected < 0 || selected >= len(values) {
+		selected = 0
+	}
+	top := 0
+	for {
+		if selected < top {
+			top = selected
+		} else if selected >= top+height {
+			top = selected - height + 1
+		}
+		for i := 0; i < height; i++ {
+			fg, bg := colorBlack, colorGrey
+			if top+i == selected {
+				fg, bg = colorWhite, colorSelection
+			}
+			t.drawCell(x, y+i, width, 1, " "+values[top+i], fg, bg)
+		}
+		t.screen.ShowCursor(x, y+selected-top)
+		t.screen.Show()
+
+		event, err := t.ReadKey()
+		if err != nil {
+			return -1, err
+		}
+		ev, ok := event.(ui.KeyEvent)
+		if !ok {
+			continue
+		}
+		switch ev.Key {
+		case tcell.KeyEnter:
+			return selected, nil
+		case tcell.KeyEsc, tcell.KeyCtrlC:
+			return -1, nil
+		case tcell.KeyUp, tcell.KeyCtrlP:
+			selected = maxInt(selected-1, 0)
+		case tcell.KeyDown, tcell.KeyCtrlN:
+			selected = minInt(selected+1, len(values)-1)
+		case tcell.KeyPgUp:
+			selected = maxInt(selected-height, 0)
+		case tcell.KeyPgDn:
+			selected = minInt(selected+height, len(values)-1)
+		case tcell.KeyHome:
+			selected = 0
+		case tcell.KeyEnd:
+			selected = len(values) - 1
+		case tcell.KeyRune:
+			selected = nextValueByRune(values, selected, ev.Ch)
+		}
+	}
+}
+
+// nextValueByRune finds the value after the selected one starting with the char, case-insensitively.
+func nextValueByRune(values []string, selected int, ch rune) int {
+	for i := 1; i <= len(values); i++ {
+		n := (selected + i) % len(values)
+		v := strings.TrimLeft(values[n], " ")
+		if v != "" && unicode.ToLower([]rune(v)[0]) == unicode.ToLower(ch) {
+			return n
+		}
+	}
+	return selected
+}
@@ -19,16 +19,15 @@ type Termbox struct {
 	screenWidth  int
 	screenHeight int
 
-	// How many rows and columns are visible for last drawing iteration.
-	calculatedViewportWidth  int
-	calculatedViewportHeight int
-
-	// Length in chars of vertical ruler for last drawing iteration.
-	vRulerWidth int
+	// Parts of the screen showing windows and the index of the active window for last drawing iteration.
+	panes        []*pane
+	activeWindow int
 
 	// Cursor position for last drawing iteration.
 	lastCursorX int
 	lastCursorY int
+	// First screen row below the cursor cell.
+	lastCursorBottom int
 
 	// What need to redrawn on next draw iteration.
 	dirty ui.DirtyFlag
@@ -36,6 +35,19 @@ type Termbox struct {
 	// Message displaying in status line and its decoration flags.
 	statusMessage string
 	statusFlags   int
+
+	// Cells referenced by the formula being edited.
+	formulaRefs []ui.FormulaRef
+
+	// Texts killed in editor, shared between editing sessions.
+	killRing []string
+
+	// Editors work in vim-like modal mode.
+	vimMode bool
+
+	// Commands and search patterns entered before, the last one is the latest.
+	commandHistory []string
+	searchHistory  []string
 }
 
 func New() *Termbox {
@@ -47,6 +59,7 @@ func New() *Termbox {
 	} else if e = s.Init(); e != nil {
 		panic(e)
 	}
+	s.EnableMouse()
 	width, height := s.Size()
 	return &Termbox{
 		screen:       s,
@@ -72,10 +85,20 @@ func (t *Termbox) Output() ui.OutputInterface {
 	return t
 }
 
+// SetVimMode turns on and off vim-like modal editing in editors.
+func (t *Termbox) SetVimMode(enabled bool) {
+	t.vimMode = enabled
+}
+
+// SetCommandHistory sets commands entered before to be navigated in command line.
+func (t *Termbox) SetCommandHistory(history []string) {
+	t.commandHistory = history
+}
+
 func (t *Termbox) ViewportHeight() int {
-	return t.calculatedViewportHeight
+	return t.activePane().viewportHeight
 }
 
 func (t *Termbox) ViewportWidth() int {
-	return t.calculatedViewportWidth
+	return t.activePane().viewportWidth
 }